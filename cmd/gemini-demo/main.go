@@ -110,7 +110,7 @@ func AnalyzePDFInline(ctx context.Context, client *genai.Client, filename string
 		nil,
 	)
 
-	fmt.Printf(result.Text())
+	fmt.Print(result.Text())
 }
 
 func AnalyzePDFByUpload(ctx context.Context, client *genai.Client, filename string) {
@@ -133,7 +133,7 @@ func AnalyzePDFByUpload(ctx context.Context, client *genai.Client, filename stri
 		nil,
 	)
 
-	fmt.Printf(result.Text())
+	fmt.Print(result.Text())
 }
 
 func StreamResponse(ctx context.Context, client *genai.Client) {
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	keystore "github.com/neyuki778/LLM-PDF-OCR/internal/keystore"
+	secret "github.com/neyuki778/LLM-PDF-OCR/internal/secret"
+)
+
+const usage = `Usage: keyctl [-db path] <command> [args]
+
+Commands:
+  list                      列出所有 key（脱敏）
+  add <key> [note]          新增 key，默认启用
+  enable <id>               启用 key
+  disable <id>              禁用 key
+  delete <id>               删除 key
+  usage [days]              每个 key 最近 days 天（默认 7）的每日用量
+  gen-master                生成新的 MASTER_KEY（base64, 32 字节）
+  encrypt <value>           用当前 MASTER_KEY 加密任意值，可填入 .env（如 MINERU_TOKEN）
+  rotate-master <key file>  用 key file 中的新 master key 重新加密所有 key
+
+db 路径默认读取 GEMINI_KEYSTORE_PATH，未设置时为 ./data/app.db
+当前 master key 读取 MASTER_KEY 或 MASTER_KEY_FILE，未设置时 key 以明文存储
+设置 GEMINI_KEY_DAILY_REQUEST_LIMIT 后 usage 会显示请求数占每日配额的比例
+`
+
+func main() {
+	godotenv.Load()
+
+	defaultPath := os.Getenv("GEMINI_KEYSTORE_PATH")
+	if defaultPath == "" {
+		defaultPath = "./data/app.db"
+	}
+	dbPath := flag.String("db", defaultPath, "keystore sqlite path")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	masterKey, err := secret.LoadFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load master key: %v", err)
+	}
+
+	// 不需要打开数据库的命令
+	switch args[0] {
+	case "gen-master":
+		encoded, err := secret.GenerateMasterKey()
+		if err != nil {
+			log.Fatalf("gen-master failed: %v", err)
+		}
+		fmt.Println(encoded)
+		return
+	case "encrypt":
+		if len(args) < 2 {
+			log.Fatalf("encrypt failed: missing value")
+		}
+		if masterKey == nil {
+			log.Fatalf("encrypt failed: MASTER_KEY or MASTER_KEY_FILE is not set")
+		}
+		encrypted, err := masterKey.Encrypt(args[1])
+		if err != nil {
+			log.Fatalf("encrypt failed: %v", err)
+		}
+		fmt.Println(encrypted)
+		return
+	}
+
+	store, err := keystore.NewStore(*dbPath, masterKey)
+	if err != nil {
+		log.Fatalf("Failed to open keystore: %v", err)
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := run(ctx, store, args[0], args[1:]); err != nil {
+		log.Fatalf("%s failed: %v", args[0], err)
+	}
+}
+
+func run(ctx context.Context, store *keystore.Store, cmd string, args []string) error {
+	switch cmd {
+	case "list":
+		keys, err := store.ListKeys(ctx)
+		if err != nil {
+			return err
+		}
+		printKeys(keys)
+		return nil
+	case "add":
+		if len(args) < 1 {
+			return errors.New("missing key")
+		}
+		key, err := store.AddKey(ctx, args[0], strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Key added: id=%d key=%s\n", key.ID, key.Masked())
+		return nil
+	case "enable", "disable":
+		id, err := parseID(args)
+		if err != nil {
+			return err
+		}
+		if err := store.SetKeyEnabled(ctx, id, cmd == "enable"); err != nil {
+			return err
+		}
+		fmt.Printf("Key %d %sd\n", id, cmd)
+		return nil
+	case "delete":
+		id, err := parseID(args)
+		if err != nil {
+			return err
+		}
+		if err := store.DeleteKey(ctx, id); err != nil {
+			return err
+		}
+		fmt.Printf("Key %d deleted\n", id)
+		return nil
+	case "usage":
+		days := 7
+		if len(args) > 0 {
+			parsed, err := strconv.Atoi(args[0])
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid days: %s", args[0])
+			}
+			days = parsed
+		}
+		rows, err := store.ListDailyUsage(ctx, days)
+		if err != nil {
+			return err
+		}
+		limit, _ := strconv.Atoi(strings.TrimSpace(os.Getenv("GEMINI_KEY_DAILY_REQUEST_LIMIT")))
+		printUsage(rows, limit)
+		return nil
+	case "rotate-master":
+		if len(args) < 1 {
+			return errors.New("missing new master key file")
+		}
+		next, err := secret.LoadFromFile(args[0])
+		if err != nil {
+			return err
+		}
+		count, err := store.RotateMasterKey(ctx, next)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Re-encrypted %d keys with master key %s; update MASTER_KEY / MASTER_KEY_FILE before restarting the server\n", count, next.KeyID())
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
+	}
+}
+
+func parseID(args []string) (int64, error) {
+	if len(args) < 1 {
+		return 0, errors.New("missing key id")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid key id: %s", args[0])
+	}
+	return id, nil
+}
+
+func printKeys(keys []keystore.Key) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKEY\tENABLED\tREQUESTS\tTOKENS\tERRORS\tFAILS\tLAST USED\tCOOLDOWN UNTIL\tNOTE")
+	now := time.Now()
+	for _, key := range keys {
+		cooldown := "-"
+		if key.CoolingDown(now) {
+			cooldown = formatTime(key.CooldownUntil)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%t\t%d\t%d\t%d\t%d\t%s\t%s\t%s\n",
+			key.ID, key.Masked(), key.Enabled, key.RequestCount, key.TokensUsed,
+			key.ErrorCount, key.ConsecutiveFailures, formatTime(key.LastUsedAt), cooldown, key.Note)
+	}
+	w.Flush()
+}
+
+func printUsage(rows []keystore.DailyUsage, limit int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DAY\tID\tKEY\tREQUESTS\tTOKENS\tERRORS\tQUOTA\tNOTE")
+	for _, row := range rows {
+		quota := "-"
+		if limit > 0 {
+			quota = fmt.Sprintf("%.0f%%", float64(row.RequestCount)*100/float64(limit))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			row.Day, row.KeyID, row.MaskedKey, row.RequestCount, row.TokensUsed, row.ErrorCount, quota, row.Note)
+	}
+	w.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
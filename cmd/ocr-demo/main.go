@@ -23,7 +23,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
-	tm, err := task.NewTaskManager(3, config)
+	tm, err := task.NewTaskManager(3, config, nil)
 	if err != nil {
 		log.Fatalf("Failed to create TaskManager: %v", err)
 	}
@@ -1,16 +1,23 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 	api "github.com/neyuki778/LLM-PDF-OCR/internal/api"
 	auth "github.com/neyuki778/LLM-PDF-OCR/internal/auth"
+	keystore "github.com/neyuki778/LLM-PDF-OCR/internal/keystore"
+	prompt "github.com/neyuki778/LLM-PDF-OCR/internal/prompt"
+	secret "github.com/neyuki778/LLM-PDF-OCR/internal/secret"
 	redis "github.com/neyuki778/LLM-PDF-OCR/internal/store/redis"
 	task "github.com/neyuki778/LLM-PDF-OCR/internal/task"
 	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
@@ -19,8 +26,8 @@ import (
 func main() {
 	godotenv.Load()
 
-	// 从环境变量加载 LLM 配置
-	config, err := llm.LoadConfigFromEnv()
+	// 从环境变量加载 LLM 配置，LLM_CONFIG_FILE 中的值覆盖环境变量
+	config, err := loadLLMConfig(false)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
@@ -42,8 +49,11 @@ func main() {
 		log.Fatalf("Failed to start TaskManager: %v", err)
 	}
 	defer tm.ShutDown()
+	tm.SetConfigLoader(func() (llm.Config, error) { return loadLLMConfig(true) })
+	go reloadOnSIGHUP(tm)
 
 	var authService *auth.Service
+	var promptStore *prompt.Store
 	jwtSecret := strings.TrimSpace(os.Getenv("JWT_SECRET"))
 	if jwtSecret != "" {
 		sqlitePath := os.Getenv("SQLITE_PATH")
@@ -57,6 +67,12 @@ func main() {
 		}
 		defer authStore.Close()
 
+		promptStore, err = prompt.NewStore(sqlitePath)
+		if err != nil {
+			log.Fatalf("Failed to init prompt template store: %v", err)
+		}
+		defer promptStore.Close()
+
 		accessTTL, err := parseDurationEnv("JWT_ACCESS_TTL", 15*time.Minute)
 		if err != nil {
 			log.Fatalf("Invalid JWT_ACCESS_TTL: %v", err)
@@ -79,6 +95,22 @@ func main() {
 		log.Println("Auth disabled: JWT_SECRET is empty")
 	}
 
+	var keyStore *keystore.Store
+	if keyStorePath := strings.TrimSpace(os.Getenv("GEMINI_KEYSTORE_PATH")); keyStorePath != "" {
+		masterKey, err := secret.LoadFromEnv()
+		if err != nil {
+			log.Fatalf("Failed to load master key: %v", err)
+		}
+		if masterKey == nil {
+			log.Println("Keystore encryption disabled: MASTER_KEY is empty, keys are stored in plaintext")
+		}
+		keyStore, err = keystore.NewStore(keyStorePath, masterKey)
+		if err != nil {
+			log.Fatalf("Failed to init keystore: %v", err)
+		}
+		defer keyStore.Close()
+	}
+
 	cookieSecure := strings.EqualFold(strings.TrimSpace(os.Getenv("AUTH_COOKIE_SECURE")), "true")
 	guestMaxPages, err := parsePositiveIntEnv("TASK_MAX_PAGES_GUEST", 20)
 	if err != nil {
@@ -93,13 +125,33 @@ func main() {
 		log.Fatalf("Invalid TASK_MAX_PAGES_HARD: %v", err)
 	}
 
-	log.Printf("Task quota config: guest=%d user=%d hard=%d", guestMaxPages, userMaxPages, hardMaxPages)
+	guestAsksPerMinute, err := parsePositiveIntEnv("ASK_PER_MINUTE_GUEST", 2)
+	if err != nil {
+		log.Fatalf("Invalid ASK_PER_MINUTE_GUEST: %v", err)
+	}
+	userAsksPerMinute, err := parsePositiveIntEnv("ASK_PER_MINUTE_USER", 10)
+	if err != nil {
+		log.Fatalf("Invalid ASK_PER_MINUTE_USER: %v", err)
+	}
+
+	keyDailyRequestLimit, err := parsePositiveIntEnv("GEMINI_KEY_DAILY_REQUEST_LIMIT", 0)
+	if err != nil {
+		log.Fatalf("Invalid GEMINI_KEY_DAILY_REQUEST_LIMIT: %v", err)
+	}
+
+	log.Printf("Task quota config: guest=%d user=%d hard=%d asks_per_minute=%d/%d", guestMaxPages, userMaxPages, hardMaxPages, guestAsksPerMinute, userAsksPerMinute)
 
 	// 创建并启动 HTTP 服务
-	server := api.NewServer(tm, authService, cookieSecure, api.TaskQuotaConfig{
+	server := api.NewServer(tm, authService, keyStore, promptStore, cookieSecure, api.TaskQuotaConfig{
 		GuestMaxPages: guestMaxPages,
 		UserMaxPages:  userMaxPages,
 		HardMaxPages:  hardMaxPages,
+
+		GuestAsksPerMinute: guestAsksPerMinute,
+		UserAsksPerMinute:  userAsksPerMinute,
+	}, api.AdminConfig{
+		Emails:               strings.Split(os.Getenv("ADMIN_EMAILS"), ","),
+		KeyDailyRequestLimit: keyDailyRequestLimit,
 	})
 	log.Println("Server starting on :8080")
 	if err := server.Run(":8080"); err != nil {
@@ -107,6 +159,34 @@ func main() {
 	}
 }
 
+// loadLLMConfig 读取 LLM 配置；reload 时重新读取 .env，使修改后的值覆盖进程启动时的环境变量
+func loadLLMConfig(reload bool) (llm.Config, error) {
+	if path := strings.TrimSpace(os.Getenv("LLM_CONFIG_FILE")); path != "" {
+		if err := godotenv.Overload(path); err != nil {
+			return llm.Config{}, fmt.Errorf("read LLM_CONFIG_FILE %s: %w", path, err)
+		}
+	} else if reload {
+		if err := godotenv.Overload(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return llm.Config{}, fmt.Errorf("read .env: %w", err)
+		}
+	}
+	return llm.LoadConfigFromEnv()
+}
+
+// reloadOnSIGHUP 收到 SIGHUP 时热加载 LLM 配置，失败时保留原配置
+func reloadOnSIGHUP(tm *task.TaskManager) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		version, err := tm.Reload()
+		if err != nil {
+			log.Printf("[server] reload config on SIGHUP failed err=%v", err)
+			continue
+		}
+		log.Printf("[server] config reloaded on SIGHUP version=%d", version)
+	}
+}
+
 func parseDurationEnv(key string, fallback time.Duration) (time.Duration, error) {
 	raw := strings.TrimSpace(os.Getenv(key))
 	if raw == "" {
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	keystore "github.com/neyuki778/LLM-PDF-OCR/internal/keystore"
+)
+
+// listKeys 处理 GET /api/admin/keys
+func (s *Server) listKeys(c *gin.Context) {
+	keys, err := s.keyStore.ListKeys(c.Request.Context())
+	if err != nil {
+		log.Printf("[admin] list keys failed err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list keys"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, keyResponse(key))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// addKey 处理 POST /api/admin/keys
+func (s *Server) addKey(c *gin.Context) {
+	var req struct {
+		Key  string `json:"key"`
+		Note string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	key, err := s.keyStore.AddKey(c.Request.Context(), req.Key, strings.TrimSpace(req.Note))
+	if err != nil {
+		switch {
+		case errors.Is(err, keystore.ErrEmptyKey):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, keystore.ErrKeyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("[admin] add key failed err=%v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add key"})
+		}
+		return
+	}
+
+	log.Printf("[admin] key added key_id=%d key=%s ip=%s", key.ID, key.Masked(), c.ClientIP())
+	c.JSON(http.StatusCreated, keyResponse(*key))
+}
+
+// updateKey 处理 PATCH /api/admin/keys/:id - 修改备注或启用状态
+func (s *Server) updateKey(c *gin.Context) {
+	id, ok := parseKeyID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Note    *string `json:"note"`
+		Enabled *bool   `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.Note != nil {
+		note := strings.TrimSpace(*req.Note)
+		req.Note = &note
+	}
+
+	key, err := s.keyStore.UpdateKey(c.Request.Context(), id, keystore.KeyUpdate{
+		Note:    req.Note,
+		Enabled: req.Enabled,
+	})
+	if err != nil {
+		if errors.Is(err, keystore.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("[admin] update key failed key_id=%d err=%v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update key"})
+		return
+	}
+
+	log.Printf("[admin] key updated key_id=%d enabled=%t ip=%s", key.ID, key.Enabled, c.ClientIP())
+	c.JSON(http.StatusOK, keyResponse(*key))
+}
+
+// deleteKey 处理 DELETE /api/admin/keys/:id
+func (s *Server) deleteKey(c *gin.Context) {
+	id, ok := parseKeyID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.keyStore.DeleteKey(c.Request.Context(), id); err != nil {
+		if errors.Is(err, keystore.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("[admin] delete key failed key_id=%d err=%v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete key"})
+		return
+	}
+
+	log.Printf("[admin] key deleted key_id=%d ip=%s", id, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"message": "key deleted"})
+}
+
+func parseKeyID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// getKeyUsage 处理 GET /api/admin/keys/usage?days=7 - 每个 key 的每日用量
+func (s *Server) getKeyUsage(c *gin.Context) {
+	days := 7
+	if raw := strings.TrimSpace(c.Query("days")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days"})
+			return
+		}
+		days = min(parsed, 90)
+	}
+
+	usage, err := s.keyStore.ListDailyUsage(c.Request.Context(), days)
+	if err != nil {
+		log.Printf("[admin] list key usage failed err=%v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list key usage"})
+		return
+	}
+
+	limit := s.keyDailyRequestLimit
+	items := make([]gin.H, 0, len(usage))
+	for _, item := range usage {
+		row := gin.H{
+			"key_id":        item.KeyID,
+			"masked_key":    item.MaskedKey,
+			"note":          item.Note,
+			"enabled":       item.Enabled,
+			"day":           item.Day,
+			"request_count": item.RequestCount,
+			"tokens_used":   item.TokensUsed,
+			"error_count":   item.ErrorCount,
+		}
+		if limit > 0 {
+			row["usage_ratio"] = float64(item.RequestCount) / float64(limit)
+		}
+		items = append(items, row)
+	}
+
+	resp := gin.H{
+		"days":  days,
+		"items": items,
+	}
+	if limit > 0 {
+		resp["daily_request_limit"] = limit
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// keyResponse 构造 key 的展示结构，只返回脱敏后的 key
+func keyResponse(key keystore.Key) gin.H {
+	return gin.H{
+		"id":                   key.ID,
+		"masked_key":           key.Masked(),
+		"note":                 key.Note,
+		"enabled":              key.Enabled,
+		"created_at":           key.CreatedAt.Unix(),
+		"updated_at":           key.UpdatedAt.Unix(),
+		"last_used_at":         unixOrNil(key.LastUsedAt),
+		"error_count":          key.ErrorCount,
+		"request_count":        key.RequestCount,
+		"tokens_used":          key.TokensUsed,
+		"last_error":           key.LastError,
+		"last_error_at":        unixOrNil(key.LastErrorAt),
+		"consecutive_failures": key.ConsecutiveFailures,
+		"cooldown_until":       unixOrNil(key.CooldownUntil),
+		"cooling_down":         key.CoolingDown(time.Now()),
+	}
+}
+
+func unixOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Unix()
+}
+
+// listUsage 处理 GET /api/admin/usage?month=YYYY-MM - 所有用户的月度用量，用于内部结算
+func (s *Server) listUsage(c *gin.Context) {
+	month, ok := parseUsageMonth(c)
+	if !ok {
+		return
+	}
+
+	items, err := s.taskManager.ListUsage(month)
+	if err != nil {
+		log.Printf("[admin] list usage failed month=%s err=%v", month, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list usage"})
+		return
+	}
+
+	var total float64
+	for _, item := range items {
+		total += item.Cost
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"month":      month,
+		"items":      items,
+		"total_cost": total,
+	})
+}
+
+// reloadConfig 处理 POST /api/admin/config/reload - 重新读取 LLM 配置并热加载，进行中的分片不受影响
+func (s *Server) reloadConfig(c *gin.Context) {
+	version, err := s.taskManager.Reload()
+	if err != nil {
+		log.Printf("[admin] reload config failed ip=%s err=%v", c.ClientIP(), err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[admin] config reloaded version=%d ip=%s", version, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{
+		"config_version": version,
+		"config":         s.taskManager.GetStatus()["config"],
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/neyuki778/LLM-PDF-OCR/internal/task"
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	qa "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/qa"
+)
+
+// maxQuestionChars 问题的最大字符数
+const maxQuestionChars = 1000
+
+// askTask 处理 POST /api/tasks/:id/ask - 基于已完成任务的 Markdown 结果回答问题，附引用页码
+func (s *Server) askTask(c *gin.Context) {
+	taskID := c.Param("id")
+	parentTask := s.taskManager.GetTask(taskID)
+
+	if parentTask == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if !s.authorizeTaskOwner(c, taskID, parentTask.OwnerUserID, "askTask") {
+		return
+	}
+
+	var req struct {
+		Question string `json:"question"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": qa.ErrEmptyQuestion.Error()})
+		return
+	}
+	if len([]rune(question)) > maxQuestionChars {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "question must be at most " + strconv.Itoa(maxQuestionChars) + " characters"})
+		return
+	}
+
+	if parentTask.CurrentStatus() != task.StatusCompleted {
+		c.JSON(http.StatusAccepted, gin.H{
+			"task_id": taskID,
+			"status":  parentTask.CurrentStatus(),
+			"message": "task not completed yet",
+		})
+		return
+	}
+
+	// 按用户等级限制提问频率，登录用户按用户 ID、游客按 IP 计
+	tier, userID, _, statusCode, tierErr := s.resolveTaskTier(c)
+	if statusCode != 0 {
+		c.JSON(statusCode, gin.H{"error": tierErr})
+		return
+	}
+	requester := userID
+	if requester == "" {
+		requester = c.ClientIP()
+	}
+	if wait := s.askLimiter.allow(tier, requester); wait > 0 {
+		log.Printf("[quota] ask rate limited tier=%s user_id=%s task_id=%s ip=%s", tier, userID, taskID, c.ClientIP())
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many questions, try again later", "tier": tier})
+		return
+	}
+
+	result, err := s.taskManager.Ask(c.Request.Context(), parentTask, question)
+	if err != nil {
+		switch {
+		case errors.Is(err, task.ErrAskUnsupported) || errors.Is(err, llm.ErrTextUnsupported):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case llmerr.ClassOf(err) == llmerr.ClassRateLimited:
+			if retryAfter := llmerr.RetryAfter(err); retryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			}
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "provider is rate limited, try again later"})
+		default:
+			log.Printf("[task] ask failed task_id=%s err=%v", taskID, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "failed to answer question"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":   taskID,
+		"question":  question,
+		"answer":    result.Text,
+		"citations": result.Citations,
+		"sources":   result.Sources,
+		"model":     result.Model,
+		"usage":     result.Usage,
+		"cost":      result.Cost,
+	})
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	task "github.com/neyuki778/LLM-PDF-OCR/internal/task"
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	pdfapi "github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// newAskTestServer 在临时目录中用 mock provider 创建一个两页的任务，游客每分钟只能提问一次
+func newAskTestServer(t *testing.T, owner string) (*Server, *task.TaskManager, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Chdir(t.TempDir())
+
+	page := `{"content":{"text":[{"value":"deadline","pos":[10,10],"font":{"name":"Helvetica","size":12}}]}}`
+	var doc bytes.Buffer
+	if err := pdfapi.Create(nil, strings.NewReader(`{"pages":{"1":`+page+`,"2":`+page+`}}`), &doc, nil); err != nil {
+		t.Fatalf("create pdf: %v", err)
+	}
+	pdfPath := filepath.Join(t.TempDir(), "doc.pdf")
+	if err := os.WriteFile(pdfPath, doc.Bytes(), 0644); err != nil {
+		t.Fatalf("write pdf: %v", err)
+	}
+
+	tm, err := task.NewTaskManager(1, llm.Config{Provider: "mock"}, nil)
+	if err != nil {
+		t.Fatalf("NewTaskManager: %v", err)
+	}
+	if err := tm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { _ = tm.ShutDown() })
+
+	taskID, err := tm.CreateTaskWithOptions(pdfPath, task.CreateTaskOptions{OwnerUserID: owner})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	server := NewServer(tm, nil, nil, nil, false, TaskQuotaConfig{GuestAsksPerMinute: 1}, AdminConfig{})
+	return server, tm, taskID
+}
+
+func ask(server *Server, taskID, question string) *httptest.ResponseRecorder {
+	body := bytes.NewBufferString(`{"question":"` + question + `"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/tasks/"+taskID+"/ask", body)
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAskTask_RequiresOwner(t *testing.T) {
+	server, _, taskID := newAskTestServer(t, "u1")
+	if got := ask(server, taskID, "deadline?"); got.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401: %s", got.Code, got.Body)
+	}
+}
+
+func TestAskTask_PendingTaskReturnsAccepted(t *testing.T) {
+	server, _, taskID := newAskTestServer(t, "")
+	got := ask(server, taskID, "deadline?")
+	if got.Code != http.StatusAccepted || !strings.Contains(got.Body.String(), "task not completed yet") {
+		t.Fatalf("status = %d, want 202: %s", got.Code, got.Body)
+	}
+}
+
+func TestAskTask_RateLimitedPerTier(t *testing.T) {
+	server, tm, taskID := newAskTestServer(t, "")
+	if err := tm.SubmitTaskToPool(taskID, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if err := tm.WaitForTask(taskID, 10*time.Second); err != nil {
+		t.Fatalf("WaitForTask: %v", err)
+	}
+
+	if got := ask(server, taskID, "deadline?"); got.Code != http.StatusOK {
+		t.Fatalf("first question status = %d, want 200: %s", got.Code, got.Body)
+	}
+	got := ask(server, taskID, "deadline?")
+	if got.Code != http.StatusTooManyRequests || got.Header().Get("Retry-After") == "" {
+		t.Fatalf("second question status = %d retry-after=%q, want 429: %s", got.Code, got.Header().Get("Retry-After"), got.Body)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	ratelimit "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/ratelimit"
+)
+
+const (
+	askLimiterIdle      = time.Minute // 超过该时间未提问的请求方令牌桶已补满，可以丢弃
+	askLimiterPruneSize = 1024        // 请求方数量达到该值后，新增请求方时清理空闲的
+)
+
+// askLimiter 按请求方（登录用户为用户 ID，游客为 IP）限制每分钟提问次数，上限按用户等级区分
+type askLimiter struct {
+	perMinute map[string]int // tier -> 每分钟提问次数
+
+	mu         sync.Mutex
+	requesters map[string]*askRequester
+}
+
+type askRequester struct {
+	limiter  *ratelimit.Limiter
+	lastSeen time.Time
+}
+
+func newAskLimiter(quota TaskQuotaConfig) *askLimiter {
+	return &askLimiter{
+		perMinute: map[string]int{
+			"guest": quota.GuestAsksPerMinute,
+			"user":  quota.UserAsksPerMinute,
+		},
+		requesters: make(map[string]*askRequester),
+	}
+}
+
+// allow 有余量时扣除一次并返回 0，否则返回还需等待的时间
+func (l *askLimiter) allow(tier, requester string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	key := tier + ":" + requester
+	entry, ok := l.requesters[key]
+	if !ok {
+		entry = &askRequester{limiter: ratelimit.New(ratelimit.Limits{RPM: l.perMinute[tier]})}
+		if len(l.requesters) >= askLimiterPruneSize {
+			l.prune(now)
+		}
+		l.requesters[key] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter.Reserve(0)
+}
+
+// prune 丢弃长时间未提问的请求方，避免 map 无限增长
+func (l *askLimiter) prune(now time.Time) {
+	for key, entry := range l.requesters {
+		if now.Sub(entry.lastSeen) > askLimiterIdle {
+			delete(l.requesters, key)
+		}
+	}
+}
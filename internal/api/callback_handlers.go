@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	task "github.com/neyuki778/LLM-PDF-OCR/internal/task"
+	mineru "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/MinerU"
+)
+
+// mineruCallbackRequest MinerU 推送的请求体：checksum = sha256(uid + seed + content)
+type mineruCallbackRequest struct {
+	Checksum string `json:"checksum" form:"checksum"`
+	Content  string `json:"content" form:"content"`
+}
+
+// mineruCallback 处理 POST /api/callbacks/mineru - MinerU 解析结果推送
+// 返回非 200 时 MinerU 最多重推 5 次
+func (s *Server) mineruCallback(c *gin.Context) {
+	var req mineruCallbackRequest
+	if err := c.ShouldBind(&req); err != nil || req.Checksum == "" || req.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "checksum and content are required"})
+		return
+	}
+
+	err := s.taskManager.HandleMinerUCallback(req.Checksum, req.Content)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	case errors.Is(err, task.ErrMinerUCallbackDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, mineru.ErrInvalidChecksum):
+		log.Printf("[mineru] reject callback with invalid checksum ip=%s", c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, mineru.ErrUnknownTask):
+		log.Printf("[mineru] callback for unknown task err=%v", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"archive/zip"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -14,7 +17,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	auth "github.com/neyuki778/LLM-PDF-OCR/internal/auth"
+	profile "github.com/neyuki778/LLM-PDF-OCR/internal/profile"
 	"github.com/neyuki778/LLM-PDF-OCR/internal/task"
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	gemini "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/gemini"
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	translate "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/translate"
+	schema "github.com/neyuki778/LLM-PDF-OCR/pkg/schema"
 )
 
 // createTask 处理 POST /api/tasks - 上传 PDF 并创建任务
@@ -42,6 +51,71 @@ func (s *Server) createTask(c *gin.Context) {
 		return
 	}
 
+	// 模型选项：来自运营方配置的白名单，按用户等级限制
+	var modelChoice llm.ModelChoice
+	if name := strings.ToLower(strings.TrimSpace(c.PostForm("model"))); name != "" {
+		choice, err := s.taskManager.ResolveChoice(name, tier)
+		if err != nil {
+			statusCode := http.StatusBadRequest
+			if errors.Is(err, task.ErrModelChoiceNotAllowed) {
+				statusCode = http.StatusForbidden
+			}
+			c.JSON(statusCode, gin.H{"error": err.Error(), "tier": tier})
+			return
+		}
+		modelChoice = choice
+	}
+
+	var docProfile profile.Profile
+	if name := strings.TrimSpace(c.PostForm("profile")); name != "" {
+		var ok bool
+		if docProfile, ok = profile.Get(name); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown profile: " + name})
+			return
+		}
+	}
+
+	mineruOptions, err := parseMinerUOptions(c.PostForm("mineru"), docProfile.MinerU)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 结构化抽取：schema 表单字段为 JSON Schema，结果为 result.json
+	responseSchema := strings.TrimSpace(c.PostForm("schema"))
+	basePrompt := docProfile.Prompt
+	if responseSchema != "" {
+		if _, err := schema.Parse([]byte(responseSchema)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// 用户模板会替换抽取提示词，两者不能同时使用
+		if strings.TrimSpace(c.PostForm("prompt_template")) != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "prompt_template cannot be combined with schema"})
+			return
+		}
+		basePrompt = gemini.ExtractPrompt
+	}
+
+	// 译文语言：OCR 完成后逐分片翻译，生成 result.<lang>.md，原文结果不变
+	translateTo := ""
+	if lang := strings.TrimSpace(c.PostForm("translate_to")); lang != "" {
+		if translateTo, err = translate.NormalizeLanguage(lang); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if responseSchema != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "translate_to cannot be combined with schema"})
+			return
+		}
+	}
+
+	taskPrompt, statusCode, promptErr := s.resolveTaskPrompt(c, userID, basePrompt)
+	if statusCode != 0 {
+		c.JSON(statusCode, gin.H{"error": promptErr})
+		return
+	}
+
 	// 3. 创建上传目录
 	uploadDir := "uploads"
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
@@ -67,12 +141,32 @@ func (s *Server) createTask(c *gin.Context) {
 	}
 
 	// 5. 调用 TaskManager 创建任务
+	processOptions := llm.ProcessOptions{
+		Prompt:      taskPrompt,
+		Provider:    docProfile.Provider,
+		MinerU:      mineruOptions,
+		TranslateTo: translateTo,
+	}
+	if responseSchema != "" {
+		processOptions.Provider = "gemini"
+		processOptions.ResponseSchema = json.RawMessage(responseSchema)
+	}
 	taskID, err := s.taskManager.CreateTaskWithOptions(savePath, task.CreateTaskOptions{
 		MaxPages:    effectiveMaxPages,
 		OwnerUserID: userID,
+		Profile:     docProfile.Name,
+		ShardSpan:   docProfile.ShardSpan,
+		PostProcess: docProfile.PostProcess,
+		Process:     processOptions,
+		ModelChoice: modelChoice.Name,
 	})
 	if err != nil {
 		s.cleanupUploadedFile(savePath, "create_task_failed")
+		if errors.Is(err, task.ErrExtractionUnsupported) || errors.Is(err, task.ErrTranslationUnsupported) ||
+			errors.Is(err, options.ErrInvalidPageRanges) || errors.Is(err, task.ErrUnknownModelChoice) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		var pageLimitErr *task.PageLimitExceededError
 		if errors.As(err, &pageLimitErr) {
 			log.Printf(
@@ -129,11 +223,47 @@ func (s *Server) getTask(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	shards := make([]gin.H, 0, len(parentTask.SubTasks))
+	for _, shard := range parentTask.ShardRecords() {
+		item := gin.H{
+			"id":         shard.ID,
+			"page_start": shard.PageStart,
+			"page_end":   shard.PageEnd,
+			"status":     shard.Status,
+			"provider":   shard.Provider,
+			"model":      shard.Model,
+			"usage":      shard.Usage,
+			"cost":       shard.Cost,
+		}
+		if shard.TranslateError != "" {
+			item["translate_error"] = shard.TranslateError
+		}
+		// 处理中的分片附带 provider 上报的进度，如 "12/40 pages"
+		if progress, ok := s.taskManager.ShardProgress(shard.ID); ok {
+			item["progress"] = progress.String()
+			item["progress_state"] = progress.State
+		}
+		shards = append(shards, item)
+	}
+	usage, cost := parentTask.UsageTotals()
+
+	response := gin.H{
 		"task_id":         taskID,
 		"completed_count": fmt.Sprintf("%d / %d", parentTask.CompletedCount, parentTask.TotalShards),
-		"status":          parentTask.Status,
-	})
+		"status":          parentTask.CurrentStatus(),
+		"profile":         parentTask.Profile,
+		"model_choice":    parentTask.ModelChoice,
+		"translate_to":    parentTask.Options.TranslateTo,
+		"format":          parentTask.OutputFormat,
+		"usage":           usage,
+		"cost":            cost,
+		"shards":          shards,
+	}
+	// 按任务实际使用的 provider 判断：选择了非 mineru 模型选项的任务不展示
+	if mineruOptions := s.taskManager.MinerUOptions(parentTask); mineruOptions != nil {
+		response["mineru_options"] = mineruOptions
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // getResult 处理 GET /api/tasks/:id/result - 下载 Markdown 结果
@@ -149,18 +279,205 @@ func (s *Server) getResult(c *gin.Context) {
 		return
 	}
 
-	if parentTask.Status != task.StatusCompleted {
+	if parentTask.CurrentStatus() != task.StatusCompleted {
 		c.JSON(http.StatusAccepted, gin.H{
 			"task_id": taskID,
-			"status":  parentTask.Status,
+			"status":  parentTask.CurrentStatus(),
 			"message": "task not completed yet",
 		})
 		return
 	}
 
+	// ?format= 只用于确认期望的结果格式，不做格式转换
+	switch format := strings.ToLower(strings.TrimSpace(c.Query("format"))); format {
+	case "":
+	case "md", task.FormatMarkdown, task.FormatJSON:
+		if format == "md" {
+			format = task.FormatMarkdown
+		}
+		if format != parentTask.OutputFormat {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("task result is %s, not %s", parentTask.OutputFormat, format),
+			})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be markdown or json"})
+		return
+	}
+
+	// ?lang= 下载译文，需创建任务时指定相同的 translate_to
+	if lang := strings.TrimSpace(c.Query("lang")); lang != "" {
+		normalized, err := translate.NormalizeLanguage(lang)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if normalized != parentTask.Options.TranslateTo {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no translation to " + normalized + " for this task"})
+			return
+		}
+		if _, err := os.Stat(parentTask.TranslatedOutputPath()); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "translation not available for this task"})
+			return
+		}
+		c.File(parentTask.TranslatedOutputPath())
+		return
+	}
+
 	c.File(parentTask.OutputPath)
 }
 
+// getLayout 处理 GET /api/tasks/:id/layout - MinerU 版面信息（bbox、块类型），页码为原文档页码（从 0 开始）
+func (s *Server) getLayout(c *gin.Context) {
+	taskID := c.Param("id")
+	parentTask := s.taskManager.GetTask(taskID)
+
+	if parentTask == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if !s.authorizeTaskOwner(c, taskID, parentTask.OwnerUserID, "getLayout") {
+		return
+	}
+
+	if parentTask.CurrentStatus() != task.StatusCompleted {
+		c.JSON(http.StatusAccepted, gin.H{
+			"task_id": taskID,
+			"status":  parentTask.CurrentStatus(),
+			"message": "task not completed yet",
+		})
+		return
+	}
+
+	layout, err := os.ReadFile(parentTask.LayoutPath())
+	if errors.Is(err, os.ErrNotExist) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "layout not available for this task"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read layout"})
+		return
+	}
+	response := gin.H{
+		"task_id": taskID,
+		"layout":  json.RawMessage(layout),
+	}
+	if contentList, err := os.ReadFile(parentTask.ContentListPath()); err == nil {
+		response["content_list"] = json.RawMessage(contentList)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// getExports 处理 GET /api/tasks/:id/exports/:format - 把 MinerU extra_formats 导出的分片文件打包为 zip 下载
+func (s *Server) getExports(c *gin.Context) {
+	taskID := c.Param("id")
+	format := c.Param("format")
+	parentTask := s.taskManager.GetTask(taskID)
+
+	if parentTask == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if !s.authorizeTaskOwner(c, taskID, parentTask.OwnerUserID, "getExports") {
+		return
+	}
+
+	if parentTask.CurrentStatus() != task.StatusCompleted {
+		c.JSON(http.StatusAccepted, gin.H{
+			"task_id": taskID,
+			"status":  parentTask.CurrentStatus(),
+			"message": "task not completed yet",
+		})
+		return
+	}
+
+	paths, err := parentTask.ExportPaths(format)
+	if errors.Is(err, task.ErrUnknownExportFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list exports"})
+		return
+	}
+	if len(paths) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no exports in this format for this task"})
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", taskID+"_"+format+".zip"))
+	archive := zip.NewWriter(c.Writer)
+	for _, path := range paths {
+		if err := addZipFile(archive, path); err != nil {
+			// 响应已开始写出，只能记录错误
+			log.Printf("[task] write export failed task_id=%s format=%s path=%s err=%v", taskID, format, path, err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("[task] write export failed task_id=%s format=%s err=%v", taskID, format, err)
+	}
+}
+
+func addZipFile(archive *zip.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w, err := archive.Create(filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// getPreview 处理 GET /api/tasks/:id/preview - OCR 进行中的实时预览
+func (s *Server) getPreview(c *gin.Context) {
+	taskID := c.Param("id")
+	parentTask := s.taskManager.GetTask(taskID)
+
+	if parentTask == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if !s.authorizeTaskOwner(c, taskID, parentTask.OwnerUserID, "getPreview") {
+		return
+	}
+
+	// 聚合完成后分片临时文件已清理，直接引导到结果接口
+	if parentTask.CurrentStatus() == task.StatusCompleted {
+		c.JSON(http.StatusOK, gin.H{
+			"task_id": taskID,
+			"status":  parentTask.CurrentStatus(),
+			"message": "task completed, use /api/tasks/" + taskID + "/result",
+		})
+		return
+	}
+
+	previews := parentTask.Preview()
+	shards := make([]gin.H, 0, len(previews))
+	for _, preview := range previews {
+		shards = append(shards, gin.H{
+			"id":         preview.ID,
+			"page_start": preview.PageStart,
+			"page_end":   preview.PageEnd,
+			"status":     preview.Status,
+			"partial":    preview.Partial,
+			"content":    preview.Content,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id": taskID,
+		"status":  parentTask.CurrentStatus(),
+		"shards":  shards,
+	})
+}
+
 // getTaskHistory 处理 GET /api/tasks/history - 获取当前登录用户历史任务
 func (s *Server) getTaskHistory(c *gin.Context) {
 	userID, statusCode, errMsg := s.resolveRequesterUserID(c, "getTaskHistory")
@@ -210,6 +527,8 @@ func (s *Server) getTaskHistory(c *gin.Context) {
 		respItems = append(respItems, gin.H{
 			"task_id":    item.TaskID,
 			"status":     item.Status,
+			"usage":      item.Usage,
+			"cost":       item.Cost,
 			"created_at": item.CreatedAt.Unix(),
 		})
 	}
@@ -227,6 +546,38 @@ func (s *Server) getTaskHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// getUsage 处理 GET /api/usage?month=YYYY-MM - 当前用户的月度用量，默认本月
+func (s *Server) getUsage(c *gin.Context) {
+	userID, ok := s.requireUserID(c, "getUsage")
+	if !ok {
+		return
+	}
+	month, ok := parseUsageMonth(c)
+	if !ok {
+		return
+	}
+
+	usage, err := s.taskManager.GetUserUsage(userID, month)
+	if err != nil {
+		log.Printf("[usage] get user usage failed user_id=%s month=%s err=%v", userID, month, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get usage"})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+func parseUsageMonth(c *gin.Context) (string, bool) {
+	month := strings.TrimSpace(c.Query("month"))
+	if month == "" {
+		return time.Now().UTC().Format("2006-01"), true
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month, expected YYYY-MM"})
+		return "", false
+	}
+	return month, true
+}
+
 // 暂不支持, task manager还没有实现对应的方法
 // deleteTask 处理 DELETE /api/tasks/:id - 删除任务
 func (s *Server) deleteTask(c *gin.Context) {
@@ -240,6 +591,43 @@ func (s *Server) deleteTask(c *gin.Context) {
 	})
 }
 
+// listProfiles 处理 GET /api/profiles - 内置文档 profile
+func (s *Server) listProfiles(c *gin.Context) {
+	profiles := profile.List()
+	items := make([]gin.H, 0, len(profiles))
+	for _, p := range profiles {
+		items = append(items, gin.H{
+			"name":         p.Name,
+			"description":  p.Description,
+			"provider":     p.Provider,
+			"shard_span":   p.ShardSpan,
+			"post_process": p.PostProcess,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// listModels 处理 GET /api/models - 当前用户等级可选的模型选项，创建任务时通过 model 字段选择
+func (s *Server) listModels(c *gin.Context) {
+	tier, _, _, statusCode, tierErr := s.resolveTaskTier(c)
+	if statusCode != 0 {
+		c.JSON(statusCode, gin.H{"error": tierErr})
+		return
+	}
+
+	choices := s.taskManager.ModelChoices(tier)
+	items := make([]gin.H, 0, len(choices))
+	for _, choice := range choices {
+		items = append(items, gin.H{
+			"name":        choice.Name,
+			"provider":    choice.Provider,
+			"model":       choice.Config.Model,
+			"description": choice.Description,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"tier": tier, "items": items})
+}
+
 // getStatus 处理 GET /api/status - 获取服务内部状态
 func (s *Server) getStatus(c *gin.Context) {
 	status := s.taskManager.GetStatus()
@@ -1,21 +1,86 @@
 package api
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	auth "github.com/neyuki778/LLM-PDF-OCR/internal/auth"
 )
 
 // 中间件处理 StatusServiceUnavailable
 func (s *Server) requireAuthService() gin.HandlerFunc {
-    return func(c *gin.Context) {
-        if s.authService == nil {
-            c.JSON(http.StatusServiceUnavailable, gin.H{
-                "error": "auth service is not configured",
-            })
-            c.Abort() // 阻止继续执行后续 handler
-            return
-        }
-        c.Next() // 继续
-    }
-}
\ No newline at end of file
+	return func(c *gin.Context) {
+		if s.authService == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "auth service is not configured",
+			})
+			c.Abort() // 阻止继续执行后续 handler
+			return
+		}
+		c.Next() // 继续
+	}
+}
+
+// requireAdmin 仅允许 ADMIN_EMAILS 中的登录用户访问
+func (s *Server) requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.authService == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "auth service is not configured",
+			})
+			c.Abort()
+			return
+		}
+
+		accessToken, _ := c.Cookie(accessTokenCookieName)
+		user, err := s.authService.Me(c.Request.Context(), accessToken)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidAccessToken) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			} else {
+				log.Printf("[auth] verify login status failed on admin ip=%s err=%v", c.ClientIP(), err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify login status"})
+			}
+			c.Abort()
+			return
+		}
+		if !s.adminEmails[strings.ToLower(user.Email)] {
+			log.Printf("[authz] admin access denied user_id=%s ip=%s", user.ID, c.ClientIP())
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireKeyStore 未配置 keystore 时返回 StatusServiceUnavailable
+func (s *Server) requireKeyStore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.keyStore == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "keystore is not configured",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// requirePromptStore 未配置提示词模板存储时返回 StatusServiceUnavailable
+func (s *Server) requirePromptStore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.promptStore == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "prompt templates are not configured",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
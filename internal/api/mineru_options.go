@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+)
+
+// mineruOptionsForm createTask 表单 mineru 字段（JSON），只允许以下 key，出现的字段覆盖 profile 默认值
+type mineruOptionsForm struct {
+	ModelVersion  *string  `json:"model_version"`
+	IsOCR         *bool    `json:"is_ocr"`
+	EnableFormula *bool    `json:"enable_formula"`
+	EnableTable   *bool    `json:"enable_table"`
+	Language      *string  `json:"language"`
+	ExtraFormats  []string `json:"extra_formats"`
+	PageRanges    *string  `json:"page_ranges"`
+}
+
+// parseMinerUOptions 解析并按白名单校验 mineru 表单字段，raw 为空时返回 base
+func parseMinerUOptions(raw string, base options.MinerUOptions) (options.MinerUOptions, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return base, nil
+	}
+
+	var form mineruOptionsForm
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&form); err != nil {
+		return base, fmt.Errorf("%w: %v", options.ErrInvalidMinerUOption, err)
+	}
+
+	merged := base
+	if form.ModelVersion != nil {
+		merged.ModelVersion = strings.TrimSpace(*form.ModelVersion)
+	}
+	if form.IsOCR != nil {
+		merged.IsOCR = *form.IsOCR
+	}
+	if form.EnableFormula != nil {
+		merged.EnableFormula = form.EnableFormula
+	}
+	if form.EnableTable != nil {
+		merged.EnableTable = form.EnableTable
+	}
+	if form.Language != nil {
+		merged.Language = strings.TrimSpace(*form.Language)
+	}
+	if form.ExtraFormats != nil {
+		merged.ExtraFormats = form.ExtraFormats
+	}
+	if form.PageRanges != nil {
+		merged.PageRanges = strings.ReplaceAll(*form.PageRanges, " ", "")
+	}
+	if err := merged.Validate(); err != nil {
+		return base, err
+	}
+	return merged, nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	pdf "github.com/neyuki778/LLM-PDF-OCR/pkg/pdf"
+)
+
+// serveOutputFile 公开任务目录中必须无鉴权访问的文件：分片 PDF（供 LLM-API 提供商按 PUBLIC_URL 拉取）
+// 和结果 Markdown 引用的图片；结果、导出、版面、翻译等文件只能通过鉴权的任务接口下载
+func (s *Server) serveOutputFile(c *gin.Context) {
+	taskID := c.Param("id")
+	name, ok := publicOutputFile(taskID, c.Param("path"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+	c.File(filepath.Join("output", taskID, filepath.FromSlash(name)))
+}
+
+// publicOutputFile 校验请求的相对路径，只接受 {分片名}.pdf 和 images/{文件名}
+func publicOutputFile(taskID, requested string) (string, bool) {
+	if _, err := uuid.Parse(taskID); err != nil {
+		return "", false
+	}
+	name := strings.TrimPrefix(requested, "/")
+	if name == "" || path.Clean(name) != name || strings.Contains(name, "\\") {
+		return "", false
+	}
+	dir, file := path.Split(name)
+	switch dir {
+	case "":
+		if _, _, ok := pdf.ShardPages(file); ok {
+			return name, true
+		}
+	case "images/":
+		if file != "" && file != ".." {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,35 @@
+package api
+
+import "testing"
+
+func TestPublicOutputFile(t *testing.T) {
+	const taskID = "0b6f1d2e-8c1a-4f5e-9d3b-2a7c4e6f8a90"
+	cases := []struct {
+		path string
+		ok   bool
+	}{
+		{"/report_1-5.pdf", true},
+		{"/report_6.pdf", true},
+		{"/images/fig1.jpg", true},
+		{"/result.md", false},
+		{"/result.json", false},
+		{"/page_1.md", false},
+		{"/report_1-5.docx", false},
+		{"/translations/en.md", false},
+		{"/layout.json", false},
+		{"/content_list.json", false},
+		{"/report_1-5.layout.json", false},
+		{"/report_1-5.content_list.json", false},
+		{"/images/../result.md", false},
+		{"/images/", false},
+		{"/", false},
+	}
+	for _, tc := range cases {
+		if _, ok := publicOutputFile(taskID, tc.path); ok != tc.ok {
+			t.Fatalf("publicOutputFile(%q) ok = %v, want %v", tc.path, ok, tc.ok)
+		}
+	}
+	if _, ok := publicOutputFile("..", "/report_1.pdf"); ok {
+		t.Fatalf("task id must be a uuid")
+	}
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	prompt "github.com/neyuki778/LLM-PDF-OCR/internal/prompt"
+)
+
+// listPrompts 处理 GET /api/prompts - 当前用户的提示词模板
+func (s *Server) listPrompts(c *gin.Context) {
+	userID, ok := s.requireUserID(c, "listPrompts")
+	if !ok {
+		return
+	}
+
+	templates, err := s.promptStore.ListTemplates(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("[prompt] list templates failed user_id=%s err=%v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list prompt templates"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(templates))
+	for _, tpl := range templates {
+		items = append(items, promptResponse(tpl))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// createPrompt 处理 POST /api/prompts
+func (s *Server) createPrompt(c *gin.Context) {
+	userID, ok := s.requireUserID(c, "createPrompt")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name    string `json:"name"`
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	tpl, err := s.promptStore.CreateTemplate(c.Request.Context(), userID, req.Name, req.Content)
+	if err != nil {
+		s.writePromptError(c, userID, "create", err)
+		return
+	}
+
+	log.Printf("[prompt] template created user_id=%s template_id=%d name=%s", userID, tpl.ID, tpl.Name)
+	c.JSON(http.StatusCreated, promptResponse(*tpl))
+}
+
+// updatePrompt 处理 PATCH /api/prompts/:id - 修改名称或内容
+func (s *Server) updatePrompt(c *gin.Context) {
+	userID, ok := s.requireUserID(c, "updatePrompt")
+	if !ok {
+		return
+	}
+	id, ok := parsePromptID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name    *string `json:"name"`
+		Content *string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	tpl, err := s.promptStore.UpdateTemplate(c.Request.Context(), userID, id, prompt.TemplateUpdate{
+		Name:    req.Name,
+		Content: req.Content,
+	})
+	if err != nil {
+		s.writePromptError(c, userID, "update", err)
+		return
+	}
+	c.JSON(http.StatusOK, promptResponse(*tpl))
+}
+
+// deletePrompt 处理 DELETE /api/prompts/:id
+func (s *Server) deletePrompt(c *gin.Context) {
+	userID, ok := s.requireUserID(c, "deletePrompt")
+	if !ok {
+		return
+	}
+	id, ok := parsePromptID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.promptStore.DeleteTemplate(c.Request.Context(), userID, id); err != nil {
+		s.writePromptError(c, userID, "delete", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "prompt template deleted"})
+}
+
+// resolveTaskPrompt 根据 createTask 表单字段（prompt_template、prompt、language、document_type）生成最终提示词
+// base 为 profile 自带的提示词，用户模板优先于它
+func (s *Server) resolveTaskPrompt(c *gin.Context, userID, base string) (string, int, string) {
+	templateName := strings.TrimSpace(c.PostForm("prompt_template"))
+	instruction := c.PostForm("prompt")
+	vars := prompt.Vars{
+		Language:     c.PostForm("language"),
+		DocumentType: c.PostForm("document_type"),
+	}
+
+	if templateName != "" {
+		if s.promptStore == nil {
+			return "", http.StatusServiceUnavailable, "prompt templates are not configured"
+		}
+		if userID == "" {
+			return "", http.StatusUnauthorized, "login required to use prompt templates"
+		}
+		tpl, err := s.promptStore.GetTemplateByName(c.Request.Context(), userID, templateName)
+		if err != nil {
+			if errors.Is(err, prompt.ErrNotFound) {
+				return "", http.StatusBadRequest, "prompt template not found: " + templateName
+			}
+			log.Printf("[prompt] load template failed user_id=%s name=%s err=%v", userID, templateName, err)
+			return "", http.StatusInternalServerError, "failed to load prompt template"
+		}
+		base = tpl.Content
+	}
+
+	composed, err := prompt.Compose(base, instruction, vars)
+	if err != nil {
+		return "", http.StatusBadRequest, err.Error()
+	}
+	return composed, 0, ""
+}
+
+// requireUserID 要求登录，游客返回 401
+func (s *Server) requireUserID(c *gin.Context, scene string) (string, bool) {
+	userID, statusCode, errMsg := s.resolveRequesterUserID(c, scene)
+	if statusCode != 0 {
+		c.JSON(statusCode, gin.H{"error": errMsg})
+		return "", false
+	}
+	if strings.TrimSpace(userID) == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return "", false
+	}
+	return userID, true
+}
+
+func (s *Server) writePromptError(c *gin.Context, userID, op string, err error) {
+	switch {
+	case errors.Is(err, prompt.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, prompt.ErrNameExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, prompt.ErrEmptyName),
+		errors.Is(err, prompt.ErrNameTooLong),
+		errors.Is(err, prompt.ErrEmptyContent),
+		errors.Is(err, prompt.ErrContentTooLong),
+		errors.Is(err, prompt.ErrUnknownVariable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("[prompt] %s template failed user_id=%s err=%v", op, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + op + " prompt template"})
+	}
+}
+
+func parsePromptID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prompt template id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func promptResponse(tpl prompt.Template) gin.H {
+	return gin.H{
+		"id":         tpl.ID,
+		"name":       tpl.Name,
+		"content":    tpl.Content,
+		"created_at": tpl.CreatedAt.Unix(),
+		"updated_at": tpl.UpdatedAt.Unix(),
+	}
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	auth "github.com/neyuki778/LLM-PDF-OCR/internal/auth"
+	keystore "github.com/neyuki778/LLM-PDF-OCR/internal/keystore"
+	prompt "github.com/neyuki778/LLM-PDF-OCR/internal/prompt"
 	task "github.com/neyuki778/LLM-PDF-OCR/internal/task"
 )
 
@@ -10,6 +14,15 @@ type TaskQuotaConfig struct {
 	GuestMaxPages int
 	UserMaxPages  int
 	HardMaxPages  int
+
+	// 文档问答每分钟提问次数（按请求方）
+	GuestAsksPerMinute int
+	UserAsksPerMinute  int
+}
+
+type AdminConfig struct {
+	Emails               []string // 允许访问 /api/admin 的用户邮箱
+	KeyDailyRequestLimit int      // 单个 key 每日请求配额，用于用量报表计算占比，0 表示不展示
 }
 
 // Server 封装 Gin 引擎和依赖
@@ -17,25 +30,48 @@ type Server struct {
 	router           *gin.Engine
 	taskManager      *task.TaskManager
 	authService      *auth.Service
+	keyStore         *keystore.Store
+	promptStore      *prompt.Store
 	authCookieSecure bool
 	taskQuota        TaskQuotaConfig
+	askLimiter       *askLimiter
+	adminEmails      map[string]bool
+
+	keyDailyRequestLimit int
 }
 
 // NewServer 创建 API 服务器实例
 func NewServer(
 	tm *task.TaskManager,
 	authService *auth.Service,
+	keyStore *keystore.Store,
+	promptStore *prompt.Store,
 	authCookieSecure bool,
 	taskQuota TaskQuotaConfig,
+	admin AdminConfig,
 ) *Server {
 	r := gin.Default() // 自带 Logger 和 Recovery 中间件
 
+	adminEmails := make(map[string]bool, len(admin.Emails))
+	for _, email := range admin.Emails {
+		if clean := strings.ToLower(strings.TrimSpace(email)); clean != "" {
+			adminEmails[clean] = true
+		}
+	}
+
+	taskQuota = normalizeTaskQuotaConfig(taskQuota)
 	s := &Server{
 		router:           r,
 		taskManager:      tm,
 		authService:      authService,
+		keyStore:         keyStore,
+		promptStore:      promptStore,
 		authCookieSecure: authCookieSecure,
-		taskQuota:        normalizeTaskQuotaConfig(taskQuota),
+		taskQuota:        taskQuota,
+		askLimiter:       newAskLimiter(taskQuota),
+		adminEmails:      adminEmails,
+
+		keyDailyRequestLimit: admin.KeyDailyRequestLimit,
 	}
 
 	s.setupRoutes()
@@ -54,10 +90,20 @@ func (s *Server) setupRoutes() {
 		api.POST("/tasks", s.createTask) // 上传 PDF，创建任务
 		api.GET("/tasks/history", s.getTaskHistory)
 		api.GET("/tasks/:id", s.getTask) // 查询任务状态
+		api.GET("/profiles", s.listProfiles)
+		api.GET("/models", s.listModels)
+		api.GET("/usage", s.getUsage) // 当前用户月度用量
 
 		// Phase 4.2
-		api.GET("/tasks/:id/result", s.getResult) // 下载结果
-		api.DELETE("/tasks/:id", s.deleteTask)    // 删除任务
+		api.GET("/tasks/:id/result", s.getResult)           // 下载结果
+		api.GET("/tasks/:id/preview", s.getPreview)         // 处理中的实时预览
+		api.GET("/tasks/:id/layout", s.getLayout)           // MinerU 版面信息
+		api.GET("/tasks/:id/exports/:format", s.getExports) // MinerU extra_formats 导出文件
+		api.POST("/tasks/:id/ask", s.askTask)               // 基于结果回答问题
+		api.DELETE("/tasks/:id", s.deleteTask)              // 删除任务
+
+		// MinerU 回调模式的结果推送，按 seed 签名校验，不走登录鉴权
+		api.POST("/callbacks/mineru", s.mineruCallback)
 
 		authGroup := api.Group("/auth")
 		authGroup.Use(s.requireAuthService())
@@ -68,6 +114,30 @@ func (s *Server) setupRoutes() {
 			authGroup.POST("/refresh", s.refresh)
 			authGroup.GET("/me", s.me)
 		}
+
+		prompts := api.Group("/prompts")
+		prompts.Use(s.requirePromptStore())
+		{
+			prompts.GET("", s.listPrompts)
+			prompts.POST("", s.createPrompt)
+			prompts.PATCH("/:id", s.updatePrompt)
+			prompts.DELETE("/:id", s.deletePrompt)
+		}
+
+		adminGroup := api.Group("/admin")
+		adminGroup.Use(s.requireAdmin())
+		{
+			adminGroup.GET("/usage", s.listUsage)
+			adminGroup.POST("/config/reload", s.reloadConfig)
+
+			keys := adminGroup.Group("/keys")
+			keys.Use(s.requireKeyStore())
+			keys.GET("", s.listKeys)
+			keys.GET("/usage", s.getKeyUsage)
+			keys.POST("", s.addKey)
+			keys.PATCH("/:id", s.updateKey)
+			keys.DELETE("/:id", s.deleteKey)
+		}
 	}
 
 	// 静态文件服务
@@ -75,7 +145,8 @@ func (s *Server) setupRoutes() {
 	s.router.StaticFile("/auth", "./web/auth.html")
 	s.router.StaticFile("/style.css", "./web/style.css")
 	s.router.Static("/dist", "./web/dist")
-	s.router.Static("/output", "./output") // 暴露分片后的 PDF 供 LLM-API 提供商拉取
+	s.router.GET("/output/:id/*path", s.serveOutputFile) // 只暴露分片 PDF 和结果引用的图片
+	s.router.HEAD("/output/:id/*path", s.serveOutputFile)
 }
 
 // Run 启动 HTTP 服务
@@ -93,5 +164,11 @@ func normalizeTaskQuotaConfig(cfg TaskQuotaConfig) TaskQuotaConfig {
 	if cfg.HardMaxPages <= 0 {
 		cfg.HardMaxPages = 100
 	}
+	if cfg.GuestAsksPerMinute <= 0 {
+		cfg.GuestAsksPerMinute = 2
+	}
+	if cfg.UserAsksPerMinute <= 0 {
+		cfg.UserAsksPerMinute = 10
+	}
 	return cfg
 }
@@ -0,0 +1,154 @@
+package keystore
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	secret "github.com/neyuki778/LLM-PDF-OCR/internal/secret"
+)
+
+// seal encrypts plaintext when the store has a cipher.
+func (s *Store) seal(plaintext string) (string, error) {
+	if s.cipher == nil {
+		return plaintext, nil
+	}
+	encrypted, err := s.cipher.Encrypt(plaintext)
+	if err != nil {
+		return "", fmt.Errorf("encrypt key: %w", err)
+	}
+	return encrypted, nil
+}
+
+// Reveal returns the plaintext of key.Key using the store's cipher.
+func (s *Store) Reveal(key Key) (string, error) {
+	return secret.Reveal(s.cipher, key.Key)
+}
+
+// RotateMasterKey re-encrypts every key with next in one transaction and
+// switches the store to next. Rows must be plaintext or encrypted with the
+// store's current cipher. Intended for offline use (keyctl), not a live server.
+func (s *Store) RotateMasterKey(ctx context.Context, next *secret.Cipher) (int, error) {
+	if next == nil {
+		return 0, fmt.Errorf("rotate master key: new cipher is nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx for rotate master key: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := loadStoredKeys(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		plaintext, err := secret.Reveal(s.cipher, row.value)
+		if err != nil {
+			return 0, fmt.Errorf("decrypt key %d: %w", row.id, err)
+		}
+		encrypted, err := next.Encrypt(plaintext)
+		if err != nil {
+			return 0, fmt.Errorf("encrypt key %d: %w", row.id, err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE gemini_keys SET key = ? WHERE id = ?;`, encrypted, row.id); err != nil {
+			return 0, fmt.Errorf("update key %d: %w", row.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit rotate master key: %w", err)
+	}
+	s.cipher = next
+	return len(rows), nil
+}
+
+// backfillKeys fills key_hash/key_hint for rows created before v4 and, when a
+// cipher is configured, encrypts rows still stored in plaintext.
+func backfillKeys(db *sql.DB, c *secret.Cipher) error {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("backfill keys: begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := loadStoredKeys(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("backfill keys: %w", err)
+	}
+	for _, row := range rows {
+		encrypted := secret.IsEncrypted(row.value)
+		needsHash := !row.hasHash
+		needsEncrypt := c != nil && !encrypted
+		if !needsHash && !needsEncrypt {
+			continue
+		}
+		if encrypted {
+			// 已加密但缺少 hash 的行只会来自外部写入，无法在没有明文的情况下补齐
+			continue
+		}
+
+		stored := row.value
+		if needsEncrypt {
+			if stored, err = c.Encrypt(row.value); err != nil {
+				return fmt.Errorf("backfill keys: encrypt key %d: %w", row.id, err)
+			}
+		}
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE gemini_keys SET key = ?, key_hash = ?, key_hint = ? WHERE id = ?;`,
+			stored,
+			hashKey(row.value),
+			MaskKey(row.value),
+			row.id,
+		); err != nil {
+			return fmt.Errorf("backfill keys: update key %d: %w", row.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("backfill keys: commit: %w", err)
+	}
+	return nil
+}
+
+type storedKey struct {
+	id      int64
+	value   string
+	hasHash bool
+}
+
+func loadStoredKeys(ctx context.Context, tx *sql.Tx) ([]storedKey, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, key, key_hash IS NOT NULL FROM gemini_keys ORDER BY id ASC;`)
+	if err != nil {
+		return nil, fmt.Errorf("load keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]storedKey, 0, 8)
+	for rows.Next() {
+		var key storedKey
+		if err := rows.Scan(&key.id, &key.value, &key.hasHash); err != nil {
+			return nil, fmt.Errorf("scan key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load keys rows: %w", err)
+	}
+	return keys, nil
+}
+
+// hashKey returns the lookup hash used for uniqueness without storing plaintext.
+func hashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -10,11 +10,15 @@ import (
 	"strings"
 	"time"
 
+	secret "github.com/neyuki778/LLM-PDF-OCR/internal/secret"
 	_ "modernc.org/sqlite"
 )
 
 const (
 	defaultBusyTimeoutMS = 5000
+
+	keyColumns = `id, key, key_hint, note, enabled, created_at, updated_at, last_used_at, error_count,
+		request_count, tokens_used, last_error, last_error_at, consecutive_failures, cooldown_until`
 )
 
 var (
@@ -26,12 +30,17 @@ var (
 )
 
 // Store manages Gemini API keys in SQLite.
+// When a cipher is configured, key material is stored AES-GCM encrypted and
+// Key.Key holds the ciphertext; decrypt it with secret.Reveal where the key is used.
 type Store struct {
-	db *sql.DB
+	db     *sql.DB
+	cipher *secret.Cipher
 }
 
 // NewStore opens (or creates) the sqlite database at dbPath and runs migrations.
-func NewStore(dbPath string) (*Store, error) {
+// c may be nil, in which case keys are stored in plaintext; with a cipher,
+// existing plaintext rows are encrypted on open.
+func NewStore(dbPath string, c *secret.Cipher) (*Store, error) {
 	cleanPath := strings.TrimSpace(dbPath)
 	if cleanPath == "" {
 		return nil, ErrEmptyPath
@@ -62,8 +71,12 @@ func NewStore(dbPath string) (*Store, error) {
 		_ = db.Close()
 		return nil, err
 	}
+	if err := backfillKeys(db, c); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 
-	return &Store{db: db}, nil
+	return &Store{db: db, cipher: c}, nil
 }
 
 // Close releases database resources.
@@ -81,12 +94,19 @@ func (s *Store) AddKey(ctx context.Context, key, note string) (*Key, error) {
 		return nil, ErrEmptyKey
 	}
 
+	stored, err := s.seal(cleanKey)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now().UTC().Unix()
 	result, err := s.db.ExecContext(
 		ctx,
-		`INSERT INTO gemini_keys (key, note, enabled, created_at, updated_at)
-		 VALUES (?, ?, 1, ?, ?);`,
-		cleanKey,
+		`INSERT INTO gemini_keys (key, key_hash, key_hint, note, enabled, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, 1, ?, ?);`,
+		stored,
+		hashKey(cleanKey),
+		MaskKey(cleanKey),
 		note,
 		now,
 		now,
@@ -101,7 +121,8 @@ func (s *Store) AddKey(ctx context.Context, key, note string) (*Key, error) {
 	id, _ := result.LastInsertId()
 	return &Key{
 		ID:        id,
-		Key:       cleanKey,
+		Key:       stored,
+		Hint:      MaskKey(cleanKey),
 		Note:      note,
 		Enabled:   true,
 		CreatedAt: time.Unix(now, 0).UTC(),
@@ -109,9 +130,17 @@ func (s *Store) AddKey(ctx context.Context, key, note string) (*Key, error) {
 	}, nil
 }
 
-// DeleteKey removes a key by id.
+// DeleteKey removes a key and its usage history by id.
 func (s *Store) DeleteKey(ctx context.Context, id int64) error {
-	result, err := s.db.ExecContext(ctx, `DELETE FROM gemini_keys WHERE id = ?;`, id)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx for delete key: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM gemini_keys WHERE id = ?;`, id)
 	if err != nil {
 		return fmt.Errorf("delete key: %w", err)
 	}
@@ -122,12 +151,65 @@ func (s *Store) DeleteKey(ctx context.Context, id int64) error {
 	if rows == 0 {
 		return ErrNotFound
 	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM gemini_key_daily_usage WHERE key_id = ?;`, id); err != nil {
+		return fmt.Errorf("delete key usage: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit delete key: %w", err)
+	}
 	return nil
 }
 
+// UpdateKey changes the note and/or enabled flag of a key; nil fields are left untouched.
+func (s *Store) UpdateKey(ctx context.Context, id int64, update KeyUpdate) (*Key, error) {
+	sets := make([]string, 0, 3)
+	args := make([]any, 0, 4)
+	if update.Note != nil {
+		sets = append(sets, "note = ?")
+		args = append(args, *update.Note)
+	}
+	if update.Enabled != nil {
+		enabled := 0
+		if *update.Enabled {
+			enabled = 1
+		}
+		sets = append(sets, "enabled = ?")
+		args = append(args, enabled)
+	}
+	if len(sets) == 0 {
+		return s.GetKey(ctx, id)
+	}
+	sets = append(sets, "updated_at = ?")
+	args = append(args, time.Now().UTC().Unix(), id)
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE gemini_keys SET `+strings.Join(sets, ", ")+` WHERE id = ?;`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("update key: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("update key rows: %w", err)
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+	return s.GetKey(ctx, id)
+}
+
+// SetKeyEnabled enables or disables a key by id.
+func (s *Store) SetKeyEnabled(ctx context.Context, id int64, enabled bool) error {
+	_, err := s.UpdateKey(ctx, id, KeyUpdate{Enabled: &enabled})
+	return err
+}
+
 // GetKey returns a key by id.
 func (s *Store) GetKey(ctx context.Context, id int64) (*Key, error) {
-	row := s.db.QueryRowContext(ctx, `SELECT id, key, note, enabled, created_at, updated_at FROM gemini_keys WHERE id = ?;`, id)
+	row := s.db.QueryRowContext(ctx, `SELECT `+keyColumns+` FROM gemini_keys WHERE id = ?;`, id)
 	key, err := scanKey(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -166,7 +248,7 @@ func (s *Store) CountEnabledKeys(ctx context.Context) (int, error) {
 }
 
 func (s *Store) listKeys(ctx context.Context, enabledOnly bool) ([]Key, error) {
-	query := `SELECT id, key, note, enabled, created_at, updated_at FROM gemini_keys`
+	query := `SELECT ` + keyColumns + ` FROM gemini_keys`
 	if enabledOnly {
 		query += ` WHERE enabled = 1`
 	}
@@ -192,8 +274,11 @@ func (s *Store) listKeys(ctx context.Context, enabledOnly bool) ([]Key, error) {
 	return keys, nil
 }
 
-func migrate(db *sql.DB) error {
-	stmts := []string{
+// migrations are applied in order; the index+1 of each entry is its schema version.
+// Never edit an existing entry, append a new one instead.
+var migrations = [][]string{
+	// v1: base table
+	{
 		`CREATE TABLE IF NOT EXISTS gemini_keys (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			key TEXT NOT NULL UNIQUE,
@@ -203,37 +288,162 @@ func migrate(db *sql.DB) error {
 			updated_at INTEGER NOT NULL
 		);`,
 		`CREATE INDEX IF NOT EXISTS idx_gemini_keys_enabled ON gemini_keys(enabled);`,
+	},
+	// v2: usage display for admin listings
+	{
+		`ALTER TABLE gemini_keys ADD COLUMN last_used_at INTEGER;`,
+		`ALTER TABLE gemini_keys ADD COLUMN error_count INTEGER NOT NULL DEFAULT 0;`,
+	},
+	// v3: per-key usage accounting and health tracking
+	{
+		`ALTER TABLE gemini_keys ADD COLUMN request_count INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE gemini_keys ADD COLUMN tokens_used INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE gemini_keys ADD COLUMN last_error TEXT;`,
+		`ALTER TABLE gemini_keys ADD COLUMN last_error_at INTEGER;`,
+		`ALTER TABLE gemini_keys ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE gemini_keys ADD COLUMN cooldown_until INTEGER;`,
+		`CREATE TABLE IF NOT EXISTS gemini_key_daily_usage (
+			key_id INTEGER NOT NULL,
+			day TEXT NOT NULL,
+			request_count INTEGER NOT NULL DEFAULT 0,
+			tokens_used INTEGER NOT NULL DEFAULT 0,
+			error_count INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (key_id, day)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_gemini_key_daily_usage_day ON gemini_key_daily_usage(day);`,
+	},
+	// v4: encryption at rest; uniqueness and display move to hash/hint columns,
+	// existing rows are filled by backfillKeys
+	{
+		`ALTER TABLE gemini_keys ADD COLUMN key_hash TEXT;`,
+		`ALTER TABLE gemini_keys ADD COLUMN key_hint TEXT;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_gemini_keys_key_hash ON gemini_keys(key_hash);`,
+	},
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS gemini_keys_schema (version INTEGER NOT NULL);`); err != nil {
+		return fmt.Errorf("migrate: %w", err)
 	}
 
-	for _, stmt := range stmts {
-		if _, err := db.Exec(stmt); err != nil {
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM gemini_keys_schema;`).Scan(&version); err != nil {
+		return fmt.Errorf("migrate: read schema version: %w", err)
+	}
+	if version == 0 {
+		legacy, err := legacyVersion(db)
+		if err != nil {
 			return fmt.Errorf("migrate: %w", err)
 		}
+		if legacy > 0 {
+			if _, err := db.Exec(`INSERT INTO gemini_keys_schema (version) VALUES (?);`, legacy); err != nil {
+				return fmt.Errorf("migrate: record legacy version: %w", err)
+			}
+			version = legacy
+		}
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("migrate v%d: begin: %w", i+1, err)
+		}
+		for _, stmt := range migrations[i] {
+			if _, err := tx.Exec(stmt); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("migrate v%d: %w", i+1, err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO gemini_keys_schema (version) VALUES (?);`, i+1); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migrate v%d: record version: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migrate v%d: commit: %w", i+1, err)
+		}
 	}
 	return nil
 }
 
+// legacyVersion maps a database created before versioned migrations to its schema version:
+// 0 for an empty database, 1 for the base table, 2 once the usage display columns exist.
+func legacyVersion(db *sql.DB) (int, error) {
+	var columns int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('gemini_keys');`).Scan(&columns); err != nil {
+		return 0, fmt.Errorf("inspect legacy schema: %w", err)
+	}
+	if columns == 0 {
+		return 0, nil
+	}
+	var usageColumns int
+	if err := db.QueryRow(
+		`SELECT COUNT(*) FROM pragma_table_info('gemini_keys') WHERE name IN ('last_used_at', 'error_count');`,
+	).Scan(&usageColumns); err != nil {
+		return 0, fmt.Errorf("inspect legacy schema: %w", err)
+	}
+	if usageColumns == 2 {
+		return 2, nil
+	}
+	return 1, nil
+}
+
 type rowScanner interface {
 	Scan(dest ...any) error
 }
 
 func scanKey(scanner rowScanner) (Key, error) {
 	var (
-		key       Key
-		enabled   int
-		createdAt int64
-		updatedAt int64
+		key           Key
+		hint          sql.NullString
+		note          sql.NullString
+		enabled       int
+		createdAt     int64
+		updatedAt     int64
+		lastUsedAt    sql.NullInt64
+		lastError     sql.NullString
+		lastErrorAt   sql.NullInt64
+		cooldownUntil sql.NullInt64
 	)
 
-	if err := scanner.Scan(&key.ID, &key.Key, &key.Note, &enabled, &createdAt, &updatedAt); err != nil {
+	if err := scanner.Scan(
+		&key.ID,
+		&key.Key,
+		&hint,
+		&note,
+		&enabled,
+		&createdAt,
+		&updatedAt,
+		&lastUsedAt,
+		&key.ErrorCount,
+		&key.RequestCount,
+		&key.TokensUsed,
+		&lastError,
+		&lastErrorAt,
+		&key.ConsecutiveFailures,
+		&cooldownUntil,
+	); err != nil {
 		return Key{}, err
 	}
+	key.Hint = hint.String
+	key.Note = note.String
 	key.Enabled = enabled == 1
 	key.CreatedAt = time.Unix(createdAt, 0).UTC()
 	key.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	key.LastUsedAt = unixPtr(lastUsedAt)
+	key.LastError = lastError.String
+	key.LastErrorAt = unixPtr(lastErrorAt)
+	key.CooldownUntil = unixPtr(cooldownUntil)
 	return key, nil
 }
 
+func unixPtr(value sql.NullInt64) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t := time.Unix(value.Int64, 0).UTC()
+	return &t
+}
+
 func isUniqueConstraint(err error) bool {
 	if err == nil {
 		return false
@@ -0,0 +1,262 @@
+package keystore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	secret "github.com/neyuki778/LLM-PDF-OCR/internal/secret"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "keys.db"), nil)
+	if err != nil {
+		t.Fatalf("new keystore: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
+func TestStore_UpdateKey(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	key, err := store.AddKey(ctx, "AIza-test-key-0001", "team a")
+	if err != nil {
+		t.Fatalf("add key: %v", err)
+	}
+
+	disabled := false
+	note := "team b"
+	updated, err := store.UpdateKey(ctx, key.ID, KeyUpdate{Note: &note, Enabled: &disabled})
+	if err != nil {
+		t.Fatalf("update key: %v", err)
+	}
+	if updated.Enabled || updated.Note != "team b" {
+		t.Fatalf("unexpected key after update: enabled=%t note=%s", updated.Enabled, updated.Note)
+	}
+
+	if _, err := store.ListEnabledKeys(ctx); !errors.Is(err, ErrNoKeys) {
+		t.Fatalf("expected ErrNoKeys, got %v", err)
+	}
+
+	if _, err := store.UpdateKey(ctx, 999, KeyUpdate{Enabled: &disabled}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStore_RecordKeyResult(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	key, err := store.AddKey(ctx, "AIza-test-key-0002", "")
+	if err != nil {
+		t.Fatalf("add key: %v", err)
+	}
+	if key.LastUsedAt != nil {
+		t.Fatalf("new key should not have last_used_at")
+	}
+
+	if err := store.RecordKeyResult(ctx, key.ID, CallResult{Tokens: 120}); err != nil {
+		t.Fatalf("record success: %v", err)
+	}
+	cooldown := time.Now().Add(time.Minute)
+	if err := store.RecordKeyResult(ctx, key.ID, CallResult{Err: errors.New("boom"), CooldownUntil: cooldown}); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+
+	got, err := store.GetKey(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("get key: %v", err)
+	}
+	if got.LastUsedAt == nil {
+		t.Fatalf("last_used_at should be set")
+	}
+	if got.ErrorCount != 1 {
+		t.Fatalf("expected error_count=1, got %d", got.ErrorCount)
+	}
+	if got.RequestCount != 2 || got.TokensUsed != 120 {
+		t.Fatalf("unexpected usage: requests=%d tokens=%d", got.RequestCount, got.TokensUsed)
+	}
+	if got.ConsecutiveFailures != 1 || got.LastError != "boom" || got.LastErrorAt == nil {
+		t.Fatalf("unexpected health: failures=%d last_error=%q", got.ConsecutiveFailures, got.LastError)
+	}
+	if !got.CoolingDown(time.Now()) {
+		t.Fatalf("key should be cooling down")
+	}
+
+	// 成功调用清零连续失败次数，但不清除冷却
+	if err := store.RecordKeyResult(ctx, key.ID, CallResult{Tokens: 30}); err != nil {
+		t.Fatalf("record success: %v", err)
+	}
+	got, err = store.GetKey(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("get key: %v", err)
+	}
+	if got.ConsecutiveFailures != 0 {
+		t.Fatalf("consecutive failures should reset, got %d", got.ConsecutiveFailures)
+	}
+
+	usage, err := store.ListDailyUsage(ctx, 1)
+	if err != nil {
+		t.Fatalf("list daily usage: %v", err)
+	}
+	if len(usage) != 1 {
+		t.Fatalf("expected 1 daily usage row, got %d", len(usage))
+	}
+	if usage[0].RequestCount != 3 || usage[0].TokensUsed != 150 || usage[0].ErrorCount != 1 {
+		t.Fatalf("unexpected daily usage: %+v", usage[0])
+	}
+	if usage[0].MaskedKey == "AIza-test-key-0002" {
+		t.Fatalf("daily usage must not expose raw key")
+	}
+}
+
+func TestStore_MigrateIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.db")
+	first, err := NewStore(path, nil)
+	if err != nil {
+		t.Fatalf("first open: %v", err)
+	}
+	if _, err := first.AddKey(context.Background(), "AIza-test-key-0003", ""); err != nil {
+		t.Fatalf("add key: %v", err)
+	}
+	_ = first.Close()
+
+	second, err := NewStore(path, nil)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer second.Close()
+
+	keys, err := second.ListKeys(context.Background())
+	if err != nil {
+		t.Fatalf("list keys: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 key after reopen, got %d", len(keys))
+	}
+}
+
+func TestStore_EncryptionAtRest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.db")
+	ctx := context.Background()
+
+	plain, err := NewStore(path, nil)
+	if err != nil {
+		t.Fatalf("new plaintext keystore: %v", err)
+	}
+	if _, err := plain.AddKey(ctx, "AIza-legacy-key-0001", "legacy"); err != nil {
+		t.Fatalf("add legacy key: %v", err)
+	}
+	_ = plain.Close()
+
+	first := newTestCipher(t)
+	store, err := NewStore(path, first)
+	if err != nil {
+		t.Fatalf("reopen with cipher: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.AddKey(ctx, "AIza-new-key-0002", ""); err != nil {
+		t.Fatalf("add encrypted key: %v", err)
+	}
+	if _, err := store.AddKey(ctx, "AIza-legacy-key-0001", ""); !errors.Is(err, ErrKeyExists) {
+		t.Fatalf("expected ErrKeyExists for duplicate key, got %v", err)
+	}
+
+	assertKeys := func(c *secret.Cipher) {
+		t.Helper()
+		keys, err := store.ListKeys(ctx)
+		if err != nil {
+			t.Fatalf("list keys: %v", err)
+		}
+		want := []string{"AIza-legacy-key-0001", "AIza-new-key-0002"}
+		if len(keys) != len(want) {
+			t.Fatalf("expected %d keys, got %d", len(want), len(keys))
+		}
+		for i, key := range keys {
+			if !secret.IsEncrypted(key.Key) {
+				t.Fatalf("key %d stored in plaintext", key.ID)
+			}
+			if key.Masked() != MaskKey(want[i]) {
+				t.Fatalf("unexpected hint for key %d: %s", key.ID, key.Masked())
+			}
+			got, err := secret.Reveal(c, key.Key)
+			if err != nil || got != want[i] {
+				t.Fatalf("decrypt key %d: got %q err=%v", key.ID, got, err)
+			}
+		}
+	}
+	assertKeys(first)
+
+	second := newTestCipher(t)
+	count, err := store.RotateMasterKey(ctx, second)
+	if err != nil {
+		t.Fatalf("rotate master key: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 rotated keys, got %d", count)
+	}
+	assertKeys(second)
+}
+
+func newTestCipher(t *testing.T) *secret.Cipher {
+	t.Helper()
+	encoded, err := secret.GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("generate master key: %v", err)
+	}
+	c, err := secret.NewCipher(encoded)
+	if err != nil {
+		t.Fatalf("new cipher: %v", err)
+	}
+	return c
+}
+
+func TestStore_MigratesUnversionedDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	// schema written before versioned migrations: base table plus usage display columns
+	for _, stmt := range []string{
+		`CREATE TABLE gemini_keys (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key TEXT NOT NULL UNIQUE,
+			note TEXT,
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			last_used_at INTEGER,
+			error_count INTEGER NOT NULL DEFAULT 0
+		);`,
+		`INSERT INTO gemini_keys (key, enabled, created_at, updated_at) VALUES ('AIza-test-key-0004', 1, 0, 0);`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed legacy schema: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	store, err := NewStore(path, nil)
+	if err != nil {
+		t.Fatalf("open legacy database: %v", err)
+	}
+	defer store.Close()
+
+	keys, err := store.ListKeys(context.Background())
+	if err != nil {
+		t.Fatalf("list keys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].RequestCount != 0 {
+		t.Fatalf("unexpected keys after migration: %+v", keys)
+	}
+}
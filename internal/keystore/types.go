@@ -3,16 +3,70 @@ package keystore
 import (
 	"strings"
 	"time"
+
+	secret "github.com/neyuki778/LLM-PDF-OCR/internal/secret"
 )
 
 // Key represents a Gemini API key record stored in SQLite.
 type Key struct {
-	ID        int64
-	Key       string
-	Note      string
-	Enabled   bool
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         int64
+	Key        string // stored value: ciphertext when the store has a cipher, see secret.Reveal
+	Hint       string // masked plaintext for display
+	Note       string
+	Enabled    bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	LastUsedAt *time.Time // nil if the key has never been used
+	ErrorCount int64
+
+	// Usage and health, updated after every provider call.
+	RequestCount        int64
+	TokensUsed          int64
+	LastError           string
+	LastErrorAt         *time.Time
+	ConsecutiveFailures int64
+	CooldownUntil       *time.Time // key should not be used before this time
+}
+
+// CoolingDown reports whether the key is in cooldown at now.
+func (k Key) CoolingDown(now time.Time) bool {
+	return k.CooldownUntil != nil && now.Before(*k.CooldownUntil)
+}
+
+// CallResult describes the outcome of one provider call made with a key.
+type CallResult struct {
+	Tokens        int64     // total tokens consumed by the call
+	Err           error     // nil on success
+	CooldownUntil time.Time // zero keeps the current cooldown
+}
+
+// DailyUsage is one row of the per-key daily usage report.
+type DailyUsage struct {
+	KeyID        int64
+	MaskedKey    string
+	Note         string
+	Enabled      bool
+	Day          string // YYYY-MM-DD in UTC
+	RequestCount int64
+	TokensUsed   int64
+	ErrorCount   int64
+}
+
+// KeyUpdate lists the mutable fields of a key; nil fields are left untouched.
+type KeyUpdate struct {
+	Note    *string
+	Enabled *bool
+}
+
+// Masked returns the display form of the key and never the raw key.
+func (k Key) Masked() string {
+	if k.Hint != "" {
+		return k.Hint
+	}
+	if secret.IsEncrypted(k.Key) {
+		return "****"
+	}
+	return MaskKey(k.Key)
 }
 
 // MaskKey hides most of the key for display purposes.
@@ -0,0 +1,138 @@
+package keystore
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	usageDayLayout   = "2006-01-02"
+	maxLastErrorSize = 500
+)
+
+// RecordKeyResult updates usage counters, health fields and today's usage row for a key.
+func (s *Store) RecordKeyResult(ctx context.Context, id int64, result CallResult) error {
+	now := time.Now().UTC()
+	failed := 0
+	var lastError any
+	var lastErrorAt any
+	if result.Err != nil {
+		failed = 1
+		msg := result.Err.Error()
+		if len(msg) > maxLastErrorSize {
+			msg = msg[:maxLastErrorSize]
+		}
+		lastError = msg
+		lastErrorAt = now.Unix()
+	}
+	var cooldownUntil any
+	if !result.CooldownUntil.IsZero() {
+		cooldownUntil = result.CooldownUntil.UTC().Unix()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx for record key result: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE gemini_keys SET
+			last_used_at = ?,
+			request_count = request_count + 1,
+			tokens_used = tokens_used + ?,
+			error_count = error_count + ?,
+			consecutive_failures = CASE WHEN ? = 1 THEN consecutive_failures + 1 ELSE 0 END,
+			last_error = COALESCE(?, last_error),
+			last_error_at = COALESCE(?, last_error_at),
+			cooldown_until = COALESCE(?, cooldown_until)
+		 WHERE id = ?;`,
+		now.Unix(),
+		result.Tokens,
+		failed,
+		failed,
+		lastError,
+		lastErrorAt,
+		cooldownUntil,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("record key result: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO gemini_key_daily_usage (key_id, day, request_count, tokens_used, error_count)
+		 VALUES (?, ?, 1, ?, ?)
+		 ON CONFLICT(key_id, day) DO UPDATE SET
+			request_count = request_count + 1,
+			tokens_used = tokens_used + excluded.tokens_used,
+			error_count = error_count + excluded.error_count;`,
+		id,
+		now.Format(usageDayLayout),
+		result.Tokens,
+		failed,
+	)
+	if err != nil {
+		return fmt.Errorf("record key daily usage: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit record key result: %w", err)
+	}
+	return nil
+}
+
+// ListDailyUsage returns per-key daily usage for the last days days (including today), newest first.
+func (s *Store) ListDailyUsage(ctx context.Context, days int) ([]DailyUsage, error) {
+	if days <= 0 {
+		days = 1
+	}
+	since := time.Now().UTC().AddDate(0, 0, -(days - 1)).Format(usageDayLayout)
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT u.key_id, k.key, k.key_hint, k.note, k.enabled, u.day, u.request_count, u.tokens_used, u.error_count
+		 FROM gemini_key_daily_usage u
+		 JOIN gemini_keys k ON k.id = u.key_id
+		 WHERE u.day >= ?
+		 ORDER BY u.day DESC, u.key_id ASC;`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list daily usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make([]DailyUsage, 0, 16)
+	for rows.Next() {
+		var (
+			item    DailyUsage
+			stored  string
+			hint    *string
+			note    *string
+			enabled int
+		)
+		if err := rows.Scan(&item.KeyID, &stored, &hint, &note, &enabled, &item.Day, &item.RequestCount, &item.TokensUsed, &item.ErrorCount); err != nil {
+			return nil, fmt.Errorf("scan daily usage: %w", err)
+		}
+		key := Key{Key: stored}
+		if hint != nil {
+			key.Hint = *hint
+		}
+		item.MaskedKey = key.Masked()
+		if note != nil {
+			item.Note = *note
+		}
+		item.Enabled = enabled == 1
+		usage = append(usage, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list daily usage rows: %w", err)
+	}
+	return usage, nil
+}
@@ -0,0 +1,122 @@
+// Package profile 定义内置文档 profile：按文档类型打包 provider、提示词、MinerU 参数、分片大小和后处理步骤。
+package profile
+
+import (
+	"sort"
+
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	"github.com/neyuki778/LLM-PDF-OCR/pkg/result"
+)
+
+// Profile 一类文档的处理方案
+type Profile struct {
+	Name        string
+	Description string
+	Provider    string // 首选 provider，未在 LLM_PROVIDER 中配置时忽略
+	Prompt      string // 基础提示词，可使用 {{language}}、{{document_type}}；用户模板优先
+	MinerU      options.MinerUOptions
+	ShardSpan   int      // 每个分片的页数
+	PostProcess []string // 聚合后依次执行的 result 后处理步骤
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+var builtin = map[string]Profile{
+	"paper": {
+		Name:        "paper",
+		Description: "学术论文：保留公式（LaTeX）、表格和参考文献",
+		Provider:    "mineru",
+		Prompt: "Extract this academic paper and convert it into clean Markdown. " +
+			"Write every formula in LaTeX ($...$ inline, $$...$$ for display). " +
+			"Keep section numbering, figure and table captions, footnotes and the full reference list with its original numbering. " +
+			"Output only the content in {{language}}, without commentary; do not translate.",
+		MinerU: options.MinerUOptions{
+			ModelVersion:  "pipeline",
+			EnableFormula: boolPtr(true),
+			EnableTable:   boolPtr(true),
+		},
+		ShardSpan: 4,
+		PostProcess: []string{
+			result.StepStripCodeFences,
+			result.StepDehyphenate,
+			result.StepNormalizeBlankLines,
+		},
+	},
+	"exam": {
+		Name:        "exam",
+		Description: "试卷：保留题号、选项和分值，填空处保留下划线",
+		Provider:    "gemini",
+		Prompt: "Extract this exam sheet and convert it into clean Markdown. " +
+			"Keep the original question numbering, sub-question labels, option letters (A/B/C/D) and points exactly as printed, one question per paragraph. " +
+			"Keep blanks to fill in as underscores and write formulas in LaTeX. " +
+			"Output only the content in {{language}}, without answers or commentary; do not translate.",
+		MinerU: options.MinerUOptions{
+			ModelVersion:  "pipeline",
+			IsOCR:         true,
+			EnableFormula: boolPtr(true),
+		},
+		ShardSpan: 2,
+		PostProcess: []string{
+			result.StepStripCodeFences,
+			result.StepNormalizeBlankLines,
+		},
+	},
+	"invoice": {
+		Name:        "invoice",
+		Description: "发票/票据：输出结构化字段和明细表",
+		Provider:    "gemini",
+		Prompt: "Extract this invoice into Markdown with exactly two sections. " +
+			"Under '## Fields', list one '- Field: value' line each for invoice number, issue date, seller, buyer, tax ID, currency, subtotal, tax and total; write 'N/A' when a field is missing. " +
+			"Under '## Line Items', output a Markdown table with columns Description, Quantity, Unit Price, Amount. " +
+			"Copy values exactly as printed; do not translate or compute missing numbers.",
+		MinerU: options.MinerUOptions{
+			ModelVersion:  "pipeline",
+			EnableFormula: boolPtr(false),
+			EnableTable:   boolPtr(true),
+		},
+		ShardSpan: 1,
+		PostProcess: []string{
+			result.StepStripCodeFences,
+			result.StepNormalizeBlankLines,
+		},
+	},
+	"book": {
+		Name:        "book",
+		Description: "书籍：去掉页眉页脚和页码，保留章节结构",
+		Provider:    "gemini",
+		Prompt: "Extract this book excerpt and convert it into clean Markdown. " +
+			"Use headings for chapter and section titles, drop running headers, footers and page numbers, " +
+			"and join paragraphs that continue across pages. " +
+			"Output only the content in {{language}}, without commentary; do not translate.",
+		MinerU: options.MinerUOptions{
+			EnableFormula: boolPtr(false),
+		},
+		ShardSpan: 4,
+		PostProcess: []string{
+			result.StepStripCodeFences,
+			result.StepDehyphenate,
+			result.StepStripPageNumbers,
+			result.StepNormalizeBlankLines,
+		},
+	},
+}
+
+// Get 按名称查找内置 profile
+func Get(name string) (Profile, bool) {
+	p, ok := builtin[name]
+	return p, ok
+}
+
+// List 返回全部内置 profile，按名称排序
+func List() []Profile {
+	profiles := make([]Profile, 0, len(builtin))
+	for _, p := range builtin {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
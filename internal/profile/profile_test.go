@@ -0,0 +1,22 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/neyuki778/LLM-PDF-OCR/internal/prompt"
+	"github.com/neyuki778/LLM-PDF-OCR/pkg/result"
+)
+
+func TestBuiltinProfilesAreValid(t *testing.T) {
+	for _, p := range List() {
+		if p.ShardSpan <= 0 {
+			t.Fatalf("profile %s: shard span must be > 0", p.Name)
+		}
+		if err := prompt.Validate(p.Prompt); err != nil {
+			t.Fatalf("profile %s: invalid prompt: %v", p.Name, err)
+		}
+		if err := result.ValidatePostProcess(p.PostProcess); err != nil {
+			t.Fatalf("profile %s: %v", p.Name, err)
+		}
+	}
+}
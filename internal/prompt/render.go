@@ -0,0 +1,92 @@
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	gemini "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/gemini"
+)
+
+const (
+	MaxNameLength        = 64
+	MaxContentLength     = 8000
+	MaxInstructionLength = 2000
+
+	defaultLanguage     = "the original language of the document"
+	defaultDocumentType = "document"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_]+)\s*\}\}`)
+
+// Validate checks a template body: non-empty, bounded, and only known variables.
+func Validate(content string) error {
+	clean := strings.TrimSpace(content)
+	if clean == "" {
+		return ErrEmptyContent
+	}
+	if len(clean) > MaxContentLength {
+		return ErrContentTooLong
+	}
+	return checkVariables(clean)
+}
+
+// Render substitutes vars into content. Empty vars fall back to neutral defaults
+// so a template stays meaningful when the caller does not set them.
+func Render(content string, vars Vars) string {
+	language := strings.TrimSpace(vars.Language)
+	if language == "" {
+		language = defaultLanguage
+	}
+	documentType := strings.TrimSpace(vars.DocumentType)
+	if documentType == "" {
+		documentType = defaultDocumentType
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(content, func(match string) string {
+		switch strings.ToLower(placeholderPattern.FindStringSubmatch(match)[1]) {
+		case "language":
+			return language
+		case "document_type":
+			return documentType
+		default:
+			return match
+		}
+	})
+}
+
+// Compose builds the final prompt for a task. base is the template content, or
+// the default Gemini prompt when empty; instruction is appended as extra
+// requirements. It returns "" when neither is set so providers keep their default.
+func Compose(base, instruction string, vars Vars) (string, error) {
+	base = strings.TrimSpace(base)
+	instruction = strings.TrimSpace(instruction)
+	if base == "" && instruction == "" {
+		return "", nil
+	}
+	if len(instruction) > MaxInstructionLength {
+		return "", ErrInstructionTooLong
+	}
+	if err := checkVariables(instruction); err != nil {
+		return "", err
+	}
+
+	if base == "" {
+		base = gemini.Prompt
+	}
+	if instruction != "" {
+		base += "\n\nAdditional instructions:\n" + instruction
+	}
+	return Render(base, vars), nil
+}
+
+func checkVariables(content string) error {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(content, -1) {
+		switch strings.ToLower(match[1]) {
+		case "language", "document_type":
+		default:
+			return fmt.Errorf("%w: %s", ErrUnknownVariable, match[1])
+		}
+	}
+	return nil
+}
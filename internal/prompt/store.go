@@ -0,0 +1,333 @@
+package prompt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	defaultBusyTimeoutMS = 5000
+
+	templateColumns = `id, user_id, name, content, created_at, updated_at`
+)
+
+var (
+	ErrNotFound           = errors.New("prompt template not found")
+	ErrNameExists         = errors.New("prompt template name already exists")
+	ErrEmptyName          = errors.New("prompt template name is empty")
+	ErrNameTooLong        = errors.New("prompt template name is too long")
+	ErrEmptyContent       = errors.New("prompt template content is empty")
+	ErrContentTooLong     = errors.New("prompt template content is too long")
+	ErrInstructionTooLong = errors.New("prompt instruction is too long")
+	ErrUnknownVariable    = errors.New("unknown prompt variable")
+	ErrEmptyPath          = errors.New("db path is empty")
+)
+
+// Store manages per-user prompt templates in SQLite.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore opens (or creates) the sqlite database at dbPath and runs migrations.
+func NewStore(dbPath string) (*Store, error) {
+	cleanPath := strings.TrimSpace(dbPath)
+	if cleanPath == "" {
+		return nil, ErrEmptyPath
+	}
+
+	if err := ensureFile(cleanPath); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", cleanPath)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// SQLite works best with a single writer connection.
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	if _, err := db.Exec("PRAGMA journal_mode=WAL;"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("set journal_mode: %w", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA busy_timeout=%d;", defaultBusyTimeoutMS)); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("set busy_timeout: %w", err)
+	}
+
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close releases sqlite resources.
+func (s *Store) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// CreateTemplate saves a new template for userID. Names are unique per user.
+func (s *Store) CreateTemplate(ctx context.Context, userID, name, content string) (*Template, error) {
+	cleanName, err := cleanTemplateName(name)
+	if err != nil {
+		return nil, err
+	}
+	cleanContent := strings.TrimSpace(content)
+	if err := Validate(cleanContent); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Unix()
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO prompt_templates (user_id, name, content, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?);`,
+		userID,
+		cleanName,
+		cleanContent,
+		now,
+		now,
+	)
+	if err != nil {
+		if isUniqueConstraint(err) {
+			return nil, ErrNameExists
+		}
+		return nil, fmt.Errorf("insert prompt template: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	return &Template{
+		ID:        id,
+		UserID:    userID,
+		Name:      cleanName,
+		Content:   cleanContent,
+		CreatedAt: time.Unix(now, 0).UTC(),
+		UpdatedAt: time.Unix(now, 0).UTC(),
+	}, nil
+}
+
+// ListTemplates returns the user's templates ordered by name.
+func (s *Store) ListTemplates(ctx context.Context, userID string) ([]Template, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+templateColumns+` FROM prompt_templates WHERE user_id = ? ORDER BY name ASC;`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list prompt templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]Template, 0, 8)
+	for rows.Next() {
+		tpl, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan prompt template: %w", err)
+		}
+		templates = append(templates, tpl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list prompt templates rows: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTemplateByName looks up one of the user's templates by name.
+func (s *Store) GetTemplateByName(ctx context.Context, userID, name string) (*Template, error) {
+	row := s.db.QueryRowContext(
+		ctx,
+		`SELECT `+templateColumns+` FROM prompt_templates WHERE user_id = ? AND name = ?;`,
+		userID,
+		strings.TrimSpace(name),
+	)
+	tpl, err := scanTemplate(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("get prompt template: %w", err)
+	}
+	return &tpl, nil
+}
+
+// UpdateTemplate changes name and/or content of one of the user's templates.
+func (s *Store) UpdateTemplate(ctx context.Context, userID string, id int64, update TemplateUpdate) (*Template, error) {
+	sets := make([]string, 0, 3)
+	args := make([]any, 0, 5)
+	if update.Name != nil {
+		cleanName, err := cleanTemplateName(*update.Name)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, "name = ?")
+		args = append(args, cleanName)
+	}
+	if update.Content != nil {
+		cleanContent := strings.TrimSpace(*update.Content)
+		if err := Validate(cleanContent); err != nil {
+			return nil, err
+		}
+		sets = append(sets, "content = ?")
+		args = append(args, cleanContent)
+	}
+
+	if len(sets) > 0 {
+		sets = append(sets, "updated_at = ?")
+		args = append(args, time.Now().UTC().Unix(), id, userID)
+		result, err := s.db.ExecContext(
+			ctx,
+			`UPDATE prompt_templates SET `+strings.Join(sets, ", ")+` WHERE id = ? AND user_id = ?;`,
+			args...,
+		)
+		if err != nil {
+			if isUniqueConstraint(err) {
+				return nil, ErrNameExists
+			}
+			return nil, fmt.Errorf("update prompt template: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return nil, ErrNotFound
+		}
+	}
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`SELECT `+templateColumns+` FROM prompt_templates WHERE id = ? AND user_id = ?;`,
+		id,
+		userID,
+	)
+	tpl, err := scanTemplate(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("get prompt template: %w", err)
+	}
+	return &tpl, nil
+}
+
+// DeleteTemplate removes one of the user's templates.
+func (s *Store) DeleteTemplate(ctx context.Context, userID string, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM prompt_templates WHERE id = ? AND user_id = ?;`, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete prompt template: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// migrations are applied in order; the index+1 of each entry is its schema version.
+// Never edit an existing entry, append a new one instead.
+var migrations = [][]string{
+	// v1: base table
+	{
+		`CREATE TABLE IF NOT EXISTS prompt_templates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			content TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			UNIQUE (user_id, name)
+		);`,
+	},
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS prompt_templates_schema (version INTEGER NOT NULL);`); err != nil {
+		return fmt.Errorf("migrate: create schema table: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM prompt_templates_schema;`).Scan(&version); err != nil {
+		return fmt.Errorf("migrate: read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("migrate v%d: begin: %w", i+1, err)
+		}
+		for _, stmt := range migrations[i] {
+			if _, err := tx.Exec(stmt); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("migrate v%d: %w", i+1, err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO prompt_templates_schema (version) VALUES (?);`, i+1); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migrate v%d: record version: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migrate v%d: commit: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTemplate(scanner rowScanner) (Template, error) {
+	var (
+		tpl       Template
+		createdAt int64
+		updatedAt int64
+	)
+	if err := scanner.Scan(&tpl.ID, &tpl.UserID, &tpl.Name, &tpl.Content, &createdAt, &updatedAt); err != nil {
+		return Template{}, err
+	}
+	tpl.CreatedAt = time.Unix(createdAt, 0).UTC()
+	tpl.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return tpl, nil
+}
+
+func cleanTemplateName(name string) (string, error) {
+	clean := strings.TrimSpace(name)
+	if clean == "" {
+		return "", ErrEmptyName
+	}
+	if len(clean) > MaxNameLength {
+		return "", ErrNameTooLong
+	}
+	return clean, nil
+}
+
+func isUniqueConstraint(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func ensureFile(path string) error {
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create db dir: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("create db file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,89 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "prompts.db"))
+	if err != nil {
+		t.Fatalf("new prompt store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
+func TestStore_TemplatesAreScopedPerUser(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	tpl, err := store.CreateTemplate(ctx, "user-a", "tables", "Render tables as HTML. Output in {{language}}.")
+	if err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+	if _, err := store.CreateTemplate(ctx, "user-a", "tables", "dup"); !errors.Is(err, ErrNameExists) {
+		t.Fatalf("expected ErrNameExists, got %v", err)
+	}
+	if _, err := store.CreateTemplate(ctx, "user-b", "tables", "Keep LaTeX."); err != nil {
+		t.Fatalf("same name for another user: %v", err)
+	}
+	if _, err := store.CreateTemplate(ctx, "user-a", "bad", "Hello {{author}}"); !errors.Is(err, ErrUnknownVariable) {
+		t.Fatalf("expected ErrUnknownVariable, got %v", err)
+	}
+
+	got, err := store.GetTemplateByName(ctx, "user-a", "tables")
+	if err != nil || got.ID != tpl.ID {
+		t.Fatalf("get by name: tpl=%+v err=%v", got, err)
+	}
+	if _, err := store.GetTemplateByName(ctx, "user-c", "tables"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for other user, got %v", err)
+	}
+
+	content := "Ignore page headers."
+	updated, err := store.UpdateTemplate(ctx, "user-a", tpl.ID, TemplateUpdate{Content: &content})
+	if err != nil || updated.Content != content {
+		t.Fatalf("update template: tpl=%+v err=%v", updated, err)
+	}
+	if _, err := store.UpdateTemplate(ctx, "user-b", tpl.ID, TemplateUpdate{Content: &content}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound when updating another user's template, got %v", err)
+	}
+	if err := store.DeleteTemplate(ctx, "user-b", tpl.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound when deleting another user's template, got %v", err)
+	}
+	if err := store.DeleteTemplate(ctx, "user-a", tpl.ID); err != nil {
+		t.Fatalf("delete template: %v", err)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	if got, err := Compose("", "", Vars{}); err != nil || got != "" {
+		t.Fatalf("expected empty prompt, got %q err=%v", got, err)
+	}
+
+	got, err := Compose("Transcribe this {{document_type}} in {{ language }}.", "Keep LaTeX.", Vars{Language: "English", DocumentType: "exam"})
+	if err != nil {
+		t.Fatalf("compose: %v", err)
+	}
+	if !strings.HasPrefix(got, "Transcribe this exam in English.") || !strings.HasSuffix(got, "Keep LaTeX.") {
+		t.Fatalf("unexpected prompt: %q", got)
+	}
+
+	got, err = Compose("", "Render tables as HTML.", Vars{})
+	if err != nil {
+		t.Fatalf("compose inline: %v", err)
+	}
+	if !strings.Contains(got, "Markdown") || !strings.HasSuffix(got, "Render tables as HTML.") {
+		t.Fatalf("inline instruction should extend the default prompt: %q", got)
+	}
+
+	if _, err := Compose("", strings.Repeat("x", MaxInstructionLength+1), Vars{}); !errors.Is(err, ErrInstructionTooLong) {
+		t.Fatalf("expected ErrInstructionTooLong, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package prompt
+
+import "time"
+
+// Template is a named prompt saved by a user.
+type Template struct {
+	ID        int64
+	UserID    string
+	Name      string
+	Content   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TemplateUpdate holds the fields to change; nil fields are left untouched.
+type TemplateUpdate struct {
+	Name    *string
+	Content *string
+}
+
+// Vars are the values substituted into {{language}} and {{document_type}}.
+type Vars struct {
+	Language     string
+	DocumentType string
+}
@@ -0,0 +1,142 @@
+// Package secret 使用 AES-GCM 加密落盘的密钥材料（keystore 中的 Gemini key、.env 中的 API key）。
+//
+// 密文格式：enc:v1:<kid>:<base64(nonce|ciphertext)>，kid 为主密钥指纹，用于主密钥轮换时识别旧数据。
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	prefix       = "enc:v1:"
+	masterKeyLen = 32 // AES-256
+)
+
+var (
+	ErrInvalidMasterKey = errors.New("master key must be 32 bytes, base64 encoded")
+	ErrNoMasterKey      = errors.New("value is encrypted but no master key is configured")
+	ErrWrongMasterKey   = errors.New("value was encrypted with a different master key")
+	ErrMalformed        = errors.New("malformed encrypted value")
+)
+
+// Cipher 持有主密钥派生的 AEAD
+type Cipher struct {
+	aead cipher.AEAD
+	kid  string
+}
+
+// NewCipher 由 base64 编码的 32 字节主密钥创建 Cipher
+func NewCipher(encodedKey string) (*Cipher, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil || len(raw) != masterKeyLen {
+		return nil, ErrInvalidMasterKey
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("create aes cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return &Cipher{aead: aead, kid: hex.EncodeToString(sum[:4])}, nil
+}
+
+// LoadFromEnv 从 MASTER_KEY 或 MASTER_KEY_FILE 读取主密钥，均未设置时返回 nil, nil
+func LoadFromEnv() (*Cipher, error) {
+	if key := strings.TrimSpace(os.Getenv("MASTER_KEY")); key != "" {
+		return NewCipher(key)
+	}
+	if path := strings.TrimSpace(os.Getenv("MASTER_KEY_FILE")); path != "" {
+		return LoadFromFile(path)
+	}
+	return nil, nil
+}
+
+// LoadFromFile 从文件读取 base64 编码的主密钥
+func LoadFromFile(path string) (*Cipher, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read master key file: %w", err)
+	}
+	return NewCipher(string(content))
+}
+
+// GenerateMasterKey 生成一个新的 base64 编码主密钥
+func GenerateMasterKey() (string, error) {
+	raw := make([]byte, masterKeyLen)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate master key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// KeyID 返回主密钥指纹
+func (c *Cipher) KeyID() string {
+	return c.kid
+}
+
+// Encrypt 加密明文
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), []byte(c.kid))
+	return prefix + c.kid + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的输出
+func (c *Cipher) Decrypt(value string) (string, error) {
+	kid, payload, ok := split(value)
+	if !ok {
+		return "", ErrMalformed
+	}
+	if kid != c.kid {
+		return "", ErrWrongMasterKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted 判断值是否为本包产生的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Reveal 明文原样返回，密文用 c 解密；c 为 nil 且值为密文时返回 ErrNoMasterKey
+func Reveal(c *Cipher, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if c == nil {
+		return "", ErrNoMasterKey
+	}
+	return c.Decrypt(value)
+}
+
+func split(value string) (kid, payload string, ok bool) {
+	if !IsEncrypted(value) {
+		return "", "", false
+	}
+	kid, payload, ok = strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return kid, payload, ok && kid != "" && payload != ""
+}
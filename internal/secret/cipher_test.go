@@ -0,0 +1,66 @@
+package secret
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestCipher(t *testing.T) *Cipher {
+	t.Helper()
+	key, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("generate master key: %v", err)
+	}
+	c, err := NewCipher(key)
+	if err != nil {
+		t.Fatalf("new cipher: %v", err)
+	}
+	return c
+}
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c := newTestCipher(t)
+
+	encrypted, err := c.Encrypt("AIza-secret")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Fatalf("expected encrypted prefix, got %s", encrypted)
+	}
+	again, _ := c.Encrypt("AIza-secret")
+	if again == encrypted {
+		t.Fatalf("encryption should use a random nonce")
+	}
+
+	plain, err := Reveal(c, encrypted)
+	if err != nil {
+		t.Fatalf("reveal: %v", err)
+	}
+	if plain != "AIza-secret" {
+		t.Fatalf("unexpected plaintext: %s", plain)
+	}
+}
+
+func TestCipher_WrongOrMissingKey(t *testing.T) {
+	encrypted, err := newTestCipher(t).Encrypt("AIza-secret")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	if _, err := newTestCipher(t).Decrypt(encrypted); !errors.Is(err, ErrWrongMasterKey) {
+		t.Fatalf("expected ErrWrongMasterKey, got %v", err)
+	}
+	if _, err := Reveal(nil, encrypted); !errors.Is(err, ErrNoMasterKey) {
+		t.Fatalf("expected ErrNoMasterKey, got %v", err)
+	}
+	if plain, err := Reveal(nil, "plain-key"); err != nil || plain != "plain-key" {
+		t.Fatalf("plaintext should pass through, got %q err=%v", plain, err)
+	}
+}
+
+func TestNewCipher_InvalidKey(t *testing.T) {
+	if _, err := NewCipher("c2hvcnQ="); !errors.Is(err, ErrInvalidMasterKey) {
+		t.Fatalf("expected ErrInvalidMasterKey, got %v", err)
+	}
+}
@@ -1,17 +1,57 @@
 package store
 
-import "time"
+import (
+	"time"
+
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
 
 type TaskRecord struct {
-	ID          string    `json:"id"`
-	OwnerUserID string    `json:"owner_user_id,omitempty"`
-	Status      string    `json:"status"`      // pending, processing, completed, failed
-	PDFPath     string    `json:"pdf_path"`    // 原始 PDF 路径
-	ResultPath  string    `json:"result_path"` // 结果 Markdown 路径
-	TotalPages  int       `json:"total_pages"`
-	Error       string    `json:"error,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string                 `json:"id"`
+	OwnerUserID string                 `json:"owner_user_id,omitempty"`
+	Status      string                 `json:"status"`      // pending, processing, completed, failed
+	PDFPath     string                 `json:"pdf_path"`    // 原始 PDF 路径
+	ResultPath  string                 `json:"result_path"` // 结果 Markdown 路径
+	TotalPages  int                    `json:"total_pages"`
+	Profile     string                 `json:"profile,omitempty"`        // 创建任务时选择的文档 profile
+	ModelChoice string                 `json:"model_choice,omitempty"`   // 创建任务时选择的模型选项
+	TranslateTo string                 `json:"translate_to,omitempty"`   // 译文语言，未请求翻译时为空
+	Format      string                 `json:"format,omitempty"`         // 结果格式：markdown / json，为空视为 markdown
+	MinerU      *options.MinerUOptions `json:"mineru_options,omitempty"` // 实际使用的 MinerU 参数，仅配置了 mineru 时记录
+	Shards      []ShardRecord          `json:"shards,omitempty"`         // 分片处理结果，任务完成时写入
+	Usage       report.Usage           `json:"usage"`                    // 所有分片累计用量
+	Cost        float64                `json:"cost"`                     // 按价目表计算的费用（美元）
+	Error       string                 `json:"error,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// ShardRecord 单个分片的持久化信息
+type ShardRecord struct {
+	ID        string       `json:"id"`
+	PageStart int          `json:"page_start"`
+	PageEnd   int          `json:"page_end"`
+	Status    string       `json:"status"`             // success / failed
+	Provider  string       `json:"provider,omitempty"` // 产出该分片结果的 provider
+	Model     string       `json:"model,omitempty"`
+	Usage     report.Usage `json:"usage"`
+	Cost      float64      `json:"cost"`
+	// TranslateError 请求了翻译但该分片翻译失败时的错误信息
+	TranslateError string `json:"translate_error,omitempty"`
+}
+
+// UserUsage 用户按月汇总的用量，用于内部结算
+type UserUsage struct {
+	UserID          string  `json:"user_id"`
+	Month           string  `json:"month"` // YYYY-MM（UTC）
+	Tasks           int64   `json:"tasks"`
+	Asks            int64   `json:"asks"` // 文档问答次数，用量计入同一汇总
+	PromptTokens    int64   `json:"prompt_tokens"`
+	CandidateTokens int64   `json:"candidate_tokens"`
+	TotalTokens     int64   `json:"total_tokens"`
+	Pages           int64   `json:"pages"`
+	Cost            float64 `json:"cost"`
 }
 
 type UserTaskHistoryEntry struct {
@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	store "github.com/neyuki778/LLM-PDF-OCR/internal/store"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+const (
+	userUsageKeyPrefix  = "user_usage:"  // user_usage:{userID}:{YYYY-MM} -> HASH
+	usageUsersKeyPrefix = "usage_users:" // usage_users:{YYYY-MM} -> SET of user ids
+	usageMonthLayout    = "2006-01"
+)
+
+// UsageMonth formats t as the month key used by the usage rollup.
+func UsageMonth(t time.Time) string {
+	return t.UTC().Format(usageMonthLayout)
+}
+
+// AddUserUsage adds one finished task's usage into the user's monthly rollup.
+func (s *RedisStore) AddUserUsage(ctx context.Context, userID string, at time.Time, usage report.Usage, cost float64) error {
+	return s.addUserUsage(ctx, userID, at, "tasks", usage, cost)
+}
+
+// AddUserAskUsage adds one document question's usage into the user's monthly rollup;
+// it counts under "asks" instead of "tasks".
+func (s *RedisStore) AddUserAskUsage(ctx context.Context, userID string, at time.Time, usage report.Usage, cost float64) error {
+	return s.addUserUsage(ctx, userID, at, "asks", usage, cost)
+}
+
+func (s *RedisStore) addUserUsage(ctx context.Context, userID string, at time.Time, counter string, usage report.Usage, cost float64) error {
+	if userID == "" {
+		return fmt.Errorf("userID should not be empty")
+	}
+
+	month := UsageMonth(at)
+	key := userUsageKeyPrefix + userID + ":" + month
+	pipe := s.client.TxPipeline()
+	pipe.HIncrBy(ctx, key, counter, 1)
+	pipe.HIncrBy(ctx, key, "prompt_tokens", usage.PromptTokens)
+	pipe.HIncrBy(ctx, key, "candidate_tokens", usage.CandidateTokens)
+	pipe.HIncrBy(ctx, key, "total_tokens", usage.TotalTokens)
+	pipe.HIncrBy(ctx, key, "pages", usage.Pages)
+	pipe.HIncrByFloat(ctx, key, "cost", cost)
+	pipe.SAdd(ctx, usageUsersKeyPrefix+month, userID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetUserUsage returns the user's rollup for month (YYYY-MM); missing months are all zero.
+func (s *RedisStore) GetUserUsage(ctx context.Context, userID, month string) (*store.UserUsage, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("userID should not be empty")
+	}
+	if _, err := time.Parse(usageMonthLayout, month); err != nil {
+		return nil, fmt.Errorf("invalid month %q: %w", month, err)
+	}
+
+	values, err := s.client.HGetAll(ctx, userUsageKeyPrefix+userID+":"+month).Result()
+	if err != nil {
+		return nil, err
+	}
+	usage := &store.UserUsage{UserID: userID, Month: month}
+	usage.Tasks = parseInt64(values["tasks"])
+	usage.Asks = parseInt64(values["asks"])
+	usage.PromptTokens = parseInt64(values["prompt_tokens"])
+	usage.CandidateTokens = parseInt64(values["candidate_tokens"])
+	usage.TotalTokens = parseInt64(values["total_tokens"])
+	usage.Pages = parseInt64(values["pages"])
+	usage.Cost, _ = strconv.ParseFloat(values["cost"], 64)
+	return usage, nil
+}
+
+// ListMonthUsage returns every user's rollup for month, highest cost first.
+func (s *RedisStore) ListMonthUsage(ctx context.Context, month string) ([]store.UserUsage, error) {
+	if _, err := time.Parse(usageMonthLayout, month); err != nil {
+		return nil, fmt.Errorf("invalid month %q: %w", month, err)
+	}
+
+	userIDs, err := s.client.SMembers(ctx, usageUsersKeyPrefix+month).Result()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]store.UserUsage, 0, len(userIDs))
+	for _, userID := range userIDs {
+		usage, err := s.GetUserUsage(ctx, userID, month)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *usage)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Cost != items[j].Cost {
+			return items[i].Cost > items[j].Cost
+		}
+		return items[i].UserID < items[j].UserID
+	})
+	return items, nil
+}
+
+func parseInt64(raw string) int64 {
+	value, _ := strconv.ParseInt(raw, 10, 64)
+	return value
+}
@@ -0,0 +1,174 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	qa "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/qa"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	result "github.com/neyuki778/LLM-PDF-OCR/pkg/result"
+)
+
+// AskResult 文档问答的回答及本次调用的用量
+type AskResult struct {
+	qa.Answer
+	Model string
+	Usage report.Usage
+	Cost  float64
+}
+
+// PagesPath 按分片页码范围保存的原始 Markdown，供文档问答检索，与结果文件同目录
+func (pt *ParentTask) PagesPath() string {
+	return filepath.Join(filepath.Dir(pt.OutputPath), "pages.json")
+}
+
+// aggregatePages 按页码顺序保存成功分片的 Markdown 及其页码范围
+func (pt *ParentTask) aggregatePages() error {
+	sections := make([]qa.Section, 0, len(pt.SubTasks))
+	for _, subTaskMeta := range pt.SortSubTasksByPageStart() {
+		if subTaskMeta.Status != SubTaskSuccess {
+			continue
+		}
+		content, err := os.ReadFile(subTaskMeta.TempFilePath)
+		if err != nil {
+			return err
+		}
+		sections = append(sections, qa.Section{
+			PageStart: subTaskMeta.PageStart,
+			PageEnd:   subTaskMeta.PageEnd,
+			Text:      string(content),
+		})
+	}
+	data, err := json.Marshal(sections)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pt.PagesPath(), data, 0644)
+}
+
+// finishPages 对 pages.json 中的各段执行与结果文件相同的后处理，rewrite 非 nil 时再修正图片路径，
+// 使问答返回的 sources 与下载的结果一致；没有 pages.json 时直接返回
+func (pt *ParentTask) finishPages(steps []string, rewrite func(string) string) error {
+	if len(steps) == 0 && rewrite == nil {
+		return nil
+	}
+	data, err := os.ReadFile(pt.PagesPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var sections []qa.Section
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return fmt.Errorf("parse %s: %w", filepath.Base(pt.PagesPath()), err)
+	}
+	for i := range sections {
+		text, err := result.PostProcess(sections[i].Text, steps)
+		if err != nil {
+			return err
+		}
+		if rewrite != nil {
+			text = rewrite(text)
+		}
+		sections[i].Text = text
+	}
+	if data, err = json.Marshal(sections); err != nil {
+		return err
+	}
+	return os.WriteFile(pt.PagesPath(), data, 0644)
+}
+
+// sections 读取问答使用的分页内容；早于 pages.json 的任务把整个结果视为一段，页码范围取所有分片
+func (pt *ParentTask) sections() ([]qa.Section, error) {
+	data, err := os.ReadFile(pt.PagesPath())
+	if err == nil {
+		var sections []qa.Section
+		if err := json.Unmarshal(data, &sections); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filepath.Base(pt.PagesPath()), err)
+		}
+		return sections, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(pt.OutputPath)
+	if err != nil {
+		return nil, err
+	}
+	section := qa.Section{PageStart: 1, PageEnd: 1, Text: string(content)}
+	for _, shard := range pt.ShardRecords() {
+		if section.PageEnd < shard.PageEnd {
+			section.PageEnd = shard.PageEnd
+		}
+	}
+	return []qa.Section{section}, nil
+}
+
+// Ask 在已完成任务的 Markdown 结果中检索与问题相关的片段，交给任务所用的 provider 回答并标注引用页码
+func (tm *TaskManager) Ask(ctx context.Context, parentTask *ParentTask, question string) (*AskResult, error) {
+	if strings.TrimSpace(question) == "" {
+		return nil, qa.ErrEmptyQuestion
+	}
+	if parentTask.CurrentStatus() != StatusCompleted {
+		return nil, ErrTaskNotCompleted
+	}
+	if parentTask.OutputFormat != FormatMarkdown {
+		return nil, ErrAskUnsupported
+	}
+	sections, err := parentTask.sections()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task result: %w", err)
+	}
+
+	callCtx, callReport := report.New(ctx)
+	generator := textGenerator(func(ctx context.Context, instruction, input string) (string, error) {
+		return tm.pool.GenerateText(ctx, parentTask.ModelChoice, instruction, input)
+	})
+	answer, err := qa.Ask(callCtx, generator, sections, question)
+	askResult := &AskResult{Answer: answer, Model: callReport.Model, Usage: callReport.Usage}
+	if cost, ok := tm.currentConfig().Prices.Cost(askResult.Model, askResult.Usage); ok {
+		askResult.Cost = cost
+	}
+	log.Printf(
+		"[task] ask task_id=%s model=%s citations=%d total_tokens=%d err=%v",
+		parentTask.ID,
+		askResult.Model,
+		len(answer.Citations),
+		askResult.Usage.TotalTokens,
+		err,
+	)
+	// 失败的调用同样消耗了 token，一并计入
+	if err == nil || askResult.Usage != (report.Usage{}) {
+		tm.addAskUsage(parentTask.OwnerUserID, askResult)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return askResult, nil
+}
+
+// addAskUsage 把问答用量计入任务所属用户的月度汇总（计为 asks，不计入 tasks）；匿名任务或未启用 Redis 时跳过
+func (tm *TaskManager) addAskUsage(ownerUserID string, askResult *AskResult) {
+	if ownerUserID == "" || tm.redisStore == nil {
+		return
+	}
+	if err := tm.redisStore.AddUserAskUsage(context.Background(), ownerUserID, time.Now().UTC(), askResult.Usage, askResult.Cost); err != nil {
+		log.Printf("[task] add ask usage failed user_id=%s err=%v", ownerUserID, err)
+	}
+}
+
+// textGenerator 把函数适配为 qa.Generator
+type textGenerator func(ctx context.Context, instruction, input string) (string, error)
+
+func (f textGenerator) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	return f(ctx, instruction, input)
+}
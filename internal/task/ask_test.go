@@ -0,0 +1,103 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	worker "github.com/neyuki778/LLM-PDF-OCR/internal/worker"
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	pricing "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/pricing"
+	qa "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/qa"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	result "github.com/neyuki778/LLM-PDF-OCR/pkg/result"
+)
+
+// answerProcessor 纯文本调用返回固定回答，记录收到的片段
+type answerProcessor struct {
+	answer string
+	input  string
+}
+
+func (p *answerProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts llm.ProcessOptions) (string, error) {
+	return "", nil
+}
+
+func (p *answerProcessor) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	p.input = input
+	if r := report.From(ctx); r != nil {
+		r.Model = "qa-model"
+		r.Usage = report.Usage{PromptTokens: 900_000, CandidateTokens: 100_000, TotalTokens: 1_000_000}
+	}
+	return p.answer, nil
+}
+
+func newAskTestManager(t *testing.T, processor llm.PDFProcessor) (*TaskManager, *ParentTask) {
+	t.Helper()
+	tm := &TaskManager{
+		tasks:    make(map[string]*ParentTask),
+		pool:     worker.NewWorkerPool(1, processor),
+		keyPools: llm.NewKeyPools(),
+		config:   llm.Config{Prices: pricing.Table{"qa-model": {InputPerMillion: 1, OutputPerMillion: 2}}},
+	}
+	parentTask := NewParentTask("t1", "a.pdf", t.TempDir())
+	parentTask.setStatus(StatusCompleted)
+	sections := []qa.Section{
+		{PageStart: 1, PageEnd: 2, Text: "The deadline is 30 June.\n\n![chart](images/a.png)\n\n7"},
+		{PageStart: 3, PageEnd: 4, Text: "Appendix."},
+	}
+	data, err := json.Marshal(sections)
+	if err != nil {
+		t.Fatalf("marshal sections: %v", err)
+	}
+	if err := os.WriteFile(parentTask.PagesPath(), data, 0644); err != nil {
+		t.Fatalf("write pages: %v", err)
+	}
+	return tm, parentTask
+}
+
+func TestAskReturnsProcessedSourcesAndExactCitations(t *testing.T) {
+	processor := &answerProcessor{answer: "It is 30 June [pp. 1-2], see page [p. 1]."}
+	tm, parentTask := newAskTestManager(t, processor)
+	rewrite := func(content string) string {
+		return rewriteImagePaths(content, "https://ocr.example.com", parentTask.ID)
+	}
+	if err := parentTask.finishPages([]string{result.StepStripPageNumbers}, rewrite); err != nil {
+		t.Fatalf("finishPages: %v", err)
+	}
+
+	answer, err := tm.Ask(context.Background(), parentTask, "When is the deadline?")
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if len(answer.Citations) != 1 || answer.Citations[0] != (qa.Citation{PageStart: 1, PageEnd: 2}) {
+		t.Fatalf("Citations = %+v, want only pages 1-2", answer.Citations)
+	}
+	source := answer.Sources[0].Text
+	if !strings.Contains(source, "](https://ocr.example.com/output/t1/images/a.png)") || strings.HasSuffix(source, "7") {
+		t.Fatalf("source not post-processed: %q", source)
+	}
+	if !strings.Contains(processor.input, "https://ocr.example.com/output/t1/images/a.png") {
+		t.Fatalf("prompt should use the processed sources:\n%s", processor.input)
+	}
+	if answer.Model != "qa-model" || answer.Usage.TotalTokens != 1_000_000 || answer.Cost != 1.1 {
+		t.Fatalf("model=%q usage=%+v cost=%v", answer.Model, answer.Usage, answer.Cost)
+	}
+}
+
+func TestAskRequiresCompletedMarkdownTask(t *testing.T) {
+	tm, parentTask := newAskTestManager(t, &answerProcessor{})
+
+	parentTask.setStatus(StatusProcessing)
+	if _, err := tm.Ask(context.Background(), parentTask, "question"); !errors.Is(err, ErrTaskNotCompleted) {
+		t.Fatalf("expected ErrTaskNotCompleted, got %v", err)
+	}
+	parentTask.setStatus(StatusCompleted)
+	parentTask.OutputFormat = FormatJSON
+	if _, err := tm.Ask(context.Background(), parentTask, "question"); !errors.Is(err, ErrAskUnsupported) {
+		t.Fatalf("expected ErrAskUnsupported, got %v", err)
+	}
+}
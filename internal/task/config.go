@@ -0,0 +1,200 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	worker "github.com/neyuki778/LLM-PDF-OCR/internal/worker"
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	mineru "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/MinerU"
+)
+
+// ConfigLoader 重新读取 LLM 配置，热加载时调用
+type ConfigLoader func() (llm.Config, error)
+
+// SetConfigLoader 设置热加载使用的配置来源，未设置时使用 llm.LoadConfigFromEnv
+func (tm *TaskManager) SetConfigLoader(loader ConfigLoader) {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+	tm.loadConfig = loader
+}
+
+// Reload 重新读取配置并热加载，返回新的配置版本；读取或创建 processor 失败时保留原配置
+func (tm *TaskManager) Reload() (int64, error) {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+
+	loader := tm.loadConfig
+	if loader == nil {
+		loader = llm.LoadConfigFromEnv
+	}
+	config, err := loader()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load config: %w", err)
+	}
+	return tm.applyConfig(config)
+}
+
+// ReloadConfig 用给定配置创建新的 processor 并原子替换到 WorkerPool；
+// 进行中的分片继续使用旧 processor，已提交到 MinerU 等待回调的分片仍能收到结果
+func (tm *TaskManager) ReloadConfig(config llm.Config) (int64, error) {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+	return tm.applyConfig(config)
+}
+
+// applyConfig key 配置未变的 Gemini key 池沿用原 processor，冷却和单 key 限流状态不重置；
+// 新配置不再使用的 key 池在使用旧配置的分片全部结束后关闭
+func (tm *TaskManager) applyConfig(config llm.Config) (int64, error) {
+	lease := tm.keyPools.Lease()
+	processor, err := lease.NewProcessor(config)
+	if err != nil {
+		lease.Release()
+		return 0, fmt.Errorf("failed to create processor: %w", err)
+	}
+	choices := make(map[string]worker.Choice, len(config.Choices))
+	for _, choice := range config.Choices {
+		choiceProcessor, err := lease.NewProcessor(choice.Config)
+		if err != nil {
+			lease.Release()
+			return 0, fmt.Errorf("failed to create processor for model choice %s: %w", choice.Name, err)
+		}
+		choices[choice.Name] = worker.Choice{Provider: choice.Provider, Processor: choiceProcessor}
+	}
+	// MinerU 回调模式下，推送到达的结果直接完成对应分片
+	receivers := config.CallbackReceivers()
+	for _, callbacks := range receivers {
+		callbacks.OnResult(tm.pool.CompleteDeferred)
+	}
+
+	tm.configMu.Lock()
+	for _, old := range tm.callbacks {
+		if !slices.Contains(receivers, old) {
+			tm.retiredCallbacks = append(tm.retiredCallbacks, old)
+		}
+	}
+	tm.callbacks = receivers
+	tm.configVersion++
+	version := tm.configVersion
+	tm.config = config
+	tm.configMu.Unlock()
+
+	tm.pool.ConfigureRateLimits(rateLimits(config), config.TokensPerPage)
+	tm.pool.SwapProcessor(&worker.ProcessorSet{
+		Version:   version,
+		Provider:  config.Provider,
+		Processor: processor,
+		Choices:   choices,
+		Release:   lease.Release,
+	})
+	log.Printf("[task] config loaded version=%d providers=%v choices=%d", version, config.Providers(), len(config.Choices))
+	return version, nil
+}
+
+// rateLimits 按回退链顺序展开各 provider 的限流配置，模型选项中新增的 provider 排在后面
+func rateLimits(config llm.Config) []worker.RateLimit {
+	var limits []worker.RateLimit
+	if len(config.Chain) == 0 {
+		limits = append(limits, worker.RateLimit{Provider: config.Provider, Limits: config.RateLimit})
+	}
+	for _, member := range config.Chain {
+		limits = append(limits, worker.RateLimit{Provider: member.Provider, Limits: member.RateLimit})
+	}
+	for _, choice := range config.Choices {
+		if !config.UsesProvider(choice.Provider) {
+			limits = append(limits, worker.RateLimit{Provider: choice.Provider, Limits: choice.Config.RateLimit})
+		}
+	}
+	return limits
+}
+
+// ResolveChoice 校验任务选择的模型选项：不在配置中返回 ErrUnknownModelChoice，用户等级不允许时返回 ErrModelChoiceNotAllowed
+func (tm *TaskManager) ResolveChoice(name, tier string) (llm.ModelChoice, error) {
+	choice, ok := tm.currentConfig().Choice(name)
+	if !ok {
+		return llm.ModelChoice{}, fmt.Errorf("%w: %s", ErrUnknownModelChoice, name)
+	}
+	if !choice.Allows(tier) {
+		return llm.ModelChoice{}, fmt.Errorf("%w: %s is not available for %s tier", ErrModelChoiceNotAllowed, name, tier)
+	}
+	return choice, nil
+}
+
+// ModelChoices 返回用户等级可以使用的模型选项
+func (tm *TaskManager) ModelChoices(tier string) []llm.ModelChoice {
+	var allowed []llm.ModelChoice
+	for _, choice := range tm.currentConfig().Choices {
+		if choice.Allows(tier) {
+			allowed = append(allowed, choice)
+		}
+	}
+	return allowed
+}
+
+// taskUsesProvider 判断任务会用到的 provider：选择了模型选项时只看该选项，否则看顶层配置（含回退链）
+func (tm *TaskManager) taskUsesProvider(parentTask *ParentTask, name string) bool {
+	if parentTask.ModelChoice != "" {
+		return parentTask.ChoiceProvider == name
+	}
+	return tm.currentConfig().UsesProvider(name)
+}
+
+// currentConfig 返回当前生效的配置
+func (tm *TaskManager) currentConfig() llm.Config {
+	tm.configMu.RLock()
+	defer tm.configMu.RUnlock()
+	return tm.config
+}
+
+// ConfigVersion 返回当前配置版本，启动时为 1，每次热加载递增
+func (tm *TaskManager) ConfigVersion() int64 {
+	tm.configMu.RLock()
+	defer tm.configMu.RUnlock()
+	return tm.configVersion
+}
+
+// HandleMinerUCallback 把 MinerU 推送交给当前配置的回调接收器；
+// 热加载前提交、仍在等待回调的任务由旧接收器处理（seed 可能已变化）
+func (tm *TaskManager) HandleMinerUCallback(checksum, content string) error {
+	receivers := tm.minerUReceivers()
+	if len(receivers) == 0 {
+		return ErrMinerUCallbackDisabled
+	}
+	var unknown error
+	err := mineru.ErrInvalidChecksum
+	for _, callbacks := range receivers {
+		err = callbacks.Handle(checksum, content)
+		switch {
+		case errors.Is(err, mineru.ErrInvalidChecksum):
+			continue
+		case errors.Is(err, mineru.ErrUnknownTask):
+			unknown = err
+			continue
+		}
+		return err
+	}
+	// 签名通过但所有接收器都不认识该任务时按未知任务返回，让 MinerU 重推
+	if unknown != nil {
+		return unknown
+	}
+	return err
+}
+
+// minerUReceivers 返回当前回调接收器和仍有等待任务的旧接收器，顺便清理已无等待任务的旧接收器
+func (tm *TaskManager) minerUReceivers() []*mineru.Callbacks {
+	tm.configMu.Lock()
+	defer tm.configMu.Unlock()
+
+	receivers := slices.Clone(tm.callbacks)
+	retired := tm.retiredCallbacks[:0]
+	for _, callbacks := range tm.retiredCallbacks {
+		if callbacks.Pending() > 0 {
+			retired = append(retired, callbacks)
+			receivers = append(receivers, callbacks)
+		}
+	}
+	tm.retiredCallbacks = retired
+	return receivers
+}
@@ -1,6 +1,33 @@
 package task
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrExtractionUnsupported 结构化抽取依赖 gemini 的 response schema
+var ErrExtractionUnsupported = errors.New("structured extraction requires the gemini provider")
+
+// ErrTranslationUnsupported 翻译需要支持纯文本调用的 provider（gemini / mock），且不能用于结构化抽取
+var ErrTranslationUnsupported = errors.New("translation requires the gemini or mock provider and markdown output")
+
+// 文档问答只支持已完成的 Markdown 结果
+var (
+	ErrTaskNotCompleted = errors.New("task not completed yet")
+	ErrAskUnsupported   = errors.New("questions are only supported for markdown results")
+)
+
+// ErrUnknownExportFormat 请求的导出格式不是 MinerU extra_formats 支持的格式
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+// 任务选择的模型选项不在 LLM_MODEL_CHOICES 中，或当前用户等级不可用
+var (
+	ErrUnknownModelChoice    = errors.New("unknown model choice")
+	ErrModelChoiceNotAllowed = errors.New("model choice not allowed")
+)
+
+// ErrMinerUCallbackDisabled 当前配置未启用 MinerU 回调模式，也没有等待回调的旧任务
+var ErrMinerUCallbackDisabled = errors.New("mineru callback mode is disabled")
 
 type PageLimitExceededError struct {
 	TotalPages int
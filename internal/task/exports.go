@@ -0,0 +1,48 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pdf "github.com/neyuki778/LLM-PDF-OCR/pkg/pdf"
+	"github.com/neyuki778/LLM-PDF-OCR/pkg/result"
+)
+
+// ExportPaths 返回 MinerU extra_formats 导出的分片文件（{分片名}{后缀}，与结果文件同目录），按页码排序；
+// 只从目录中查找，已从 Redis 恢复的任务同样可用
+func (pt *ParentTask) ExportPaths(format string) ([]string, error) {
+	ext, ok := result.FormatExtension(format)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownExportFormat, format)
+	}
+	entries, err := os.ReadDir(filepath.Dir(pt.OutputPath))
+	if err != nil {
+		return nil, err
+	}
+	type export struct {
+		path  string
+		first int
+	}
+	var exports []export
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ext {
+			continue
+		}
+		first, _, ok := pdf.ShardPages(strings.TrimSuffix(name, ext) + ".pdf")
+		if !ok {
+			continue
+		}
+		exports = append(exports, export{path: filepath.Join(filepath.Dir(pt.OutputPath), name), first: first})
+	}
+	sort.Slice(exports, func(i, j int) bool { return exports[i].first < exports[j].first })
+
+	paths := make([]string, len(exports))
+	for i, e := range exports {
+		paths[i] = e.path
+	}
+	return paths, nil
+}
@@ -0,0 +1,77 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neyuki778/LLM-PDF-OCR/pkg/result"
+)
+
+// LayoutPath 合并后的 MinerU layout.json 路径，与结果文件同目录
+func (pt *ParentTask) LayoutPath() string {
+	return filepath.Join(filepath.Dir(pt.OutputPath), "layout.json")
+}
+
+// ContentListPath 合并后的 MinerU content_list.json 路径
+func (pt *ParentTask) ContentListPath() string {
+	return filepath.Join(filepath.Dir(pt.OutputPath), "content_list.json")
+}
+
+// aggregateLayout 按页码顺序合并各分片的 MinerU 版面文件并删除分片文件；
+// 没有分片产出版面文件（如 gemini 处理）时不生成
+func (pt *ParentTask) aggregateLayout() error {
+	var layouts, contentLists []result.LayoutPart
+	var shardFiles []string
+	defer func() {
+		for _, path := range shardFiles {
+			os.Remove(path)
+		}
+	}()
+
+	for _, subTaskMeta := range pt.SortSubTasksByPageStart() {
+		base := strings.TrimSuffix(subTaskMeta.SplitPDFPath, filepath.Ext(subTaskMeta.SplitPDFPath))
+		offset := subTaskMeta.PageStart - 1
+		for _, target := range []struct {
+			suffix string
+			parts  *[]result.LayoutPart
+		}{
+			{result.LayoutSuffix, &layouts},
+			{result.ContentListSuffix, &contentLists},
+		} {
+			path := base + target.suffix
+			data, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			shardFiles = append(shardFiles, path)
+			if subTaskMeta.Status == SubTaskSuccess {
+				*target.parts = append(*target.parts, result.LayoutPart{PageOffset: offset, Data: data})
+			}
+		}
+	}
+
+	if len(layouts) > 0 {
+		merged, err := result.MergeLayouts(layouts)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(pt.LayoutPath(), merged, 0644); err != nil {
+			return err
+		}
+	}
+	if len(contentLists) > 0 {
+		merged, err := result.MergeContentLists(contentLists)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(pt.ContentListPath(), merged, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -15,7 +16,11 @@ import (
 	redis "github.com/neyuki778/LLM-PDF-OCR/internal/store/redis"
 	worker "github.com/neyuki778/LLM-PDF-OCR/internal/worker"
 	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	mineru "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/MinerU"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	translate "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/translate"
 	pdf "github.com/neyuki778/LLM-PDF-OCR/pkg/pdf"
+	result "github.com/neyuki778/LLM-PDF-OCR/pkg/result"
 )
 
 // TaskManager 任务管理器（全局单例）
@@ -27,8 +32,15 @@ type TaskManager struct {
 	// Worker Pool
 	pool *worker.WorkerPool
 
-	// 配置信息
-	config llm.Config
+	// 配置信息，可热加载
+	config           llm.Config
+	configVersion    int64
+	configMu         sync.RWMutex        // 保护 config / configVersion / callbacks / retiredCallbacks
+	callbacks        []*mineru.Callbacks // 当前配置的 MinerU 回调接收器
+	retiredCallbacks []*mineru.Callbacks // 热加载前的 MinerU 回调接收器，等待中的任务处理完后清理
+	reloadMu         sync.Mutex          // 串行化热加载
+	loadConfig       ConfigLoader
+	keyPools         *llm.KeyPools // 热加载之间复用 Gemini key 池
 
 	// 生命周期控制
 	stopChan chan struct{} // 用于停止监听器
@@ -40,28 +52,59 @@ type TaskManager struct {
 type CreateTaskOptions struct {
 	MaxPages    int
 	OwnerUserID string
+	Profile     string             // 文档 profile 名称，仅用于记录
+	ShardSpan   int                // 每个分片的页数，<=0 时使用 defaultShardSpan
+	PostProcess []string           // 聚合后执行的后处理步骤
+	Process     llm.ProcessOptions // 下发给每个分片的处理参数（提示词、首选 provider、MinerU 参数）
+	ModelChoice string             // 模型选项名字（需先经 ResolveChoice 校验），为空时使用顶层配置
 }
 
 type TaskHistoryItem struct {
 	TaskID    string
 	Status    string
+	Usage     report.Usage
+	Cost      float64
 	CreatedAt time.Time
 }
 
-const defaultCreateTaskMaxPages = 30
+const (
+	defaultCreateTaskMaxPages = 30
+	defaultShardSpan          = 2
+)
 
 func NewTaskManager(workCount int, config llm.Config, redisStore *redis.RedisStore) (*TaskManager, error) {
-	processor, err := llm.NewProcessor(config)
+	breakerConfig, err := worker.LoadBreakerConfigFromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create processor: %w", err)
+		return nil, err
+	}
+	retryConfig, err := worker.LoadRetryConfigFromEnv()
+	if err != nil {
+		return nil, err
 	}
-	return &TaskManager{
+	pool := worker.NewWorkerPool(workCount, nil)
+	pool.ConfigureBreaker(breakerConfig)
+	pool.ConfigureRetry(retryConfig)
+	tm := &TaskManager{
 		tasks:      make(map[string]*ParentTask),
-		pool:       worker.NewWorkerPool(workCount, processor),
-		config:     config,
+		pool:       pool,
 		stopChan:   make(chan struct{}),
 		redisStore: redisStore,
-	}, nil
+		keyPools:   llm.NewKeyPools(),
+	}
+	if _, err := tm.ReloadConfig(config); err != nil {
+		return nil, err
+	}
+	return tm, nil
+}
+
+// ShardProgress 返回处理中分片最近一次上报的进度
+func (tm *TaskManager) ShardProgress(subTaskID string) (report.Progress, bool) {
+	return tm.pool.ShardProgress(subTaskID)
+}
+
+// UsesProvider 判断当前配置（含回退链）是否包含指定 provider
+func (tm *TaskManager) UsesProvider(name string) bool {
+	return tm.currentConfig().UsesProvider(name)
 }
 
 func (tm *TaskManager) Start() error {
@@ -90,6 +133,7 @@ func (tm *TaskManager) ListenResult() error {
 func (tm *TaskManager) ShutDown() error {
 	close(tm.stopChan)
 	tm.pool.Shutdown()
+	tm.keyPools.Close()
 	return nil
 }
 
@@ -98,8 +142,20 @@ func (tm *TaskManager) handleResult(signal *worker.CompletionSignal) error {
 	if !exists {
 		return fmt.Errorf("%s don't exists!", signal.ParentID)
 	}
+	config := tm.currentConfig()
 	if !signal.Success {
 		log.Printf("[task] subtask failed parent_id=%s subtask_id=%s err=%v", signal.ParentID, signal.SubTaskID, signal.Error)
+	} else if signal.Provider == "" && len(config.Chain) == 0 {
+		signal.Provider = config.Provider
+	}
+	// 每次尝试按其实际使用的模型计价，回退到其他 provider 的尝试不会按最后一个模型计费
+	signal.Cost = 0
+	for model, usage := range signal.ModelUsage {
+		if cost, ok := config.Prices.Cost(model, usage); ok {
+			signal.Cost += cost
+		} else if model != "" && len(config.Prices) > 0 {
+			log.Printf("[task] no price for model=%s subtask_id=%s", model, signal.SubTaskID)
+		}
 	}
 
 	if err := parentTask.OnSubTaskComplete(signal); err != nil {
@@ -114,14 +170,38 @@ func (tm *TaskManager) handleResult(signal *worker.CompletionSignal) error {
 				return
 			}
 
-			// 2. 聚合完成后修正 MinerU 图片路径
-			if tm.config.Provider == "mineru" {
-				if err := rewriteResultImages(parentTask.OutputPath, tm.config.PublicURL, parentTask.ID); err != nil {
-					log.Printf("[TaskManager] Rewrite images failed for task %s: %v", parentTask.ID, err)
+			// 2. 按 profile 执行后处理，译文与原文相同
+			outputs := []string{parentTask.OutputPath}
+			if translated := parentTask.TranslatedOutputPath(); translated != "" {
+				outputs = append(outputs, translated)
+			}
+			for _, output := range outputs {
+				if err := result.PostProcessFile(output, parentTask.PostProcess); err != nil {
+					log.Printf("[TaskManager] Post-process failed for task %s: %v", parentTask.ID, err)
+				}
+			}
+
+			// 3. 聚合完成后修正 MinerU 图片路径
+			var rewrite func(string) string
+			if tm.taskUsesProvider(parentTask, "mineru") && parentTask.OutputFormat == FormatMarkdown {
+				rewrite = func(content string) string {
+					return rewriteImagePaths(content, config.PublicURL, parentTask.ID)
+				}
+				for _, output := range outputs {
+					if err := rewriteResultImages(output, config.PublicURL, parentTask.ID); err != nil {
+						log.Printf("[TaskManager] Rewrite images failed for task %s: %v", parentTask.ID, err)
+					}
+				}
+			}
+			// 问答使用的分页内容与结果文件做相同处理
+			if parentTask.OutputFormat == FormatMarkdown {
+				if err := parentTask.finishPages(parentTask.PostProcess, rewrite); err != nil {
+					log.Printf("[TaskManager] Post-process pages failed for task %s: %v", parentTask.ID, err)
 				}
 			}
 
-			// 2. 聚合成功后写入 Redis
+			// 4. 所有处理完成后才标记完成，问答和结果接口不会读到未处理的内容；之后写入 Redis
+			parentTask.setStatus(StatusCompleted)
 			ctx := context.Background()
 			createdAt := time.Now().UTC()
 			totalPages := 0
@@ -137,6 +217,7 @@ func (tm *TaskManager) handleResult(signal *worker.CompletionSignal) error {
 					}
 				}
 			}
+			usage, cost := parentTask.UsageTotals()
 			record := &store.TaskRecord{
 				ID:          parentTask.ID,
 				OwnerUserID: ownerUserID,
@@ -144,13 +225,27 @@ func (tm *TaskManager) handleResult(signal *worker.CompletionSignal) error {
 				PDFPath:     parentTask.OriginalPDF,
 				ResultPath:  parentTask.OutputPath,
 				TotalPages:  totalPages,
+				Profile:     parentTask.Profile,
+				ModelChoice: parentTask.ModelChoice,
+				TranslateTo: parentTask.Options.TranslateTo,
+				Format:      parentTask.OutputFormat,
+				Shards:      parentTask.ShardRecords(),
+				Usage:       usage,
+				Cost:        cost,
 				CreatedAt:   createdAt,
 				UpdatedAt:   time.Now().UTC(),
 			}
+			record.MinerU = tm.mineruOptionsRecord(parentTask)
 			if tm.redisStore != nil {
 				if err := tm.redisStore.SaveTaskPersistent(ctx, record); err != nil {
 					log.Printf("[TaskManager] Redis save failed for task %s: %v", parentTask.ID, err)
 				}
+				// 5. 计入用户月度用量
+				if ownerUserID != "" {
+					if err := tm.redisStore.AddUserUsage(ctx, ownerUserID, createdAt, usage, cost); err != nil {
+						log.Printf("[TaskManager] Add user usage failed task_id=%s user_id=%s err=%v", parentTask.ID, ownerUserID, err)
+					}
+				}
 			}
 		}()
 	}
@@ -167,6 +262,40 @@ func (tm *TaskManager) CreateTask(pdfPath string) (taskID string, err error) {
 
 // CreateTaskWithOptions 完整的任务创建功能，包含 PDF 切分。
 func (tm *TaskManager) CreateTaskWithOptions(pdfPath string, options CreateTaskOptions) (taskID string, err error) {
+	var choice llm.ModelChoice
+	if options.ModelChoice != "" {
+		var ok bool
+		if choice, ok = tm.currentConfig().Choice(options.ModelChoice); !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnknownModelChoice, options.ModelChoice)
+		}
+	}
+	extraction := len(options.Process.ResponseSchema) > 0
+	if extraction {
+		supported := tm.currentConfig().UsesProvider("gemini")
+		if choice.Name != "" {
+			supported = choice.Provider == "gemini"
+		}
+		if !supported {
+			return "", ErrExtractionUnsupported
+		}
+	}
+
+	if options.Process.TranslateTo != "" {
+		lang, err := translate.NormalizeLanguage(options.Process.TranslateTo)
+		if err != nil {
+			return "", err
+		}
+		options.Process.TranslateTo = lang
+		config := tm.currentConfig()
+		supported := config.UsesProvider("gemini") || config.UsesProvider("mock")
+		if choice.Name != "" {
+			supported = choice.Provider == "gemini" || choice.Provider == "mock"
+		}
+		if extraction || !supported {
+			return "", ErrTranslationUnsupported
+		}
+	}
+
 	taskID = uuid.New().String()
 	workDir := filepath.Join("./output/", taskID)
 
@@ -190,15 +319,37 @@ func (tm *TaskManager) CreateTaskWithOptions(pdfPath string, options CreateTaskO
 		}
 	}
 
-	// 目前固定每个PDF最大2页
-	span := 2
+	// page_ranges 选择需要处理的页，没有选中页的分片不创建；
+	// 分片内页码只有 MinerU 支持，其他 provider 处理部分选中分片的全部页
+	selectedPages, err := selectPages(options.Process.MinerU.PageRanges, totalPages)
+	if err != nil {
+		return "", err
+	}
+
+	span := options.ShardSpan
+	if span <= 0 {
+		span = defaultShardSpan
+	}
 	totalShards := (totalPages + span - 1) / span
 
 	parentTask := NewParentTask(taskID, pdfPath, workDir)
 	parentTask.OwnerUserID = strings.TrimSpace(options.OwnerUserID)
-	parentTask.TotalShards = totalShards
+	parentTask.Profile = options.Profile
+	parentTask.PostProcess = options.PostProcess
+	parentTask.Options = options.Process
+	parentTask.ModelChoice = choice.Name
+	parentTask.ChoiceProvider = choice.Provider
+	shardExt := ".md"
+	if extraction {
+		// 结构化抽取：分片输出 JSON，聚合为 result.json，不做 Markdown 后处理
+		parentTask.OutputFormat = FormatJSON
+		parentTask.OutputPath = filepath.Join(workDir, "result.json")
+		parentTask.PostProcess = nil
+		shardExt = ".json"
+	}
 
 	// 创建并填充sub-task
+	var skippedSplits []string
 	for i := range totalShards {
 
 		subTaskID := fmt.Sprintf("%s_%d", taskID, i+1)
@@ -211,7 +362,13 @@ func (tm *TaskManager) CreateTaskWithOptions(pdfPath string, options CreateTaskO
 			splitFileName = fmt.Sprintf("%s_%d.pdf", nameWithoutExt, pageStart)
 		}
 		splitPath := filepath.Join(workDir, splitFileName)
-		tempFilePath := filepath.Join(workDir, fmt.Sprintf("page_%d.md", i+1))
+		tempFilePath := filepath.Join(workDir, fmt.Sprintf("page_%d%s", i+1, shardExt))
+
+		pageRanges, selected := shardPageRanges(selectedPages, pageStart, pageEnd)
+		if !selected {
+			skippedSplits = append(skippedSplits, splitPath)
+			continue
+		}
 
 		meta := SubTaskMeta{
 			ID:           subTaskID,
@@ -219,6 +376,7 @@ func (tm *TaskManager) CreateTaskWithOptions(pdfPath string, options CreateTaskO
 			PageEnd:      pageEnd,
 			SplitPDFPath: splitPath,
 			TempFilePath: tempFilePath,
+			PageRanges:   pageRanges,
 			Status:       SubTaskPending,
 			Error:        nil,
 		}
@@ -231,6 +389,10 @@ func (tm *TaskManager) CreateTaskWithOptions(pdfPath string, options CreateTaskO
 	if err := pdf.SplitPDF(ctx, pdfPath, workDir, span); err != nil {
 		return "", fmt.Errorf("failed to split PDF: %w", err)
 	}
+	for _, path := range skippedSplits {
+		os.Remove(path)
+	}
+	parentTask.TotalShards = len(parentTask.SubTasks)
 
 	tm.mu.Lock()
 	tm.tasks[taskID] = parentTask
@@ -250,7 +412,12 @@ func (tm *TaskManager) SubmitTaskToPool(taskID string, timeout time.Duration) er
 		return fmt.Errorf("task not found: %s", taskID)
 	}
 
+	// 先标记处理中：分片提交后可能立即完成并触发聚合
+	parentTask.setStatus(StatusProcessing)
 	for _, subTask := range parentTask.SubTasks {
+		// 任务级 page_ranges 已换算为分片内页码
+		shardOptions := parentTask.Options
+		shardOptions.MinerU.PageRanges = subTask.PageRanges
 		workerTask := &worker.SubTask{
 			ID:         subTask.ID,
 			ParentID:   taskID,
@@ -258,6 +425,8 @@ func (tm *TaskManager) SubmitTaskToPool(taskID string, timeout time.Duration) er
 			OutputPath: subTask.TempFilePath,
 			PageStart:  subTask.PageStart,
 			PageEnd:    subTask.PageEnd,
+			Options:    shardOptions,
+			Processor:  parentTask.ModelChoice,
 		}
 
 		if err := tm.pool.Submit(workerTask, timeout); err != nil {
@@ -265,7 +434,6 @@ func (tm *TaskManager) SubmitTaskToPool(taskID string, timeout time.Duration) er
 		}
 	}
 
-	parentTask.Status = StatusProcessing
 	return nil
 }
 
@@ -287,7 +455,7 @@ func (tm *TaskManager) WaitForTask(taskID string, timeout time.Duration) error {
 		case <-ddl:
 			return fmt.Errorf("timeout waiting for task %s", taskID)
 		case <-ticker.C:
-			if parentTask.Status == StatusCompleted {
+			if parentTask.CurrentStatus() == StatusCompleted {
 				return nil
 			}
 		}
@@ -313,15 +481,49 @@ func (tm *TaskManager) GetTask(taskID string) *ParentTask {
 	}
 
 	// 3. 把 TaskRecord 转成 ParentTask 返回
-	// 注意：这是一个"只读"的 ParentTask，只包含基本信息
-	return &ParentTask{
-		ID:          record.ID,
-		OwnerUserID: record.OwnerUserID,
-		Status:      record.Status,
-		OriginalPDF: record.PDFPath,
-		OutputPath:  record.ResultPath,
-		// SubTasks 等运行时信息已丢失，保持为空
+	// 注意：这是一个"只读"的 ParentTask，只包含基本信息和持久化的分片结果
+	subTasks := make(map[string]*SubTaskMeta, len(record.Shards))
+	for _, shard := range record.Shards {
+		subTasks[shard.ID] = &SubTaskMeta{
+			ID:        shard.ID,
+			PageStart: shard.PageStart,
+			PageEnd:   shard.PageEnd,
+			Status:    shard.Status,
+			Provider:  shard.Provider,
+			Model:     shard.Model,
+			Usage:     shard.Usage,
+			Cost:      shard.Cost,
+		}
+		if shard.TranslateError != "" {
+			subTasks[shard.ID].TranslateError = errors.New(shard.TranslateError)
+		}
 	}
+	completedCount := 0
+	if record.Status == StatusCompleted {
+		completedCount = len(subTasks)
+	}
+	format := record.Format
+	if format == "" {
+		format = FormatMarkdown
+	}
+	restored := &ParentTask{
+		ID:             record.ID,
+		OwnerUserID:    record.OwnerUserID,
+		Profile:        record.Profile,
+		ModelChoice:    record.ModelChoice,
+		Status:         record.Status,
+		OriginalPDF:    record.PDFPath,
+		OutputPath:     record.ResultPath,
+		OutputFormat:   format,
+		TotalShards:    len(subTasks),
+		SubTasks:       subTasks,
+		CompletedCount: completedCount,
+	}
+	if record.MinerU != nil {
+		restored.Options.MinerU = *record.MinerU
+	}
+	restored.Options.TranslateTo = record.TranslateTo
+	return restored
 }
 
 func (tm *TaskManager) ListUserTaskHistory(userID string, cursor time.Time, limit int64) ([]TaskHistoryItem, error) {
@@ -336,19 +538,52 @@ func (tm *TaskManager) ListUserTaskHistory(userID string, cursor time.Time, limi
 
 	items := make([]TaskHistoryItem, 0, len(entries))
 	for _, entry := range entries {
-		status := StatusPending
-		if task := tm.GetTask(entry.TaskID); task != nil && strings.TrimSpace(task.Status) != "" {
-			status = task.Status
-		}
-		items = append(items, TaskHistoryItem{
+		item := TaskHistoryItem{
 			TaskID:    entry.TaskID,
-			Status:    status,
+			Status:    StatusPending,
 			CreatedAt: entry.CreatedAt,
-		})
+		}
+		if task := tm.GetTask(entry.TaskID); task != nil {
+			if status := task.CurrentStatus(); strings.TrimSpace(status) != "" {
+				item.Status = status
+			}
+			item.Usage, item.Cost = task.UsageTotals()
+		}
+		items = append(items, item)
 	}
 	return items, nil
 }
 
+// GetUserUsage 返回用户某月（YYYY-MM）的汇总用量
+func (tm *TaskManager) GetUserUsage(userID, month string) (*store.UserUsage, error) {
+	if tm.redisStore == nil {
+		return nil, fmt.Errorf("redis store is not configured")
+	}
+	return tm.redisStore.GetUserUsage(context.Background(), userID, month)
+}
+
+// ListUsage 返回某月所有用户的汇总用量，用于内部结算
+func (tm *TaskManager) ListUsage(month string) ([]store.UserUsage, error) {
+	if tm.redisStore == nil {
+		return nil, fmt.Errorf("redis store is not configured")
+	}
+	return tm.redisStore.ListMonthUsage(context.Background(), month)
+}
+
+// MinerUOptions 返回任务的 MinerU 参数，任务不会用到 mineru（含所选模型选项）时为 nil
+func (tm *TaskManager) MinerUOptions(parentTask *ParentTask) *llm.MinerUOptions {
+	return tm.mineruOptionsRecord(parentTask)
+}
+
+// mineruOptionsRecord 返回记录在任务上的 MinerU 参数，未配置 mineru 时为 nil
+func (tm *TaskManager) mineruOptionsRecord(parentTask *ParentTask) *llm.MinerUOptions {
+	if !tm.taskUsesProvider(parentTask, "mineru") {
+		return nil
+	}
+	mineruOptions := parentTask.Options.MinerU
+	return &mineruOptions
+}
+
 func (tm *TaskManager) persistTaskCreateMetadata(parentTask *ParentTask, totalPages int) {
 	if tm.redisStore == nil || parentTask == nil {
 		return
@@ -363,9 +598,14 @@ func (tm *TaskManager) persistTaskCreateMetadata(parentTask *ParentTask, totalPa
 		PDFPath:     parentTask.OriginalPDF,
 		ResultPath:  parentTask.OutputPath,
 		TotalPages:  totalPages,
+		Profile:     parentTask.Profile,
+		ModelChoice: parentTask.ModelChoice,
+		TranslateTo: parentTask.Options.TranslateTo,
+		Format:      parentTask.OutputFormat,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
+	record.MinerU = tm.mineruOptionsRecord(parentTask)
 	if err := tm.redisStore.SaveTaskPersistent(ctx, record); err != nil {
 		log.Printf("[task] save task metadata failed task_id=%s owner_user_id=%s err=%v", parentTask.ID, parentTask.OwnerUserID, err)
 		return
@@ -391,24 +631,45 @@ func (tm *TaskManager) GetStatus() map[string]interface{} {
 	}
 
 	for _, task := range tm.tasks {
-		statusCount[task.Status]++
+		statusCount[task.CurrentStatus()]++
 	}
 
-	// 脱敏配置信息（隐藏 APIKey）
-	sanitizedConfig := map[string]interface{}{
-		"provider":   tm.config.Provider,
-		"model":      tm.config.Model,
-		"base_url":   tm.config.BaseURL,
-		"public_url": tm.config.PublicURL,
-		"api_key":    maskAPIKey(tm.config.APIKey),
+	return map[string]interface{}{
+		"total_tasks":    len(tm.tasks),
+		"task_status":    statusCount,
+		"worker_pool":    tm.pool.GetStatus(),
+		"config":         sanitizeConfig(tm.currentConfig()),
+		"config_version": tm.ConfigVersion(),
 	}
+}
 
-	return map[string]interface{}{
-		"total_tasks": len(tm.tasks),
-		"task_status": statusCount,
-		"worker_pool": tm.pool.GetStatus(),
-		"config":      sanitizedConfig,
+// sanitizeConfig 脱敏配置信息（隐藏 APIKey），回退链逐个展开
+func sanitizeConfig(cfg llm.Config) map[string]interface{} {
+	sanitized := map[string]interface{}{
+		"provider":   cfg.Provider,
+		"model":      cfg.Model,
+		"base_url":   cfg.BaseURL,
+		"public_url": cfg.PublicURL,
+		"api_key":    maskAPIKey(cfg.APIKey),
+	}
+	if len(cfg.Chain) > 0 {
+		chain := make([]map[string]interface{}, 0, len(cfg.Chain))
+		for _, member := range cfg.Chain {
+			chain = append(chain, sanitizeConfig(member))
+		}
+		sanitized["chain"] = chain
+	}
+	if len(cfg.Choices) > 0 {
+		choices := make([]map[string]interface{}, 0, len(cfg.Choices))
+		for _, choice := range cfg.Choices {
+			item := sanitizeConfig(choice.Config)
+			item["name"] = choice.Name
+			item["tiers"] = choice.Tiers
+			choices = append(choices, item)
+		}
+		sanitized["choices"] = choices
 	}
+	return sanitized
 }
 
 // maskAPIKey 脱敏 API Key，只显示前后几位
@@ -423,7 +684,7 @@ func maskAPIKey(apiKey string) string {
 }
 
 func rewriteResultImages(outputPath, publicURL, taskID string) error {
-	if publicURL == "" || taskID == "" {
+	if taskID == "" {
 		return nil
 	}
 
@@ -432,10 +693,14 @@ func rewriteResultImages(outputPath, publicURL, taskID string) error {
 		return err
 	}
 
+	return os.WriteFile(outputPath, []byte(rewriteImagePaths(string(content), publicURL, taskID)), 0644)
+}
+
+// rewriteImagePaths 把 MinerU 结果中的相对图片路径改为可访问的地址；
+// 未配置 PUBLIC_URL 时使用站内绝对路径，由 /output 路由公开提供
+func rewriteImagePaths(content, publicURL, taskID string) string {
 	base := strings.TrimRight(publicURL, "/")
 	prefix := base + "/output/" + taskID + "/images/"
-	updated := strings.ReplaceAll(string(content), "](images/", "]("+prefix)
-	updated = strings.ReplaceAll(updated, "src=\"images/", "src=\""+prefix)
-
-	return os.WriteFile(outputPath, []byte(updated), 0644)
+	updated := strings.ReplaceAll(content, "](images/", "]("+prefix)
+	return strings.ReplaceAll(updated, "src=\"images/", "src=\""+prefix)
 }
@@ -0,0 +1,38 @@
+package task
+
+import (
+	"sort"
+
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+)
+
+// selectPages 解析任务级 page_ranges（原文档页码），spec 为空时返回 nil 表示全部页
+func selectPages(spec string, totalPages int) ([]int, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	return options.ParsePageRanges(spec, totalPages)
+}
+
+// shardPageRanges 把选中页换算为分片 [pageStart, pageEnd] 内的页码。
+// 整个分片都被选中时返回空字符串（不限制）；分片内没有选中页时 ok 为 false，该分片不需要处理。
+func shardPageRanges(pages []int, pageStart, pageEnd int) (spec string, ok bool) {
+	if pages == nil {
+		return "", true
+	}
+	first := sort.SearchInts(pages, pageStart)
+	local := make([]int, 0, pageEnd-pageStart+1)
+	for _, page := range pages[first:] {
+		if page > pageEnd {
+			break
+		}
+		local = append(local, page-pageStart+1)
+	}
+	if len(local) == 0 {
+		return "", false
+	}
+	if len(local) == pageEnd-pageStart+1 {
+		return "", true
+	}
+	return options.FormatPageRanges(local), true
+}
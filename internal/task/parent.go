@@ -1,12 +1,17 @@
 package task
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
 
+	store "github.com/neyuki778/LLM-PDF-OCR/internal/store"
 	worker "github.com/neyuki778/LLM-PDF-OCR/internal/worker"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	schema "github.com/neyuki778/LLM-PDF-OCR/pkg/schema"
 )
 
 func NewParentTask(id, pdfPath, workDir string) *ParentTask {
@@ -15,6 +20,7 @@ func NewParentTask(id, pdfPath, workDir string) *ParentTask {
 		OriginalPDF:    pdfPath,
 		WorkDir:        workDir,
 		OutputPath:     filepath.Join(workDir, "result.md"),
+		OutputFormat:   FormatMarkdown,
 		TotalShards:    0,
 		SubTasks:       make(map[string]*SubTaskMeta),
 		CompletedCount: 0,
@@ -31,10 +37,17 @@ func (pt *ParentTask) OnSubTaskComplete(signal *worker.CompletionSignal) error {
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
 
+	subTask := pt.SubTasks[signal.SubTaskID]
+	subTask.Model = signal.Model
+	subTask.Usage = signal.Usage
+	subTask.Cost = signal.Cost
 	if signal.Success {
 		pt.SubTasks[signal.SubTaskID].Status = SubTaskSuccess
+		pt.SubTasks[signal.SubTaskID].Provider = signal.Provider
+		pt.SubTasks[signal.SubTaskID].TranslateError = signal.TranslateError
 	} else {
 		pt.SubTasks[signal.SubTaskID].Status = SubTaskFailed
+		pt.SubTasks[signal.SubTaskID].Error = signal.Error
 		pt.FailedTasks = append(pt.FailedTasks, signal.SubTaskID)
 	}
 	pt.CompletedCount++
@@ -46,6 +59,54 @@ func (pt *ParentTask) IsAllDone() bool {
 }
 
 func (pt *ParentTask) doAggregate() error {
+	write := pt.aggregateMarkdown
+	if pt.OutputFormat == FormatJSON {
+		write = pt.aggregateJSON
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	// 译文是附加产物，合并失败不影响原文结果
+	if pt.OutputFormat == FormatMarkdown && pt.Options.TranslateTo != "" {
+		if err := pt.aggregateTranslation(); err != nil {
+			log.Printf("[task] aggregate translation failed task_id=%s lang=%s err=%v", pt.ID, pt.Options.TranslateTo, err)
+		}
+	}
+	// 分页内容供文档问答检索，保存失败时问答退化为整篇检索
+	if pt.OutputFormat == FormatMarkdown {
+		if err := pt.aggregatePages(); err != nil {
+			log.Printf("[task] aggregate pages failed task_id=%s err=%v", pt.ID, err)
+		}
+	}
+	// 版面文件只是附加产物，合并失败不影响结果
+	if err := pt.aggregateLayout(); err != nil {
+		log.Printf("[task] aggregate layout failed task_id=%s err=%v", pt.ID, err)
+	}
+
+	// 清除临时文件
+	for _, subTask := range pt.SubTasks {
+		os.Remove(subTask.SplitPDFPath)
+		os.Remove(subTask.TempFilePath)
+	}
+
+	// 状态由 TaskManager 在后处理和持久化都完成后设为 completed
+	return nil
+}
+
+// CurrentStatus 返回任务状态，可与聚合、提交并发调用
+func (pt *ParentTask) CurrentStatus() string {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.Status
+}
+
+func (pt *ParentTask) setStatus(status string) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.Status = status
+}
+
+func (pt *ParentTask) aggregateMarkdown() error {
 	file, err := os.OpenFile(pt.OutputPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
@@ -70,17 +131,36 @@ func (pt *ParentTask) doAggregate() error {
 			}
 		}
 	}
+	return nil
+}
 
-	// 清除临时文件
-	for _, subTask := range pt.SubTasks {
-		os.Remove(subTask.SplitPDFPath)
-		os.Remove(subTask.TempFilePath)
+// aggregateJSON 按页码顺序合并各分片的 JSON 抽取结果，失败的分片跳过（状态见 shards）
+func (pt *ParentTask) aggregateJSON() error {
+	values := make([]any, 0, len(pt.SubTasks))
+	for _, subTaskMeta := range pt.SortSubTasksByPageStart() {
+		if subTaskMeta.Status != SubTaskSuccess {
+			continue
+		}
+		content, err := os.ReadFile(subTaskMeta.TempFilePath)
+		if err != nil {
+			return err
+		}
+		var value any
+		if err := json.Unmarshal(content, &value); err != nil {
+			return fmt.Errorf("parse shard %s: %w", subTaskMeta.ID, err)
+		}
+		values = append(values, value)
 	}
 
-	// 更新parent task status
-	pt.Status = StatusCompleted
-
-	return nil
+	merged := schema.Merge(values)
+	if merged == nil {
+		merged = map[string]any{}
+	}
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pt.OutputPath, append(data, '\n'), 0644)
 }
 
 func (pt *ParentTask) SortSubTasksByPageStart() []*SubTaskMeta {
@@ -96,6 +176,91 @@ func (pt *ParentTask) SortSubTasksByPageStart() []*SubTaskMeta {
 	return list
 }
 
+// ShardRecords 返回按页码排序的分片快照，用于状态展示和持久化
+func (pt *ParentTask) ShardRecords() []store.ShardRecord {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	records := make([]store.ShardRecord, 0, len(pt.SubTasks))
+	for _, subTask := range pt.SortSubTasksByPageStart() {
+		record := store.ShardRecord{
+			ID:        subTask.ID,
+			PageStart: subTask.PageStart,
+			PageEnd:   subTask.PageEnd,
+			Status:    subTask.Status,
+			Provider:  subTask.Provider,
+			Model:     subTask.Model,
+			Usage:     subTask.Usage,
+			Cost:      subTask.Cost,
+		}
+		if subTask.TranslateError != nil {
+			record.TranslateError = subTask.TranslateError.Error()
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// UsageTotals 汇总所有分片的用量和费用
+func (pt *ParentTask) UsageTotals() (report.Usage, float64) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	var (
+		usage report.Usage
+		cost  float64
+	)
+	for _, subTask := range pt.SubTasks {
+		usage.Add(subTask.Usage)
+		cost += subTask.Cost
+	}
+	return usage, cost
+}
+
+// ShardPreview 分片的实时预览
+type ShardPreview struct {
+	ID        string
+	PageStart int
+	PageEnd   int
+	Status    string
+	Content   string // 已产出的 Markdown，尚未开始时为空
+	Partial   bool   // true 表示内容来自仍在生成中的 .partial 文件
+}
+
+// Preview 读取各分片当前的输出：已完成的读最终文件，处理中的读 .partial 文件。
+// 聚合完成后临时文件已被清理，此时应直接读取结果文件。
+func (pt *ParentTask) Preview() []ShardPreview {
+	pt.mu.Lock()
+	subTasks := pt.SortSubTasksByPageStart()
+	previews := make([]ShardPreview, 0, len(subTasks))
+	paths := make([]string, 0, len(subTasks))
+	for _, subTask := range subTasks {
+		previews = append(previews, ShardPreview{
+			ID:        subTask.ID,
+			PageStart: subTask.PageStart,
+			PageEnd:   subTask.PageEnd,
+			Status:    subTask.Status,
+		})
+		paths = append(paths, subTask.TempFilePath)
+	}
+	pt.mu.Unlock()
+
+	for i := range previews {
+		if paths[i] == "" {
+			continue
+		}
+		if content, err := os.ReadFile(paths[i] + worker.PartialSuffix); err == nil {
+			previews[i].Content = string(content)
+			previews[i].Partial = true
+			continue
+		}
+		if content, err := os.ReadFile(paths[i]); err == nil {
+			previews[i].Content = string(content)
+		}
+	}
+	return previews
+}
+
 func (pt *ParentTask) Aggregate() error {
 	var err error
 	pt.aggregateOnce.Do(func() {
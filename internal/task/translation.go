@@ -0,0 +1,48 @@
+package task
+
+import (
+	"fmt"
+	"os"
+
+	worker "github.com/neyuki778/LLM-PDF-OCR/internal/worker"
+)
+
+// TranslatedOutputPath 译文结果路径：./output/{ID}/result.{lang}.md，未请求翻译时为空
+func (pt *ParentTask) TranslatedOutputPath() string {
+	if pt.Options.TranslateTo == "" {
+		return ""
+	}
+	return worker.TranslatedPath(pt.OutputPath, pt.Options.TranslateTo)
+}
+
+// aggregateTranslation 按页码顺序合并各分片的译文并删除分片译文；
+// OCR 或翻译失败的分片写入注释占位，原文结果不受影响
+func (pt *ParentTask) aggregateTranslation() error {
+	file, err := os.OpenFile(pt.TranslatedOutputPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, subTaskMeta := range pt.SortSubTasksByPageStart() {
+		path := worker.TranslatedPath(subTaskMeta.TempFilePath, pt.Options.TranslateTo)
+		switch {
+		case subTaskMeta.Status != SubTaskSuccess:
+			_, err = fmt.Fprintf(file, "<!-- [OCR Failed] Pages %d-%d: %s -->\n",
+				subTaskMeta.PageStart, subTaskMeta.PageEnd, subTaskMeta.ID)
+		case subTaskMeta.TranslateError != nil:
+			_, err = fmt.Fprintf(file, "<!-- [Translation Failed] Pages %d-%d: %s -->\n",
+				subTaskMeta.PageStart, subTaskMeta.PageEnd, subTaskMeta.ID)
+		default:
+			var content []byte
+			if content, err = os.ReadFile(path); err == nil {
+				_, err = file.Write(content)
+			}
+		}
+		os.Remove(path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,31 +2,51 @@ package task
 
 import (
 	"sync"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
 )
 
 // SubTaskMeta 子任务元信息（ParentTask用于追踪）
 type SubTaskMeta struct {
-	ID           string // 子任务ID
-	PageStart    int    // 起始页码
-	PageEnd      int    // 结束页码
-	SplitPDFPath string // 分片PDF路径：./output/{parentID}/split_1.pdf
-	TempFilePath string // 临时MD路径：./output/{parentID}/page_1.md
-	Status       string // pending/processing/success/failed
-	Error        error  // 失败时的错误信息
+	ID           string       // 子任务ID
+	PageStart    int          // 起始页码
+	PageEnd      int          // 结束页码
+	SplitPDFPath string       // 分片PDF路径：./output/{parentID}/split_1.pdf
+	TempFilePath string       // 临时MD路径：./output/{parentID}/page_1.md
+	PageRanges   string       // 分片内需要处理的页（MinerU page_ranges），为空表示全部
+	Status       string       // pending/processing/success/failed
+	Error        error        // 失败时的错误信息
+	Provider     string       // 产出结果的 provider
+	Model        string       // 实际使用的模型
+	Usage        report.Usage // 累计用量（含失败的尝试）
+	Cost         float64      // 按价目表计算的费用
+	// TranslateError 请求了翻译但该分片翻译失败，原文结果不受影响
+	TranslateError error
 }
 
 // ParentTask 父任务（对应一个完整的PDF处理请求）
 type ParentTask struct {
-	ID          string // 任务唯一ID（UUID）
-	OwnerUserID string // 任务归属用户ID，游客为空
-	OriginalPDF string // 原始PDF路径（输入）
-	WorkDir     string // 工作目录：./output/{ID}/
-	OutputPath  string // 最终结果路径：./output/{ID}/result.md
+	ID           string // 任务唯一ID（UUID）
+	OwnerUserID  string // 任务归属用户ID，游客为空
+	Profile      string // 文档 profile 名称，未选择时为空
+	OriginalPDF  string // 原始PDF路径（输入）
+	WorkDir      string // 工作目录：./output/{ID}/
+	OutputPath   string // 最终结果路径：./output/{ID}/result.md，结构化抽取时为 result.json
+	OutputFormat string // FormatMarkdown / FormatJSON
 
 	// 分片信息
 	TotalShards int                     // 总分片数
 	SubTasks    map[string]*SubTaskMeta // key: SubTaskID
 
+	// 处理参数（提示词等），提交时下发给每个分片
+	Options     llm.ProcessOptions
+	PostProcess []string // 聚合后执行的后处理步骤
+
+	// 模型选项（LLM_MODEL_CHOICES），为空时使用顶层配置
+	ModelChoice    string
+	ChoiceProvider string
+
 	// 进度追踪
 	CompletedCount int      // 已完成数量（成功+失败）
 	FailedTasks    []string // 失败的SubTaskID列表
@@ -47,6 +67,12 @@ const (
 	StatusFailed     = "failed"
 )
 
+// 结果格式
+const (
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json" // 结构化抽取
+)
+
 // SubTaskStatus 定义子任务状态常量
 const (
 	SubTaskPending    = "pending"
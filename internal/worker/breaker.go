@@ -0,0 +1,240 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	async "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/async"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+)
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// ErrBreakerOpen provider 的熔断器打开，不经过队列的调用直接返回
+var ErrBreakerOpen = errors.New("provider circuit breaker is open")
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// BreakerConfig 熔断参数：连续失败 Threshold 次后打开，Cooldown 后放行一个探测请求
+type BreakerConfig struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+// LoadBreakerConfigFromEnv 读取 WORKER_BREAKER_THRESHOLD / WORKER_BREAKER_COOLDOWN，未设置时使用默认值
+func LoadBreakerConfigFromEnv() (BreakerConfig, error) {
+	cfg := BreakerConfig{Threshold: defaultBreakerThreshold, Cooldown: defaultBreakerCooldown}
+	if raw := strings.TrimSpace(os.Getenv("WORKER_BREAKER_THRESHOLD")); raw != "" {
+		threshold, err := strconv.Atoi(raw)
+		if err != nil || threshold <= 0 {
+			return BreakerConfig{}, fmt.Errorf("invalid WORKER_BREAKER_THRESHOLD: %s", raw)
+		}
+		cfg.Threshold = threshold
+	}
+	if raw := strings.TrimSpace(os.Getenv("WORKER_BREAKER_COOLDOWN")); raw != "" {
+		cooldown, err := time.ParseDuration(raw)
+		if err != nil || cooldown <= 0 {
+			return BreakerConfig{}, fmt.Errorf("invalid WORKER_BREAKER_COOLDOWN: %s", raw)
+		}
+		cfg.Cooldown = cooldown
+	}
+	return cfg, nil
+}
+
+// breaker 单个 processor 的熔断器。打开期间取出的分片放回等待，不判失败；
+// 冷却结束后进入半开状态，只放行一个探测调用，成功则关闭，失败则重新打开。
+// 回退链没有整体的熔断器（nil，总是放行），由 breakerGuard 按成员熔断。
+type breaker struct {
+	provider  string
+	threshold int
+	cooldown  time.Duration
+	nowFn     func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int // 连续失败次数
+	openedAt time.Time
+	probing  bool // 半开状态下探测调用是否在进行中
+}
+
+func newBreaker(provider string, cfg BreakerConfig) *breaker {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = defaultBreakerThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultBreakerCooldown
+	}
+	return &breaker{
+		provider:  provider,
+		threshold: cfg.Threshold,
+		cooldown:  cfg.Cooldown,
+		nowFn:     time.Now,
+		state:     BreakerClosed,
+	}
+}
+
+// tryAcquire 检查是否允许发起调用，不阻塞；不允许时返回最早可能放行的等待时间。
+// 返回的 probe 表示本次是半开状态下的探测调用，调用结束后必须以 record 或 release 归还。
+func (b *breaker) tryAcquire() (probe bool, ok bool, wait time.Duration) {
+	if b == nil {
+		return false, true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		wait = b.openedAt.Add(b.cooldown).Sub(b.nowFn())
+		if wait > 0 {
+			return false, false, wait
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true, true, 0
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, false, b.cooldown
+		}
+		b.probing = true
+		return true, true, 0
+	}
+	return false, true, 0
+}
+
+// ready 检查现在是否可能放行调用，不改变状态；不能时返回最早可能放行的等待时间
+func (b *breaker) ready() (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		wait := b.openedAt.Add(b.cooldown).Sub(b.nowFn())
+		return wait <= 0, max(wait, 0)
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, b.cooldown
+		}
+	}
+	return true, 0
+}
+
+// allowRetry 分片重试前检查：只有关闭状态下才在当前 worker 内继续重试
+func (b *breaker) allowRetry() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == BreakerClosed
+}
+
+// record 记录一次调用结果，返回调用后熔断器是否处于关闭状态
+func (b *breaker) record(probe, success bool) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+
+	if success {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			log.Printf("[worker] breaker closed provider=%s", b.provider)
+			b.state = BreakerClosed
+		}
+		return true
+	}
+
+	b.failures++
+	switch {
+	case b.state == BreakerHalfOpen && probe:
+		b.open()
+	case b.state == BreakerClosed && b.failures >= b.threshold:
+		b.open()
+	}
+	return b.state == BreakerClosed
+}
+
+// release 归还未产生结果的调用（如 worker 关闭时被取消），不影响计数
+func (b *breaker) release(probe bool) {
+	if b == nil || !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// settle 按调用错误记录结果，规则与分片处理相同：已交给远端异步处理视为成功；
+// 取消、不可重试、内容被拦截和带等待时间的限流与 provider 是否可用无关，不计入熔断
+func (b *breaker) settle(probe bool, err error) {
+	var pending *async.Pending
+	switch {
+	case err == nil || errors.As(err, &pending):
+		b.record(probe, true)
+	case errors.Is(err, context.Canceled) || llmerr.Fatal(err) || llmerr.RetryAfter(err) > 0:
+		b.release(probe)
+	default:
+		b.record(probe, false)
+	}
+}
+
+// breakerGuard 回退链按成员熔断，成员的熔断器以 provider 名为 key，与单 provider 配置共用
+type breakerGuard struct {
+	wp *WorkerPool
+}
+
+func (g breakerGuard) Allow(provider string) (func(error), time.Duration, bool) {
+	b := g.wp.breakerFor(provider)
+	probe, ok, wait := b.tryAcquire()
+	if !ok {
+		return nil, wait, false
+	}
+	return func(err error) { b.settle(probe, err) }, 0, true
+}
+
+func (b *breaker) open() {
+	b.openedAt = b.nowFn()
+	log.Printf(
+		"[worker] breaker opened provider=%s consecutive_failures=%d cooldown=%s",
+		b.provider,
+		b.failures,
+		b.cooldown,
+	)
+	b.state = BreakerOpen
+}
+
+// status 供 GetStatus 展示
+func (b *breaker) status() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := map[string]interface{}{
+		"state":                b.state,
+		"consecutive_failures": b.failures,
+		"threshold":            b.threshold,
+		"cooldown":             b.cooldown.String(),
+	}
+	if b.state == BreakerOpen {
+		status["retry_at"] = b.openedAt.Add(b.cooldown)
+	}
+	return status
+}
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAndProbes(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBreaker("gemini", BreakerConfig{Threshold: 2, Cooldown: time.Minute})
+	b.nowFn = func() time.Time { return now }
+
+	if !b.record(false, false) {
+		t.Fatalf("breaker should stay closed below threshold")
+	}
+	if b.record(false, false) {
+		t.Fatalf("breaker should open at threshold")
+	}
+	if b.allowRetry() {
+		t.Fatalf("open breaker must not allow retries")
+	}
+
+	if _, ok, wait := b.tryAcquire(); ok || wait != time.Minute {
+		t.Fatalf("open breaker must wait for cooldown, ok=%v wait=%s", ok, wait)
+	}
+
+	now = now.Add(time.Minute)
+	probe, ok, _ := b.tryAcquire()
+	if !ok || !probe {
+		t.Fatalf("expected half-open probe after cooldown, probe=%v ok=%v", probe, ok)
+	}
+	if got := b.status()["state"]; got != BreakerHalfOpen {
+		t.Fatalf("state = %v, want %s", got, BreakerHalfOpen)
+	}
+
+	// 探测进行中时其他调用继续等待
+	if _, ok, _ := b.tryAcquire(); ok {
+		t.Fatalf("only one probe may run in half-open state")
+	}
+
+	if b.record(true, false) {
+		t.Fatalf("failed probe should reopen the breaker")
+	}
+	now = now.Add(time.Minute)
+	probe, _, _ = b.tryAcquire()
+	if !b.record(probe, true) {
+		t.Fatalf("successful probe should close the breaker")
+	}
+	if probe, ok, _ := b.tryAcquire(); !ok || probe {
+		t.Fatalf("closed breaker should allow calls without probing")
+	}
+}
+
+func TestHeldShardKeepsUsage(t *testing.T) {
+	processor := &fallbackProcessor{}
+	wp := NewWorkerPool(1, processor)
+	wp.ConfigureRetry(RetryConfig{MaxRetries: 3, BackoffBase: time.Millisecond, BackoffMax: time.Millisecond})
+	wp.ConfigureBreaker(BreakerConfig{Threshold: 1, Cooldown: 10 * time.Millisecond})
+	wp.Start()
+	defer wp.Shutdown()
+
+	task := &SubTask{ID: "s1", ParentID: "p1", OutputPath: filepath.Join(t.TempDir(), "page_1.md")}
+	if err := wp.Submit(task, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case signal := <-wp.ResultChan():
+		if !signal.Success {
+			t.Fatalf("expected success after breaker cooldown, got %v", signal.Error)
+		}
+		// 第一次尝试失败后熔断、分片放回，重新取出后仍计入失败尝试的用量
+		if signal.Usage.TotalTokens != 110 || signal.ModelUsage["primary"].TotalTokens != 100 {
+			t.Fatalf("usage lost across hold: total=%d by_model=%v", signal.Usage.TotalTokens, signal.ModelUsage)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no completion signal")
+	}
+}
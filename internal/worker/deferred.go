@@ -0,0 +1,170 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	async "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/async"
+)
+
+// deferredShard 已交给远端异步处理、等待结果的分片，不占用 worker
+type deferredShard struct {
+	task   *SubTask
+	route  route // 提交时的 processor，结果送达后用于翻译；等待期间持有其 processor 组的引用
+	signal *CompletionSignal
+	timer  *time.Timer // 超过 taskTimeout 仍无结果时判失败
+}
+
+// earlyResult 分片登记前送达的异步结果：回调可能在创建远端任务的请求返回前到达，保留一段时间等待登记
+type earlyResult struct {
+	result async.Result
+	timer  *time.Timer // 超过 earlyResultTTL 仍未登记时丢弃
+}
+
+const (
+	earlyResultTTL   = time.Minute
+	earlyResultLimit = 1024 // 最多保留的未登记结果数，超出后直接丢弃
+)
+
+// deferShard 登记异步分片，worker 随即返回处理下一个分片；登记前已送达的结果立即完成该分片
+func (wp *WorkerPool) deferShard(pending *async.Pending, task *SubTask, r route, signal *CompletionSignal) {
+	signal.Provider = pending.Provider
+	remoteID := pending.RemoteID
+	wp.retainProcessor(r.set)
+
+	wp.mu.Lock()
+	early, arrived := wp.early[remoteID]
+	if arrived {
+		delete(wp.early, remoteID)
+		early.timer.Stop()
+	}
+	wp.deferred[remoteID] = &deferredShard{
+		task:   task,
+		route:  r,
+		signal: signal,
+		timer: time.AfterFunc(wp.taskTimeout, func() {
+			// 超时后不再接收该任务的结果，让 provider 释放登记
+			if pending.Cancel != nil {
+				pending.Cancel()
+			}
+			wp.CompleteDeferred(async.Result{
+				RemoteID: remoteID,
+				Err:      fmt.Errorf("%s task %s: no result after %s", pending.Provider, remoteID, wp.taskTimeout),
+			})
+		}),
+	}
+	wp.mu.Unlock()
+	log.Printf(
+		"[worker] subtask deferred parent_id=%s subtask_id=%s provider=%s remote_id=%s",
+		task.ParentID,
+		task.ID,
+		pending.Provider,
+		remoteID,
+	)
+	if arrived {
+		wp.CompleteDeferred(early.result)
+	}
+}
+
+// CompleteDeferred 接收远端异步结果，写入分片输出并发出完成信号；
+// 未知的 RemoteID 可能是尚未登记的分片，保留 earlyResultTTL 后丢弃（已超时分片的迟到结果同样在此丢弃）
+func (wp *WorkerPool) CompleteDeferred(result async.Result) {
+	wp.mu.Lock()
+	shard, ok := wp.deferred[result.RemoteID]
+	if wp.stopping {
+		wp.mu.Unlock()
+		log.Printf("[worker] drop result after shutdown remote_id=%s", result.RemoteID)
+		return
+	}
+	if !ok {
+		wp.keepEarly(result)
+		wp.mu.Unlock()
+		return
+	}
+	delete(wp.deferred, result.RemoteID)
+	shard.timer.Stop()
+	// 后台翻译计入 wg，Shutdown 等它结束
+	wp.wg.Add(1)
+	wp.mu.Unlock()
+	translating := false
+	defer func() {
+		if !translating {
+			wp.releaseProcessor(shard.route.set)
+			wp.wg.Done()
+		}
+	}()
+
+	wp.clearProgress(shard.task.ID)
+	signal := shard.signal
+	if result.Model != "" {
+		signal.Model = result.Model
+	}
+	signal.AddUsage(result.Model, result.Usage)
+	if result.Err != nil {
+		signal.Success = false
+		signal.Error = result.Err
+	} else if err := writeOutput(shard.task.OutputPath, result.Content); err != nil {
+		signal.Success = false
+		signal.Error = err
+	} else {
+		signal.Success = true
+		signal.Error = nil
+		if shard.task.Options.TranslateTo != "" {
+			// 回调在 HTTP handler 或轮询中送达，翻译放到后台，完成后再发出信号
+			translating = true
+			go func() {
+				defer wp.wg.Done()
+				defer wp.releaseProcessor(shard.route.set)
+				wp.translateShard(shard.task, shard.route, result.Content, signal)
+				wp.emit(signal)
+			}()
+			return
+		}
+	}
+	wp.emit(signal)
+}
+
+// keepEarly 保留尚未登记的分片的结果，调用方持有 wp.mu
+func (wp *WorkerPool) keepEarly(result async.Result) {
+	remoteID := result.RemoteID
+	if _, exists := wp.early[remoteID]; exists {
+		return
+	}
+	if len(wp.early) >= earlyResultLimit {
+		log.Printf("[worker] drop result for unknown remote task remote_id=%s", remoteID)
+		return
+	}
+	early := &earlyResult{result: result}
+	early.timer = time.AfterFunc(earlyResultTTL, func() {
+		wp.mu.Lock()
+		expired := wp.early[remoteID] == early
+		if expired {
+			delete(wp.early, remoteID)
+		}
+		wp.mu.Unlock()
+		if expired {
+			log.Printf("[worker] drop result for unknown remote task remote_id=%s", remoteID)
+		}
+	})
+	wp.early[remoteID] = early
+}
+
+// emit 发出异步分片的完成信号，Shutdown 之后丢弃；发送可能阻塞，不持有 wp.mu
+func (wp *WorkerPool) emit(signal *CompletionSignal) {
+	wp.emitMu.RLock()
+	defer wp.emitMu.RUnlock()
+	if wp.closed {
+		return
+	}
+	select {
+	case wp.resultChan <- signal:
+	case <-wp.ctx.Done():
+	}
+}
+
+func (wp *WorkerPool) deferredCount() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return len(wp.deferred)
+}
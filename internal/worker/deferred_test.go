@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	async "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/async"
+)
+
+// pendingProcessor 总是把分片交给“远端”，结果永远不会送达
+type pendingProcessor struct {
+	cancels atomic.Int32
+}
+
+func (p *pendingProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts llm.ProcessOptions) (string, error) {
+	return "", &async.Pending{Provider: "remote", RemoteID: "r1", Cancel: func() { p.cancels.Add(1) }}
+}
+
+func TestDeferredTimeoutCancelsPending(t *testing.T) {
+	processor := &pendingProcessor{}
+	wp := NewWorkerPool(1, processor)
+	wp.taskTimeout = 20 * time.Millisecond
+	wp.Start()
+	defer wp.Shutdown()
+
+	task := &SubTask{ID: "s1", ParentID: "p1", MaxRetries: 1, OutputPath: filepath.Join(t.TempDir(), "page_1.md")}
+	if err := wp.Submit(task, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case signal := <-wp.ResultChan():
+		if signal.Success {
+			t.Fatalf("expected deferred shard to time out")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no completion signal")
+	}
+	if got := processor.cancels.Load(); got != 1 {
+		t.Fatalf("Cancel called %d times, want 1", got)
+	}
+	if n := wp.deferredCount(); n != 0 {
+		t.Fatalf("deferred = %d, want 0", n)
+	}
+}
+
+// slowTranslator 分片交给“远端”，翻译阻塞到 release 关闭
+type slowTranslator struct {
+	pendingProcessor
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *slowTranslator) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	close(p.started)
+	<-p.release
+	return strings.ToUpper(input), nil
+}
+
+func TestShutdownWaitsForDeferredTranslation(t *testing.T) {
+	processor := &slowTranslator{started: make(chan struct{}), release: make(chan struct{})}
+	wp := NewWorkerPool(1, processor)
+	wp.Start()
+
+	task := &SubTask{ID: "s1", ParentID: "p1", MaxRetries: 1, OutputPath: filepath.Join(t.TempDir(), "page_1.md")}
+	task.Options.TranslateTo = "en"
+	if err := wp.Submit(task, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for wp.deferredCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("shard was not deferred")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	wp.CompleteDeferred(async.Result{RemoteID: "r1", Content: "hello"})
+	<-processor.started
+
+	done := make(chan struct{})
+	go func() {
+		wp.Shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("Shutdown returned before the translation finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(processor.release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Shutdown did not return")
+	}
+	translated, err := os.ReadFile(TranslatedPath(task.OutputPath, "en"))
+	if err != nil || string(translated) != "HELLO" {
+		t.Fatalf("translation = %q, %v", translated, err)
+	}
+}
+
+func TestResultBeforeRegistrationCompletesShard(t *testing.T) {
+	wp := NewWorkerPool(1, &pendingProcessor{})
+	wp.taskTimeout = time.Minute
+	// 回调早于创建远端任务的请求返回
+	wp.CompleteDeferred(async.Result{RemoteID: "r1", Content: "early"})
+	wp.Start()
+	defer wp.Shutdown()
+
+	task := &SubTask{ID: "s1", ParentID: "p1", MaxRetries: 1, OutputPath: filepath.Join(t.TempDir(), "page_1.md")}
+	if err := wp.Submit(task, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case signal := <-wp.ResultChan():
+		if !signal.Success {
+			t.Fatalf("subtask failed: %v", signal.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("early result was not matched to the shard")
+	}
+	content, err := os.ReadFile(task.OutputPath)
+	if err != nil || string(content) != "early" {
+		t.Fatalf("output = %q, %v", content, err)
+	}
+	if n := wp.deferredCount(); n != 0 {
+		t.Fatalf("deferred = %d, want 0", n)
+	}
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// PartialSuffix 分片处理中增量输出文件的后缀：page_N.md.partial
+const PartialSuffix = ".partial"
+
+// partialFile 实现 report.Stream，把 provider 的增量输出写入 <OutputPath>.partial 供预览接口读取。
+// 预览是尽力而为的，写入失败只记日志，不影响分片处理。
+type partialFile struct {
+	path   string
+	mu     sync.Mutex
+	file   *os.File
+	failed bool
+}
+
+func newPartialFile(outputPath string) *partialFile {
+	return &partialFile{path: outputPath + PartialSuffix}
+}
+
+// Reset 清空已写入的内容，provider 开始新一轮生成时调用
+func (p *partialFile) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closeLocked()
+	file, err := os.OpenFile(p.path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		p.logOnce(err)
+		return
+	}
+	p.file = file
+}
+
+// Write 追加一段增量输出
+func (p *partialFile) Write(chunk string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.file == nil {
+		file, err := os.OpenFile(p.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			p.logOnce(err)
+			return
+		}
+		p.file = file
+	}
+	if _, err := p.file.WriteString(chunk); err != nil {
+		p.logOnce(err)
+	}
+}
+
+// Discard 关闭并删除 partial 文件，分片结束（成功或失败）后调用
+func (p *partialFile) Discard() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closeLocked()
+	if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("[worker] remove partial file failed path=%s err=%v", p.path, err)
+	}
+}
+
+func (p *partialFile) closeLocked() {
+	if p.file != nil {
+		_ = p.file.Close()
+		p.file = nil
+	}
+}
+
+func (p *partialFile) logOnce(err error) {
+	if p.failed {
+		return
+	}
+	p.failed = true
+	log.Printf("[worker] write partial file failed path=%s err=%v", p.path, err)
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math"
 	"os"
 	"path/filepath"
 	"runtime/debug"
@@ -13,25 +12,37 @@ import (
 	"time"
 
 	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	async "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/async"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	ratelimit "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/ratelimit"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
 )
 
 const defaultSubTaskTimeout = 8 * time.Minute
 
-// 初始化worker pool
+const defaultProvider = "default"
+
+// 初始化worker pool；processor 可为 nil，由 SwapProcessor 在 Start 之前设置
 func NewWorkerPool(workerCount int, processor llm.PDFProcessor) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
 	// client, _ := genai.NewClient(ctx, nil)
 
-	return &WorkerPool{
+	wp := &WorkerPool{
 		workerCount: workerCount,
 		taskQueue:   make(chan *SubTask, 100),
 		resultChan:  make(chan *CompletionSignal, 10),
-		processor:   processor,
 		taskTimeout: defaultSubTaskTimeout,
+		retry:       defaultRetryConfig(),
+		deferred:    make(map[string]*deferredShard),
+		early:       make(map[string]*earlyResult),
+		progress:    make(map[string]report.Progress),
+		breakers:    make(map[string]*breaker),
 		ctx:         ctx,
 		cancel:      cancel,
 		wg:          sync.WaitGroup{},
 	}
+	wp.processors.Store(&ProcessorSet{Provider: defaultProvider, Processor: processor})
+	return wp
 }
 
 // 启动worker pool
@@ -53,35 +64,198 @@ func (wp *WorkerPool) Submit(task *SubTask, timeout time.Duration) error {
 	}
 }
 
-// 从worker pool的task queue中取任务
+// ConfigureRetry 设置重试次数和退避参数，需在 Start 之前调用
+func (wp *WorkerPool) ConfigureRetry(cfg RetryConfig) {
+	wp.retry = cfg.withDefaults()
+}
+
+// 从worker pool的task queue中取任务，按分片选择的模型选项分发到对应 processor；
+// 该 processor 的熔断器打开期间分片放回等待，不判失败
 func (wp *WorkerPool) worker() {
 	defer wp.wg.Done()
 
-	for task := range wp.taskQueue {
-		wp.processTask(task)
+	for {
+		task, ok := wp.nextTask()
+		if !ok {
+			return
+		}
+		// 放回等待的分片重新取用时按当前 processor 解析，不保留旧的引用
+		set := wp.acquireProcessor()
+		r, err := set.resolve(task.Processor)
+		if err != nil {
+			wp.releaseProcessor(set)
+			wp.fail(task, err)
+			continue
+		}
+		b := wp.routeBreaker(r)
+		probe, allowed, _ := b.tryAcquire()
+		if !allowed {
+			wp.releaseProcessor(set)
+			wp.hold(task)
+			continue
+		}
+		wp.processTask(task, r, b, probe)
+		wp.releaseProcessor(set)
+		// 处理结束后熔断器可能已恢复，唤醒等待中的 worker 检查放回的分片
+		if wp.heldCount() > 0 {
+			wp.wakeHeld()
+		}
+	}
+}
+
+// nextTask 先取熔断器已放行的等待分片，再从队列取；队列关闭且没有等待的分片时返回 false
+func (wp *WorkerPool) nextTask() (*SubTask, bool) {
+	queue := wp.taskQueue
+	for {
+		task, wait, wake := wp.takeHeld()
+		if task != nil {
+			return task, true
+		}
+		if queue == nil && wake == nil {
+			return nil, false
+		}
+
+		var timeout <-chan time.Time
+		stop := func() {}
+		if wake != nil {
+			timer := time.NewTimer(wait)
+			timeout, stop = timer.C, func() { timer.Stop() }
+		}
+		select {
+		case task, ok := <-queue:
+			stop()
+			if !ok {
+				queue = nil
+				continue
+			}
+			return task, true
+		case <-wake:
+		case <-timeout:
+		case <-wp.ctx.Done():
+			stop()
+			return nil, false
+		}
+		stop()
+	}
+}
+
+// takeHeld 取出第一个熔断器已放行的等待分片；都未放行时返回最短等待时间和唤醒 channel，没有等待的分片时 wake 为 nil
+func (wp *WorkerPool) takeHeld() (task *SubTask, wait time.Duration, wake <-chan struct{}) {
+	set := wp.currentProcessor()
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if len(wp.held) == 0 {
+		return nil, 0, nil
+	}
+
+	// 回退链按分片参数检查能处理它的成员，同一 processor 的分片可能一个放行一个不放行，逐个检查
+	for i, held := range wp.held {
+		r, err := set.resolve(held.Processor)
+		if err == nil {
+			ready, readyIn := wp.routeReady(r, held.Options)
+			if !ready {
+				if wait == 0 || readyIn < wait {
+					wait = readyIn
+				}
+				continue
+			}
+		}
+		wp.held = append(wp.held[:i], wp.held[i+1:]...)
+		return held, 0, nil
+	}
+	if wp.heldWake == nil {
+		wp.heldWake = make(chan struct{})
+	}
+	return nil, max(wait, time.Millisecond), wp.heldWake
+}
+
+// hold 把分片放回等待，熔断器恢复后重新处理，不视为失败
+func (wp *WorkerPool) hold(task *SubTask) {
+	if task == nil {
+		return
+	}
+	wp.clearProgress(task.ID)
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.held = append(wp.held, task)
+}
+
+// wakeHeld 唤醒等待放回分片的 worker 重新检查熔断器
+func (wp *WorkerPool) wakeHeld() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.heldWake != nil {
+		close(wp.heldWake)
+		wp.heldWake = nil
+	}
+}
+
+func (wp *WorkerPool) heldCount() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return len(wp.held)
+}
+
+// fail 分片无法分发时直接发出失败信号
+func (wp *WorkerPool) fail(task *SubTask, err error) {
+	log.Printf("[worker] subtask dispatch failed parent_id=%s subtask_id=%s err=%v", task.ParentID, task.ID, err)
+	wp.resultChan <- &CompletionSignal{
+		SubTaskID: task.ID,
+		ParentID:  task.ParentID,
+		Success:   false,
+		Error:     err,
 	}
 }
 
 // 关闭worker pool中的channels
 func (wp *WorkerPool) Shutdown() {
 	close(wp.taskQueue)
+	// 之后送达的异步结果不再启动后台翻译，wg 不会在 Wait 期间从 0 增加
+	wp.mu.Lock()
+	wp.stopping = true
+	wp.mu.Unlock()
 	wp.wg.Wait()
+
+	// 等待中的异步分片不再送达结果
+	wp.cancel()
+	wp.mu.Lock()
+	for _, shard := range wp.deferred {
+		shard.timer.Stop()
+	}
+	for _, early := range wp.early {
+		early.timer.Stop()
+	}
+	wp.mu.Unlock()
+	// ctx 已取消，正在发送的 emit 会退出并释放读锁
+	wp.emitMu.Lock()
+	wp.closed = true
 	close(wp.resultChan)
+	wp.emitMu.Unlock()
 }
 
 func (wp *WorkerPool) ResultChan() <-chan *CompletionSignal {
 	return wp.resultChan
 }
 
-func (wp *WorkerPool) processTask(task *SubTask) {
+// processTask 处理一个分片；processor 在取出分片时确定，热加载不影响进行中的分片及其重试
+func (wp *WorkerPool) processTask(task *SubTask, r route, b *breaker, probe bool) {
+	// 半开状态的探测调用在结束前未记录结果时（如 panic）归还
+	defer func() {
+		b.release(probe)
+	}()
 	if task == nil {
 		log.Printf("[worker] received nil subtask, skip")
 		return
 	}
 
-	signal := &CompletionSignal{
-		SubTaskID: task.ID,
-		ParentID:  task.ParentID,
+	// 熔断期间放回过的分片沿用之前的信号，已失败尝试的用量不丢失
+	signal := task.carried
+	task.carried = nil
+	if signal == nil {
+		signal = &CompletionSignal{
+			SubTaskID: task.ID,
+			ParentID:  task.ParentID,
+		}
 	}
 	shouldEmit := false
 	defer func() {
@@ -98,44 +272,139 @@ func (wp *WorkerPool) processTask(task *SubTask) {
 			)
 		}
 		if shouldEmit {
+			wp.clearProgress(task.ID)
 			wp.resultChan <- signal
 		}
 	}()
 
 	// 设置默认重试次数
 	if task.MaxRetries == 0 {
-		task.MaxRetries = 3
+		task.MaxRetries = wp.retry.MaxRetries
 	}
 
 	taskCtx, cancel := context.WithTimeout(wp.ctx, wp.taskTimeout)
 	defer cancel()
 
+	// 处理过程中的增量输出写入 partial 文件，最终结果仍在处理完成后一次性写入 OutputPath
+	partial := newPartialFile(task.OutputPath)
+	defer partial.Discard()
+	streamCtx := report.WithStream(taskCtx, partial)
+	// provider 上报的进度（MinerU 已解析页数、Gemini 已生成字符数）供 getTask 展示
+	streamCtx = report.WithProgress(streamCtx, func(progress report.Progress) {
+		wp.setProgress(task.ID, progress)
+	})
+
 	var content string
 	var err error
+	var callReport *report.Report
+	limiter, tokensPerPage := wp.limiterFor(r)
+	callBase := wp.withGate(streamCtx, r, ratelimit.EstimateTokens(task.PageEnd-task.PageStart+1, tokensPerPage))
+	callBase = wp.withGuard(callBase, r)
 	for ; task.RetryCount < task.MaxRetries; task.RetryCount++ {
 		if taskCtx.Err() != nil {
 			err = taskCtx.Err()
 			break
 		}
 		attempt := task.RetryCount + 1
-		content, err = wp.processor.ProcessPDF(taskCtx, task.PDFPath)
+		// 重试前熔断器已打开：不再消耗重试次数，分片放回等待
+		if attempt > 1 && !probe && !b.allowRetry() {
+			task.carried = signal
+			wp.hold(task)
+			return
+		}
+		// 所有 worker 共享的 RPM / TPM 限流，等待时间计入子任务超时
+		var reserved int64
+		if reserved, err = wp.waitRateLimit(streamCtx, limiter, tokensPerPage, task); err != nil {
+			break
+		}
+		var callCtx context.Context
+		callCtx, callReport = report.New(callBase)
+		content, err = r.processor.ProcessPDF(callCtx, task.PDFPath, task.Options)
+		if reserved > 0 && callReport.Usage.TotalTokens > 0 {
+			limiter.Adjust(callReport.Usage.TotalTokens - reserved)
+		}
+		if callReport.Model != "" {
+			signal.Model = callReport.Model
+		}
+		signal.AddUsage(callReport.Model, callReport.Usage)
+		// 已交给远端异步处理（如 MinerU 回调模式），释放 worker，结果由 CompleteDeferred 送达
+		var pending *async.Pending
+		if err == nil || errors.As(err, &pending) {
+			b.record(probe, true)
+			probe = false
+		}
 		if err == nil {
 			break
 		}
+		if pending != nil {
+			wp.deferShard(pending, task, r, signal)
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			break
+		}
+		// 回退链的成员都因熔断被跳过：分片放回等待，不消耗重试次数
+		if errors.Is(err, llm.ErrProviderUnavailable) {
+			task.carried = signal
+			wp.hold(task)
+			return
+		}
+		class := llmerr.ClassOf(err)
 		log.Printf(
-			"[worker] subtask attempt failed parent_id=%s subtask_id=%s attempt=%d/%d err=%v",
+			"[worker] subtask attempt failed parent_id=%s subtask_id=%s attempt=%d/%d class=%s err=%v",
 			task.ParentID,
 			task.ID,
 			attempt,
 			task.MaxRetries,
+			class,
 			err,
 		)
-		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+
+		// 不可重试或内容被拦截：直接失败；这类错误与 provider 是否可用无关，不计入熔断
+		if llmerr.Fatal(err) {
+			b.release(probe)
+			probe = false
 			break
 		}
+		// 限流且给出了等待时间：等待后重试，不消耗重试次数，也不计入熔断
+		if retryAfter := llmerr.RetryAfter(err); retryAfter > 0 {
+			b.release(probe)
+			probe = false
+			if deadline, ok := taskCtx.Deadline(); ok && time.Until(deadline) < retryAfter {
+				err = fmt.Errorf("rate limited, retry after %s exceeds subtask timeout: %w", retryAfter, err)
+				break
+			}
+			if !sleepWithContext(taskCtx, retryAfter) {
+				err = taskCtx.Err()
+				break
+			}
+			task.RetryCount--
+			continue
+		}
+
+		wasProbe := probe
+		closed := b.record(probe, false)
+		probe = false
+		if errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+		if !closed {
+			// 熔断期间的失败不计入重试次数；探测失败计入，避免单个异常分片无限探测
+			if wasProbe {
+				task.RetryCount++
+				if task.RetryCount >= task.MaxRetries {
+					break
+				}
+			}
+			task.carried = signal
+			wp.hold(task)
+			return
+		}
+		if task.RetryCount+1 >= task.MaxRetries {
+			continue
+		}
 		// 指数退避
-		backoff := time.Duration(math.Pow(2, float64(task.RetryCount))) * time.Second
-		if !sleepWithContext(taskCtx, backoff) {
+		if !sleepWithContext(taskCtx, wp.retry.backoff(task.RetryCount)) {
 			err = taskCtx.Err()
 			break
 		}
@@ -152,35 +421,43 @@ func (wp *WorkerPool) processTask(task *SubTask) {
 		return
 	}
 
-	// 确保目录存在
-	if err := os.MkdirAll(filepath.Dir(task.OutputPath), 0755); err != nil {
+	if err := writeOutput(task.OutputPath, content); err != nil {
 		signal.Success = false
-		signal.Error = fmt.Errorf("failed to create directory: %w", err)
+		signal.Error = err
 		shouldEmit = true
 		return
 	}
 
-	// 写入文件
-	file, err := os.OpenFile(task.OutputPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		signal.Success = false
-		signal.Error = fmt.Errorf("can't open file %s: %w", task.OutputPath, err)
-		shouldEmit = true
-		return
+	wp.translateShard(task, r, content, signal)
+
+	signal.Success = true
+	signal.Error = nil
+	if callReport != nil {
+		signal.Provider = callReport.Provider
 	}
-	defer file.Close()
+	if signal.Provider == "" {
+		signal.Provider = r.provider
+	}
+	shouldEmit = true
+}
 
-	_, err = file.WriteString(content)
+// writeOutput 写入分片结果文件
+func writeOutput(path, content string) error {
+	// 确保目录存在
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		signal.Success = false
-		signal.Error = fmt.Errorf("failed to write content: %w", err)
-		shouldEmit = true
-		return
+		return fmt.Errorf("can't open file %s: %w", path, err)
 	}
+	defer file.Close()
 
-	signal.Success = true
-	signal.Error = nil
-	shouldEmit = true
+	if _, err := file.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write content: %w", err)
+	}
+	return nil
 }
 
 func sleepWithContext(ctx context.Context, d time.Duration) bool {
@@ -205,5 +482,11 @@ func (wp *WorkerPool) GetStatus() map[string]interface{} {
 		"queue_length":       len(wp.taskQueue),
 		"queue_capacity":     cap(wp.taskQueue),
 		"result_chan_length": len(wp.resultChan),
+		"processor_version":  wp.currentProcessor().Version,
+		"deferred_count":     wp.deferredCount(),
+		"held_count":         wp.heldCount(),
+		"breakers":           wp.breakerStatus(),
+		"rate_limits":        wp.rateLimitStatus(),
+		"key_rate_limits":    wp.keyRateLimitStatus(),
 	}
 }
@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+)
+
+// ErrUnknownChoice 分片选择的模型选项不在当前配置中（如热加载时被移除）
+var ErrUnknownChoice = errors.New("model choice is not configured")
+
+// ProcessorSet 一次配置加载生成的 processor，配置热加载时整体替换
+type ProcessorSet struct {
+	Version   int64             // 配置版本，每次加载递增
+	Provider  string            // 默认 processor 对应的 provider 名字，作为熔断器的 key
+	Processor llm.PDFProcessor  // 未选择模型选项的分片使用
+	Choices   map[string]Choice // 模型选项名字 -> processor
+	Release   func()            // 被替换且不再有分片使用后调用一次，释放 processor 持有的资源（如 key 池），可为 nil
+
+	refs    int  // 使用中的分片（含等待异步结果的）和纯文本调用数，由 WorkerPool.setMu 保护
+	retired bool // 已被替换
+}
+
+// Choice 任务可选的 processor
+type Choice struct {
+	Provider  string
+	Processor llm.PDFProcessor
+}
+
+// route 分片实际使用的 processor
+type route struct {
+	name      string // 熔断器的 key：默认为 provider，模型选项为 provider/选项名
+	provider  string // 模型选项的 provider，用于选择限流器；默认 processor 为空，按分片首选 provider 限流
+	processor llm.PDFProcessor
+	set       *ProcessorSet // 所属的 processor 组，分片转入异步等待时据此保留引用
+}
+
+// resolve 按分片选择的模型选项返回 processor
+func (s *ProcessorSet) resolve(choice string) (route, error) {
+	if choice == "" {
+		return route{name: s.Provider, processor: s.Processor, set: s}, nil
+	}
+	c, ok := s.Choices[choice]
+	if !ok {
+		return route{}, llmerr.Permanent(fmt.Errorf("%w: %s", ErrUnknownChoice, choice))
+	}
+	return route{name: c.Provider + "/" + choice, provider: c.Provider, processor: c.Processor, set: s}, nil
+}
+
+// SwapProcessor 原子替换 processor：已开始处理的分片（含其重试、翻译和异步等待）继续使用旧 processor，之后取出的分片使用新的；
+// 旧 processor 组的 Release 在最后一个使用它的分片结束后调用
+func (wp *WorkerPool) SwapProcessor(set *ProcessorSet) {
+	if set == nil || set.Processor == nil {
+		return
+	}
+	if set.Provider == "" {
+		set.Provider = defaultProvider
+	}
+	wp.setMu.Lock()
+	old := wp.processors.Swap(set)
+	drained := false
+	if old != nil {
+		old.retired = true
+		drained = old.refs == 0
+	}
+	wp.setMu.Unlock()
+	if drained {
+		wp.retireProcessor(old)
+	}
+	wp.wakeHeld()
+	log.Printf("[worker] processor swapped version=%d provider=%s choices=%d", set.Version, set.Provider, len(set.Choices))
+}
+
+// currentProcessor 返回当前生效的 processor
+func (wp *WorkerPool) currentProcessor() *ProcessorSet {
+	return wp.processors.Load()
+}
+
+// acquireProcessor 返回当前生效的 processor 并增加引用，用完后调用 releaseProcessor
+func (wp *WorkerPool) acquireProcessor() *ProcessorSet {
+	wp.setMu.Lock()
+	defer wp.setMu.Unlock()
+	set := wp.processors.Load()
+	set.refs++
+	return set
+}
+
+// retainProcessor 为已持有引用的调用方再增加一次引用，如分片转入异步等待
+func (wp *WorkerPool) retainProcessor(set *ProcessorSet) {
+	wp.setMu.Lock()
+	defer wp.setMu.Unlock()
+	set.refs++
+}
+
+// releaseProcessor 释放引用；已被替换的 processor 组引用归零时释放其资源
+func (wp *WorkerPool) releaseProcessor(set *ProcessorSet) {
+	wp.setMu.Lock()
+	set.refs--
+	drained := set.retired && set.refs == 0
+	wp.setMu.Unlock()
+	if drained {
+		wp.retireProcessor(set)
+	}
+}
+
+func (wp *WorkerPool) retireProcessor(set *ProcessorSet) {
+	if set.Release == nil {
+		return
+	}
+	set.Release()
+	log.Printf("[worker] retired processor released version=%d provider=%s", set.Version, set.Provider)
+}
+
+// ConfigureBreaker 设置熔断参数并清空已有熔断器，需在 Start 之前调用；
+// 每个 processor 的熔断器在首次使用时创建
+func (wp *WorkerPool) ConfigureBreaker(cfg BreakerConfig) {
+	wp.ctlMu.Lock()
+	defer wp.ctlMu.Unlock()
+	wp.breakerConfig = cfg
+	wp.breakers = make(map[string]*breaker)
+}
+
+// routeBreaker 返回分片整体使用的熔断器；回退链返回 nil，由链通过 breakerGuard 按成员熔断
+func (wp *WorkerPool) routeBreaker(r route) *breaker {
+	if isChain(r.processor) {
+		return nil
+	}
+	return wp.breakerFor(r.name)
+}
+
+// routeReady 检查调用现在是否可能放行：回退链中能处理 opts 的任一成员的熔断器放行即可，否则返回最短等待时间
+func (wp *WorkerPool) routeReady(r route, opts llm.ProcessOptions) (bool, time.Duration) {
+	chain, ok := r.processor.(*llm.ChainProcessor)
+	if !ok {
+		return wp.breakerFor(r.name).ready()
+	}
+	providers := chain.Providers(opts)
+	if len(providers) == 0 {
+		// 没有能处理的成员，调用会直接失败，不必等待
+		return true, 0
+	}
+	var wait time.Duration
+	for _, provider := range providers {
+		ready, readyIn := wp.breakerFor(provider).ready()
+		if ready {
+			return true, 0
+		}
+		if wait == 0 || readyIn < wait {
+			wait = readyIn
+		}
+	}
+	return false, wait
+}
+
+// withGuard 回退链的调用：把按成员熔断的 Guard 放入 ctx；其他 processor 原样返回
+func (wp *WorkerPool) withGuard(ctx context.Context, r route) context.Context {
+	if !isChain(r.processor) {
+		return ctx
+	}
+	return llm.WithGuard(ctx, breakerGuard{wp: wp})
+}
+
+// breakerFor 返回 processor 的熔断器；热加载切换 provider 后旧熔断器保留，切回时沿用原状态
+func (wp *WorkerPool) breakerFor(name string) *breaker {
+	wp.ctlMu.Lock()
+	defer wp.ctlMu.Unlock()
+	b, ok := wp.breakers[name]
+	if !ok {
+		b = newBreaker(name, wp.breakerConfig)
+		wp.breakers[name] = b
+	}
+	return b
+}
+
+func (wp *WorkerPool) breakerStatus() map[string]interface{} {
+	wp.ctlMu.RLock()
+	defer wp.ctlMu.RUnlock()
+	status := make(map[string]interface{}, len(wp.breakers))
+	for name, b := range wp.breakers {
+		status[name] = b.status()
+	}
+	return status
+}
@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	async "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/async"
+)
+
+// gatedProcessor 通知调用开始后阻塞到 release 关闭，返回固定内容
+type gatedProcessor struct {
+	content string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *gatedProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts llm.ProcessOptions) (string, error) {
+	if p.started != nil {
+		close(p.started)
+	}
+	if p.release != nil {
+		<-p.release
+	}
+	return p.content, nil
+}
+
+func waitOutput(t *testing.T, wp *WorkerPool, path string) string {
+	t.Helper()
+	select {
+	case signal := <-wp.ResultChan():
+		if !signal.Success {
+			t.Fatalf("subtask failed: %v", signal.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no completion signal")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	return string(content)
+}
+
+func TestSwapProcessorKeepsRunningShardOnOldProcessor(t *testing.T) {
+	old := &gatedProcessor{content: "old", started: make(chan struct{}), release: make(chan struct{})}
+	wp := NewWorkerPool(1, old)
+	wp.Start()
+	defer wp.Shutdown()
+
+	dir := t.TempDir()
+	first := &SubTask{ID: "s1", ParentID: "p1", OutputPath: filepath.Join(dir, "page_1.md")}
+	if err := wp.Submit(first, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-old.started
+
+	wp.SwapProcessor(&ProcessorSet{Version: 2, Provider: "mock", Processor: &gatedProcessor{content: "new"}})
+	close(old.release)
+	if got := waitOutput(t, wp, first.OutputPath); got != "old" {
+		t.Fatalf("running shard output = %q, want old", got)
+	}
+
+	second := &SubTask{ID: "s2", ParentID: "p1", OutputPath: filepath.Join(dir, "page_2.md")}
+	if err := wp.Submit(second, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if got := waitOutput(t, wp, second.OutputPath); got != "new" {
+		t.Fatalf("next shard output = %q, want new", got)
+	}
+	if version := wp.GetStatus()["processor_version"]; version != int64(2) {
+		t.Fatalf("processor_version = %v, want 2", version)
+	}
+}
+
+func TestShardDispatchedToSelectedChoice(t *testing.T) {
+	wp := NewWorkerPool(1, &gatedProcessor{content: "default"})
+	wp.SwapProcessor(&ProcessorSet{
+		Version:   1,
+		Provider:  "gemini",
+		Processor: &gatedProcessor{content: "default"},
+		Choices:   map[string]Choice{"contract": {Provider: "gemini", Processor: &gatedProcessor{content: "contract"}}},
+	})
+	wp.Start()
+	defer wp.Shutdown()
+
+	task := &SubTask{ID: "s1", ParentID: "p1", OutputPath: filepath.Join(t.TempDir(), "page_1.md"), Processor: "contract"}
+	if err := wp.Submit(task, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if got := waitOutput(t, wp, task.OutputPath); got != "contract" {
+		t.Fatalf("output = %q, want contract", got)
+	}
+	if _, ok := wp.breakerStatus()["gemini/contract"]; !ok {
+		t.Fatalf("choice should have its own breaker, got %v", wp.breakerStatus())
+	}
+}
+
+func TestRetiredProcessorReleasedAfterDeferredShard(t *testing.T) {
+	wp := NewWorkerPool(1, &gatedProcessor{content: "default"})
+	wp.taskTimeout = time.Minute
+	var released atomic.Int32
+	wp.SwapProcessor(&ProcessorSet{
+		Version:   1,
+		Provider:  "remote",
+		Processor: &pendingProcessor{},
+		Release:   func() { released.Add(1) },
+	})
+	wp.Start()
+	defer wp.Shutdown()
+
+	task := &SubTask{ID: "s1", ParentID: "p1", OutputPath: filepath.Join(t.TempDir(), "page_1.md")}
+	if err := wp.Submit(task, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for wp.deferredCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("shard was not deferred")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 异步等待中的分片仍引用旧 processor 组，替换后不释放
+	wp.SwapProcessor(&ProcessorSet{Version: 2, Provider: "mock", Processor: &gatedProcessor{content: "new"}})
+	if got := released.Load(); got != 0 {
+		t.Fatalf("Release called %d times before deferred shard finished", got)
+	}
+
+	wp.CompleteDeferred(async.Result{RemoteID: "r1", Content: "remote"})
+	if got := waitOutput(t, wp, task.OutputPath); got != "remote" {
+		t.Fatalf("output = %q, want remote", got)
+	}
+	if got := released.Load(); got != 1 {
+		t.Fatalf("Release called %d times, want 1", got)
+	}
+}
@@ -0,0 +1,25 @@
+package worker
+
+import (
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+func (wp *WorkerPool) setProgress(subTaskID string, progress report.Progress) {
+	wp.progressMu.Lock()
+	defer wp.progressMu.Unlock()
+	wp.progress[subTaskID] = progress
+}
+
+func (wp *WorkerPool) clearProgress(subTaskID string) {
+	wp.progressMu.Lock()
+	defer wp.progressMu.Unlock()
+	delete(wp.progress, subTaskID)
+}
+
+// ShardProgress 返回分片最近一次上报的进度；分片不在处理中或 provider 未上报时 ok 为 false
+func (wp *WorkerPool) ShardProgress(subTaskID string) (report.Progress, bool) {
+	wp.progressMu.Lock()
+	defer wp.progressMu.Unlock()
+	progress, ok := wp.progress[subTaskID]
+	return progress, ok
+}
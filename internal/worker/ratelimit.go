@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	ratelimit "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/ratelimit"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// RateLimit 单个 provider 的限流配置
+type RateLimit struct {
+	Provider string
+	Limits   ratelimit.Limits
+}
+
+// ConfigureRateLimits 设置各 provider 所有 worker 共享的限流器，替换之前的配置，可在运行中调用（配置热加载）；
+// 限额未变的 provider 沿用原限流器，已用配额和统计不清零。回退链的每个成员按各自的限流器限流
+func (wp *WorkerPool) ConfigureRateLimits(limits []RateLimit, tokensPerPage int) {
+	wp.ctlMu.Lock()
+	defer wp.ctlMu.Unlock()
+
+	limiters := make(map[string]*ratelimit.Limiter, len(limits))
+	var order []string
+	for _, item := range limits {
+		if !item.Limits.Enabled() {
+			continue
+		}
+		if _, exists := limiters[item.Provider]; !exists {
+			order = append(order, item.Provider)
+		}
+		if existing, ok := wp.limiters[item.Provider]; ok && existing.Limits() == item.Limits {
+			limiters[item.Provider] = existing
+			continue
+		}
+		limiters[item.Provider] = ratelimit.New(item.Limits)
+	}
+	wp.limiters = limiters
+	wp.limiterOrder = order
+	wp.tokensPerPage = tokensPerPage
+}
+
+// limiterFor 返回分片调用前需要等待的限流器（未配置时为 nil）和预估用的每页 token 数：
+// 模型选项按其 provider 限流，单个默认 provider 按它自己限流；回退链返回 nil，由 gateFor 交给链按成员限流
+func (wp *WorkerPool) limiterFor(r route) (*ratelimit.Limiter, int) {
+	wp.ctlMu.RLock()
+	defer wp.ctlMu.RUnlock()
+	if r.provider != "" {
+		return wp.limiters[r.provider], wp.tokensPerPage
+	}
+	if isChain(r.processor) || len(wp.limiterOrder) == 0 {
+		return nil, wp.tokensPerPage
+	}
+	return wp.limiters[wp.limiterOrder[0]], wp.tokensPerPage
+}
+
+// withGate 回退链的分片：把按成员选择限流器的 Gate 放入 ctx，estimate 为本次调用的预估 token；其他 processor 原样返回
+func (wp *WorkerPool) withGate(ctx context.Context, r route, estimate int64) context.Context {
+	if r.provider != "" || !isChain(r.processor) {
+		return ctx
+	}
+	wp.ctlMu.RLock()
+	defer wp.ctlMu.RUnlock()
+	// ConfigureRateLimits 整体替换 map，不会修改已交给 Gate 的 map
+	return ratelimit.WithGate(ctx, ratelimit.NewGate(wp.limiters, estimate))
+}
+
+func isChain(processor llm.PDFProcessor) bool {
+	_, ok := processor.(*llm.ChainProcessor)
+	return ok
+}
+
+// waitRateLimit 调用 ProcessPDF 前按请求数和预估 token 等待限流器，返回预占的 token 数供调用后修正
+func (wp *WorkerPool) waitRateLimit(ctx context.Context, limiter *ratelimit.Limiter, tokensPerPage int, task *SubTask) (int64, error) {
+	if limiter == nil {
+		return 0, nil
+	}
+	estimate := ratelimit.EstimateTokens(task.PageEnd-task.PageStart+1, tokensPerPage)
+	if wait := limiter.Reserve(estimate); wait == 0 {
+		return estimate, nil
+	}
+
+	report.SetProgress(ctx, report.Progress{State: "rate limited"})
+	waited, err := limiter.Wait(ctx, estimate)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf(
+		"[worker] rate limit wait parent_id=%s subtask_id=%s waited=%s",
+		task.ParentID,
+		task.ID,
+		waited.Round(time.Millisecond),
+	)
+	return estimate, nil
+}
+
+func (wp *WorkerPool) rateLimitStatus() map[string]interface{} {
+	wp.ctlMu.RLock()
+	defer wp.ctlMu.RUnlock()
+	status := make(map[string]interface{}, len(wp.limiters))
+	for provider, limiter := range wp.limiters {
+		status[provider] = limiterStatus(limiter)
+	}
+	return status
+}
+
+// keyLimiterSource 由 key 池（以及包含 key 池的回退链）实现
+type keyLimiterSource interface {
+	KeyLimiters() map[string]*ratelimit.Limiter
+}
+
+// keyRateLimitStatus 当前 processor 中各 key 的单 key 限流状态，key 已脱敏
+func (wp *WorkerPool) keyRateLimitStatus() map[string]interface{} {
+	status := make(map[string]interface{})
+	if source, ok := wp.currentProcessor().Processor.(keyLimiterSource); ok {
+		for key, limiter := range source.KeyLimiters() {
+			status[key] = limiterStatus(limiter)
+		}
+	}
+	return status
+}
+
+func limiterStatus(limiter *ratelimit.Limiter) map[string]interface{} {
+	stats := limiter.Stats()
+	limits := limiter.Limits()
+	return map[string]interface{}{
+		"rpm":                limits.RPM,
+		"tpm":                limits.TPM,
+		"waits":              stats.Waits,
+		"waiting":            stats.Waiting,
+		"wait_total_seconds": stats.WaitTotal.Seconds(),
+	}
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxRetries  = 3
+	defaultBackoffBase = time.Second
+	defaultBackoffMax  = 30 * time.Second
+)
+
+// RetryConfig 分片重试参数：临时错误最多尝试 MaxRetries 次，第 n 次失败后等待 BackoffBase*2^(n-1)，不超过 BackoffMax。
+// 不可重试错误直接失败，带等待时间的限流错误按服务端建议等待且不消耗次数。
+type RetryConfig struct {
+	MaxRetries  int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+func defaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:  defaultMaxRetries,
+		BackoffBase: defaultBackoffBase,
+		BackoffMax:  defaultBackoffMax,
+	}
+}
+
+// LoadRetryConfigFromEnv 读取 WORKER_MAX_RETRIES / WORKER_BACKOFF_BASE / WORKER_BACKOFF_MAX，未设置时使用默认值
+func LoadRetryConfigFromEnv() (RetryConfig, error) {
+	cfg := defaultRetryConfig()
+	if raw := strings.TrimSpace(os.Getenv("WORKER_MAX_RETRIES")); raw != "" {
+		retries, err := strconv.Atoi(raw)
+		if err != nil || retries <= 0 {
+			return RetryConfig{}, fmt.Errorf("invalid WORKER_MAX_RETRIES: %s", raw)
+		}
+		cfg.MaxRetries = retries
+	}
+	for _, item := range []struct {
+		env    string
+		target *time.Duration
+	}{
+		{"WORKER_BACKOFF_BASE", &cfg.BackoffBase},
+		{"WORKER_BACKOFF_MAX", &cfg.BackoffMax},
+	} {
+		raw := strings.TrimSpace(os.Getenv(item.env))
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			return RetryConfig{}, fmt.Errorf("invalid %s: %s", item.env, raw)
+		}
+		*item.target = d
+	}
+	return cfg, nil
+}
+
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+	if c.BackoffBase < 0 {
+		c.BackoffBase = defaultBackoffBase
+	}
+	if c.BackoffMax <= 0 {
+		c.BackoffMax = defaultBackoffMax
+	}
+	return c
+}
+
+// backoff 返回第 retryCount+1 次失败后的等待时间
+func (c RetryConfig) backoff(retryCount int) time.Duration {
+	wait := c.BackoffBase
+	for i := 0; i < retryCount && wait < c.BackoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, c.BackoffMax)
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// scriptedProcessor 按顺序返回预设的错误，用完后返回成功
+type scriptedProcessor struct {
+	errs  []error
+	calls atomic.Int32
+}
+
+func (p *scriptedProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts llm.ProcessOptions) (string, error) {
+	call := int(p.calls.Add(1)) - 1
+	if call < len(p.errs) {
+		return "", p.errs[call]
+	}
+	return "ok", nil
+}
+
+func runSubTask(t *testing.T, processor llm.PDFProcessor) *CompletionSignal {
+	t.Helper()
+	wp := NewWorkerPool(1, processor)
+	wp.ConfigureRetry(RetryConfig{MaxRetries: 3, BackoffBase: time.Millisecond, BackoffMax: time.Millisecond})
+	wp.Start()
+	defer wp.Shutdown()
+
+	task := &SubTask{ID: "s1", ParentID: "p1", OutputPath: filepath.Join(t.TempDir(), "page_1.md")}
+	if err := wp.Submit(task, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case signal := <-wp.ResultChan():
+		return signal
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no completion signal")
+		return nil
+	}
+}
+
+func TestPermanentErrorFailsFast(t *testing.T) {
+	processor := &scriptedProcessor{errs: []error{llmerr.Permanent(errors.New("invalid pdf"))}}
+	signal := runSubTask(t, processor)
+	if signal.Success || llmerr.ClassOf(signal.Error) != llmerr.ClassPermanent {
+		t.Fatalf("expected permanent failure, got success=%v err=%v", signal.Success, signal.Error)
+	}
+	if calls := processor.calls.Load(); calls != 1 {
+		t.Fatalf("permanent error must not be retried, calls = %d", calls)
+	}
+}
+
+func TestRateLimitWaitDoesNotConsumeRetries(t *testing.T) {
+	limited := llmerr.RateLimited(errors.New("429"), 5*time.Millisecond)
+	processor := &scriptedProcessor{errs: []error{limited, limited, limited, limited}}
+	signal := runSubTask(t, processor)
+	if !signal.Success {
+		t.Fatalf("expected success after rate limit waits, got %v", signal.Error)
+	}
+	if calls := processor.calls.Load(); calls != 5 {
+		t.Fatalf("calls = %d, want 5", calls)
+	}
+}
+
+// fallbackProcessor 第一次以 primary 模型失败，之后以 fallback 模型成功，模拟回退链
+type fallbackProcessor struct {
+	calls atomic.Int32
+}
+
+func (p *fallbackProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts llm.ProcessOptions) (string, error) {
+	r := report.From(ctx)
+	if p.calls.Add(1) == 1 {
+		r.Model = "primary"
+		r.Usage.Add(report.Usage{PromptTokens: 100, TotalTokens: 100})
+		return "", llmerr.Retryable(errors.New("timeout"))
+	}
+	r.Model = "fallback"
+	r.Usage.Add(report.Usage{PromptTokens: 10, TotalTokens: 10})
+	return "ok", nil
+}
+
+func TestUsageIsSplitByAttemptModel(t *testing.T) {
+	signal := runSubTask(t, &fallbackProcessor{})
+	if !signal.Success {
+		t.Fatalf("expected success, got %v", signal.Error)
+	}
+	if signal.Model != "fallback" || signal.Usage.TotalTokens != 110 {
+		t.Fatalf("model = %s total = %d", signal.Model, signal.Usage.TotalTokens)
+	}
+	if got := signal.ModelUsage["primary"].TotalTokens; got != 100 {
+		t.Fatalf("primary usage = %d, want 100", got)
+	}
+	if got := signal.ModelUsage["fallback"].TotalTokens; got != 10 {
+		t.Fatalf("fallback usage = %d, want 10", got)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	cfg := RetryConfig{MaxRetries: 5, BackoffBase: time.Second, BackoffMax: 3 * time.Second}
+	for retry, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		if got := cfg.backoff(retry); got != want {
+			t.Fatalf("backoff(%d) = %s, want %s", retry, got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"unicode/utf8"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// GenerateText 不经过任务队列，用模型选项 choice（为空时为默认）对应的 processor 执行一次纯文本调用（如文档问答）；
+// 与分片共享限流器，熔断器打开时直接返回限流错误。用量写入 ctx 中的 report
+func (wp *WorkerPool) GenerateText(ctx context.Context, choice, instruction, input string) (string, error) {
+	set := wp.acquireProcessor()
+	defer wp.releaseProcessor(set)
+	r, err := set.resolve(choice)
+	if err != nil {
+		return "", err
+	}
+	generator, ok := r.processor.(llm.TextGenerator)
+	if !ok {
+		return "", llmerr.Permanent(llm.ErrTextUnsupported)
+	}
+	if ready, wait := wp.routeReady(r, llm.ProcessOptions{}); !ready {
+		return "", llmerr.RateLimited(ErrBreakerOpen, wait)
+	}
+
+	limiter, _ := wp.limiterFor(r)
+	// 按字符数粗略估计 token，调用结束后按实际用量修正
+	estimate := int64(utf8.RuneCountInString(instruction) + 2*utf8.RuneCountInString(input))
+	ctx = wp.withGuard(wp.withGate(ctx, r, estimate), r)
+	if limiter != nil {
+		if _, err := limiter.Wait(ctx, estimate); err != nil {
+			return "", err
+		}
+	}
+	callCtx, callReport := report.New(ctx)
+	text, err := generator.GenerateText(callCtx, instruction, input)
+	if limiter != nil && callReport.Usage.TotalTokens > 0 {
+		limiter.Adjust(callReport.Usage.TotalTokens - estimate)
+	}
+	if outer := report.From(ctx); outer != nil {
+		outer.Usage.Add(callReport.Usage)
+		if callReport.Model != "" {
+			outer.Model = callReport.Model
+		}
+		if callReport.Provider != "" {
+			outer.Provider = callReport.Provider
+		}
+	}
+	return text, err
+}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// reportingGenerator 纯文本调用转为大写，并写入固定的模型和用量
+type reportingGenerator struct {
+	textProcessor
+	calls int
+}
+
+func (p *reportingGenerator) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	p.calls++
+	if r := report.From(ctx); r != nil {
+		r.Model = "text-model"
+		r.Usage.TotalTokens += 42
+	}
+	return p.textProcessor.GenerateText(ctx, instruction, input)
+}
+
+func TestGenerateTextUsesChoiceAndReportsUsage(t *testing.T) {
+	fallback := &reportingGenerator{}
+	choice := &reportingGenerator{}
+	wp := NewWorkerPool(1, fallback)
+	wp.SwapProcessor(&ProcessorSet{
+		Processor: fallback,
+		Choices:   map[string]Choice{"fast": {Provider: "mock", Processor: choice}},
+	})
+
+	ctx, callReport := report.New(context.Background())
+	text, err := wp.GenerateText(ctx, "fast", "instruction", "hello")
+	if err != nil {
+		t.Fatalf("GenerateText: %v", err)
+	}
+	if text != "HELLO" || choice.calls != 1 || fallback.calls != 0 {
+		t.Fatalf("text=%q choice calls=%d default calls=%d", text, choice.calls, fallback.calls)
+	}
+	if callReport.Model != "text-model" || callReport.Usage.TotalTokens != 42 {
+		t.Fatalf("report model=%q usage=%+v", callReport.Model, callReport.Usage)
+	}
+
+	if _, err := wp.GenerateText(context.Background(), "missing", "instruction", "hello"); !errors.Is(err, ErrUnknownChoice) {
+		t.Fatalf("expected ErrUnknownChoice, got %v", err)
+	}
+}
+
+func TestGenerateTextRejectsUnsupportedAndOpenBreaker(t *testing.T) {
+	wp := NewWorkerPool(1, &scriptedProcessor{})
+	_, err := wp.GenerateText(context.Background(), "", "instruction", "hello")
+	if !errors.Is(err, llm.ErrTextUnsupported) || llmerr.ClassOf(err) != llmerr.ClassPermanent {
+		t.Fatalf("expected permanent ErrTextUnsupported, got %v", err)
+	}
+
+	processor := &reportingGenerator{}
+	wp = NewWorkerPool(1, processor)
+	wp.ConfigureBreaker(BreakerConfig{Threshold: 1, Cooldown: time.Minute})
+	wp.breakerFor(defaultProvider).record(false, false)
+	_, err = wp.GenerateText(context.Background(), "", "instruction", "hello")
+	if !errors.Is(err, ErrBreakerOpen) || llmerr.ClassOf(err) != llmerr.ClassRateLimited || llmerr.RetryAfter(err) <= 0 {
+		t.Fatalf("expected rate limited ErrBreakerOpen, got %v", err)
+	}
+	if processor.calls != 0 {
+		t.Fatalf("open breaker must not call the provider, calls=%d", processor.calls)
+	}
+}
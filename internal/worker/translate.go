@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	ratelimit "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/ratelimit"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	translate "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/translate"
+)
+
+// TranslatedPath 分片译文的路径：page_1.md -> page_1.en.md
+func TranslatedPath(outputPath, lang string) string {
+	ext := filepath.Ext(outputPath)
+	return strings.TrimSuffix(outputPath, ext) + "." + lang + ext
+}
+
+// translateShard 把分片的 OCR 结果翻译为 task.Options.TranslateTo 并写入 TranslatedPath；
+// 失败只记录在 signal.TranslateError，不影响分片本身的成功状态。翻译有独立的超时，用量计入 signal
+func (wp *WorkerPool) translateShard(task *SubTask, r route, content string, signal *CompletionSignal) {
+	lang := task.Options.TranslateTo
+	if lang == "" {
+		return
+	}
+	err := wp.runTranslation(task, r, content, signal)
+	if err != nil {
+		signal.TranslateError = err
+		log.Printf("[worker] subtask translation failed parent_id=%s subtask_id=%s lang=%s err=%v", task.ParentID, task.ID, lang, err)
+	}
+}
+
+func (wp *WorkerPool) runTranslation(task *SubTask, r route, content string, signal *CompletionSignal) error {
+	generator, ok := r.processor.(llm.TextGenerator)
+	if !ok {
+		return llm.ErrTextUnsupported
+	}
+
+	ctx, cancel := context.WithTimeout(wp.ctx, wp.taskTimeout)
+	defer cancel()
+	ctx = report.WithProgress(ctx, func(progress report.Progress) {
+		wp.setProgress(task.ID, progress)
+	})
+	report.SetProgress(ctx, report.Progress{State: "translating"})
+	defer wp.clearProgress(task.ID)
+
+	limiter, tokensPerPage := wp.limiterFor(r)
+	ctx = wp.withGate(ctx, r, ratelimit.EstimateTokens(task.PageEnd-task.PageStart+1, tokensPerPage))
+	maxRetries := task.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = wp.retry.MaxRetries
+	}
+	var err error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		var reserved int64
+		if reserved, err = wp.waitRateLimit(ctx, limiter, tokensPerPage, task); err != nil {
+			break
+		}
+		callCtx, callReport := report.New(ctx)
+		var translated string
+		translated, err = translate.Markdown(callCtx, generator, content, task.Options.TranslateTo)
+		if reserved > 0 && callReport.Usage.TotalTokens > 0 {
+			limiter.Adjust(callReport.Usage.TotalTokens - reserved)
+		}
+		signal.AddUsage(callReport.Model, callReport.Usage)
+		if err == nil {
+			return writeOutput(TranslatedPath(task.OutputPath, task.Options.TranslateTo), translated)
+		}
+		if ctx.Err() != nil || llmerr.Fatal(err) {
+			break
+		}
+		// 限流给出等待时间时等待后重试，不消耗重试次数
+		if retryAfter := llmerr.RetryAfter(err); retryAfter > 0 {
+			if !sleepWithContext(ctx, retryAfter) {
+				break
+			}
+			attempt--
+			continue
+		}
+		if attempt+1 < maxRetries && !sleepWithContext(ctx, wp.retry.backoff(attempt)) {
+			break
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("translation timeout after %s: %w", wp.taskTimeout, ctx.Err())
+	}
+	return err
+}
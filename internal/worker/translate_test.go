@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+)
+
+// textProcessor OCR 返回固定内容，翻译转为大写或返回 err
+type textProcessor struct {
+	content string
+	err     error
+}
+
+func (p *textProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts llm.ProcessOptions) (string, error) {
+	return p.content, nil
+}
+
+func (p *textProcessor) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	return strings.ToUpper(input), nil
+}
+
+func TestShardTranslationWritesSeparateFile(t *testing.T) {
+	wp := NewWorkerPool(1, &textProcessor{content: "hello $x$"})
+	wp.Start()
+	defer wp.Shutdown()
+
+	task := &SubTask{ID: "s1", ParentID: "p1", OutputPath: filepath.Join(t.TempDir(), "page_1.md")}
+	task.Options.TranslateTo = "en"
+	if err := wp.Submit(task, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if got := waitOutput(t, wp, task.OutputPath); got != "hello $x$" {
+		t.Fatalf("original output = %q", got)
+	}
+	translated, err := os.ReadFile(TranslatedPath(task.OutputPath, "en"))
+	if err != nil {
+		t.Fatalf("read translation: %v", err)
+	}
+	if string(translated) != "HELLO $x$" {
+		t.Fatalf("translation = %q, want formula kept", translated)
+	}
+}
+
+func TestShardTranslationFailureKeepsShard(t *testing.T) {
+	wp := NewWorkerPool(1, &textProcessor{content: "hello", err: errors.New("boom")})
+	wp.ConfigureRetry(RetryConfig{MaxRetries: 1})
+	wp.Start()
+	defer wp.Shutdown()
+
+	task := &SubTask{ID: "s1", ParentID: "p1", OutputPath: filepath.Join(t.TempDir(), "page_1.md")}
+	task.Options.TranslateTo = "ja"
+	if err := wp.Submit(task, time.Second); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case signal := <-wp.ResultChan():
+		if !signal.Success || signal.TranslateError == nil {
+			t.Fatalf("signal success=%t translate_error=%v, want success with translate error", signal.Success, signal.TranslateError)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no completion signal")
+	}
+}
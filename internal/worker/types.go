@@ -3,9 +3,12 @@ package worker
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	llm "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM"
+	ratelimit "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/ratelimit"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
 )
 
 type SubTask struct {
@@ -17,22 +20,72 @@ type SubTask struct {
 	PageEnd    int // 结束页码
 	RetryCount int // 当前重试次数
 	MaxRetries int // 最大重试次数（默认3）
+
+	Options   llm.ProcessOptions // 单次调用参数（提示词等），同一父任务的分片相同
+	Processor string             // 任务选择的模型选项名字，为空时使用默认 processor
+
+	carried *CompletionSignal // 熔断放回前已累计的用量和模型，重新取出后继续累计
 }
 
 type CompletionSignal struct {
-	SubTaskID string // 分片ID
-	ParentID  string // 父任务ID
-	Success   bool   // 是否成功
-	Error     error  // 失败时的错误信息
+	SubTaskID string       // 分片ID
+	ParentID  string       // 父任务ID
+	Success   bool         // 是否成功
+	Error     error        // 失败时的错误信息
+	Provider  string       // 实际产出结果的 provider（回退链场景下可能不是首选）
+	Model     string       // 实际使用的模型
+	Usage     report.Usage // 所有尝试累计的用量（失败的尝试同样计费）
+	Cost      float64      // 由 TaskManager 按价目表填写
+
+	ModelUsage map[string]report.Usage // 按实际使用的模型拆分的用量，回退或换模型时分别计价
+
+	TranslateError error // 请求了翻译但翻译失败，原文结果不受影响
+}
+
+// AddUsage 累计一次调用的用量；model 为空时记在当前模型名下
+func (s *CompletionSignal) AddUsage(model string, usage report.Usage) {
+	s.Usage.Add(usage)
+	if model == "" {
+		model = s.Model
+	}
+	if s.ModelUsage == nil {
+		s.ModelUsage = make(map[string]report.Usage)
+	}
+	total := s.ModelUsage[model]
+	total.Add(usage)
+	s.ModelUsage[model] = total
 }
 
 type WorkerPool struct {
 	workerCount int                    // worker数量（固定5）
 	taskQueue   chan *SubTask          // 任务队列（容量100）
 	resultChan  chan *CompletionSignal // 结果通道（容量10）
-	processor   llm.PDFProcessor       // 可选的LLM API客户端
 	taskTimeout time.Duration          // 单个子任务处理超时
+	retry       RetryConfig            // 重试次数与退避参数
 	ctx         context.Context        // 上下文
 	cancel      context.CancelFunc     // 取消函数
-	wg          sync.WaitGroup         // 等待所有worker退出
+	wg          sync.WaitGroup         // 等待所有worker和异步分片的后台翻译退出
+
+	mu       sync.Mutex                // 保护 deferred / early / held / heldWake / stopping
+	deferred map[string]*deferredShard // 远端任务 ID -> 等待异步结果的分片
+	early    map[string]*earlyResult   // 远端任务 ID -> 分片登记前送达的结果
+	stopping bool                      // Shutdown 已开始，之后送达的异步结果直接丢弃
+	held     []*SubTask                // 熔断期间放回等待的分片，熔断器放行后优先于 taskQueue 取出
+	heldWake chan struct{}             // 放回的分片可能可以处理时关闭，唤醒等待的 worker
+
+	emitMu sync.RWMutex // 异步分片发送完成信号时持读锁，Shutdown 持写锁关闭 resultChan
+	closed bool         // Shutdown 后不再发送完成信号
+
+	processors atomic.Pointer[ProcessorSet] // 当前生效的 processor，配置热加载时原子替换
+	setMu      sync.Mutex                   // 保护 ProcessorSet 的引用计数，与替换互斥
+
+	ctlMu         sync.RWMutex                  // 保护 breakers / limiters，配置热加载时会修改
+	breakerConfig BreakerConfig                 // 新建熔断器使用的参数
+	breakers      map[string]*breaker           // provider -> 熔断器
+	limiters      map[string]*ratelimit.Limiter // provider -> 所有 worker 共享的限流器
+	limiterOrder  []string                      // 配置顺序，首个为未指定 provider 的分片使用
+	tokensPerPage int                           // 预估 TPM 用的每页 token 数
+
+	progressMu sync.Mutex
+	progress   map[string]report.Progress // 分片ID -> provider 最近上报的进度
 }
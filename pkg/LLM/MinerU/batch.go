@@ -0,0 +1,180 @@
+package mineru
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// CreateUploadBatch 申请文件上传链接；文件上传完成后 MinerU 自动提交解析任务
+func (c *Client) CreateUploadBatch(ctx context.Context, req CreateUploadBatchRequest) (*CreateUploadBatchResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	url := c.BaseURL + "/api/v4/file-urls/batch"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out CreateUploadBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if out.Code != 0 {
+		return &out, &APIError{Op: "upload_urls", Code: out.Code, Msg: out.Msg, TraceID: out.TraceID}
+	}
+	return &out, nil
+}
+
+// UploadFile 把本地文件 PUT 到上传链接，按文档要求不设置 Content-Type
+func (c *Client) UploadFile(ctx context.Context, uploadURL, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, file)
+	if err != nil {
+		return err
+	}
+	httpReq.ContentLength = info.Size()
+
+	resp, err := c.HTTP.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upload %s: unexpected status %d", filepath.Base(path), resp.StatusCode)
+	}
+	return nil
+}
+
+// GetBatch 查询批量任务中各文件的解析结果
+func (c *Client) GetBatch(ctx context.Context, batchID string) (*GetBatchResponse, error) {
+	url := c.BaseURL + "/api/v4/extract-results/batch/" + batchID
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	httpReq.Header.Set("Accept", "*/*")
+
+	resp, err := c.HTTP.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out GetBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if out.Code != 0 {
+		return &out, &APIError{Op: "get_batch", Code: out.Code, Msg: out.Msg, TraceID: out.TraceID}
+	}
+	return &out, nil
+}
+
+// WaitForBatch 轮询批量结果直到 fileName 对应的文件解析完成或失败
+func (c *Client) WaitForBatch(ctx context.Context, batchID, fileName string) (*ExtractResult, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for batch: %w", ctx.Err())
+		case <-ticker.C:
+			batch, err := c.GetBatch(ctx, batchID)
+			if err != nil {
+				return nil, err
+			}
+			item := findBatchResult(batch, fileName)
+			if item == nil {
+				// 文件刚上传完成时结果列表可能还是空的
+				continue
+			}
+			reportProgress(ctx, item.State, item.ProgressInfo)
+
+			switch strings.TrimSpace(item.State) {
+			case "done":
+				return item, nil
+			case "failed":
+				return nil, fmt.Errorf("task failed: %s", item.ErrMsg)
+			}
+		}
+	}
+}
+
+func findBatchResult(batch *GetBatchResponse, fileName string) *ExtractResult {
+	for i := range batch.Data.ExtractResult {
+		if batch.Data.ExtractResult[i].FileName == fileName {
+			return &batch.Data.ExtractResult[i].ExtractResult
+		}
+	}
+	if len(batch.Data.ExtractResult) == 1 {
+		return &batch.Data.ExtractResult[0].ExtractResult
+	}
+	return nil
+}
+
+// processByUpload 文件直传流程：申请上传链接、PUT 分片、轮询批量结果，无需本服务对公网可达
+func (c *Client) processByUpload(ctx context.Context, pdfPath, modelVersion string, mineruOpts options.MinerUOptions) (string, *ExtractResult, error) {
+	fileName := filepath.Base(pdfPath)
+	batch, err := c.CreateUploadBatch(ctx, CreateUploadBatchRequest{
+		Files: []BatchFile{{
+			Name:       fileName,
+			IsOCR:      mineruOpts.IsOCR,
+			PageRanges: mineruOpts.PageRanges,
+		}},
+		ModelVersion:  modelVersion,
+		EnableFormula: mineruOpts.EnableFormula,
+		EnableTable:   mineruOpts.EnableTable,
+		Language:      mineruOpts.Language,
+		ExtraFormats:  mineruOpts.ExtraFormats,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to request upload url: %w", err)
+	}
+	if len(batch.Data.FileURLs) != 1 {
+		return "", nil, fmt.Errorf("expected 1 upload url, got %d", len(batch.Data.FileURLs))
+	}
+	batchID := batch.Data.BatchID
+
+	report.SetProgress(ctx, report.Progress{State: "uploading"})
+	if err := c.UploadFile(ctx, batch.Data.FileURLs[0], pdfPath); err != nil {
+		return "", nil, fmt.Errorf("failed to upload pdf: %w", err)
+	}
+
+	item, err := c.WaitForBatch(ctx, batchID, fileName)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to wait for mineru batch: %w", err)
+	}
+	return batchID, item, nil
+}
@@ -0,0 +1,121 @@
+package mineru
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+)
+
+// batchServer 模拟 MinerU 批量接口：申请上传链接、接收 PUT、按调用次数返回批量结果
+func batchServer(t *testing.T, results func(poll int) string) (*httptest.Server, *[]byte) {
+	t.Helper()
+	var uploaded []byte
+	var polls atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v4/file-urls/batch":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				t.Errorf("missing bearer token")
+			}
+			var req CreateUploadBatchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Files) != 1 {
+				t.Errorf("bad upload batch request: %v", err)
+			}
+			io.WriteString(w, `{"code":0,"data":{"batch_id":"b-1","file_urls":["`+server.URL+`/upload/a.pdf"]}}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/upload/a.pdf":
+			if r.Header.Get("Content-Type") != "" {
+				t.Errorf("upload must not set Content-Type")
+			}
+			uploaded, _ = io.ReadAll(r.Body)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/extract-results/batch/b-1":
+			io.WriteString(w, `{"code":0,"data":{"batch_id":"b-1","extract_result":[`+results(int(polls.Add(1)))+`]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &uploaded
+}
+
+func useFastPolling(t *testing.T) {
+	t.Helper()
+	old := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = old })
+}
+
+func TestBatch_UploadAndWaitDone(t *testing.T) {
+	useFastPolling(t)
+	server, uploaded := batchServer(t, func(poll int) string {
+		switch poll {
+		case 1:
+			return ""
+		case 2:
+			return `{"file_name":"a.pdf","state":"running"}`
+		default:
+			return `{"file_name":"other.pdf","state":"failed"},{"file_name":"a.pdf","state":"done","full_zip_url":"https://example.com/a.zip"}`
+		}
+	})
+	pdfPath := filepath.Join(t.TempDir(), "a.pdf")
+	if err := os.WriteFile(pdfPath, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatalf("write pdf: %v", err)
+	}
+
+	client := NewClient(server.URL, "token", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	batchID, item, err := client.processByUpload(ctx, pdfPath, "vlm", options.MinerUOptions{})
+	if err != nil {
+		t.Fatalf("process by upload: %v", err)
+	}
+	if batchID != "b-1" || item.State != "done" || item.FullZipURL != "https://example.com/a.zip" {
+		t.Fatalf("unexpected result: batch=%s item=%+v", batchID, item)
+	}
+	if string(*uploaded) != "%PDF-1.4" {
+		t.Fatalf("uploaded = %q", *uploaded)
+	}
+}
+
+func TestBatch_WaitFailed(t *testing.T) {
+	useFastPolling(t)
+	server, _ := batchServer(t, func(int) string {
+		return `{"file_name":"a.pdf","state":"failed","err_msg":"bad pdf"}`
+	})
+	client := NewClient(server.URL, "token", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.WaitForBatch(ctx, "b-1", "a.pdf"); err == nil || !strings.Contains(err.Error(), "bad pdf") {
+		t.Fatalf("expected failed task error, got %v", err)
+	}
+}
+
+func TestBatch_FileNotInBatch(t *testing.T) {
+	useFastPolling(t)
+	server, _ := batchServer(t, func(int) string {
+		return `{"file_name":"x.pdf","state":"done"},{"file_name":"y.pdf","state":"done"}`
+	})
+	client := NewClient(server.URL, "token", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.WaitForBatch(ctx, "b-1", "a.pdf"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected timeout while file is missing, got %v", err)
+	}
+
+	single := &GetBatchResponse{}
+	single.Data.ExtractResult = []BatchExtractResult{{FileName: "renamed.pdf"}}
+	if findBatchResult(single, "a.pdf") == nil {
+		t.Fatalf("single result should match regardless of file name")
+	}
+}
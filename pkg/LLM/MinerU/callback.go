@@ -0,0 +1,180 @@
+package mineru
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	async "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/async"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// CallbackPath 回调接收端点，相对 PUBLIC_URL
+const CallbackPath = "/api/callbacks/mineru"
+
+const (
+	// collectTimeout 回调到达后下载、解压结果的超时
+	collectTimeout = 5 * time.Minute
+	// pendingTTL 超过该时间仍未回调的任务不再等待（分片已由 worker 超时判失败）
+	pendingTTL = 24 * time.Hour
+)
+
+var (
+	ErrInvalidSeed     = errors.New("mineru callback seed must be 1-64 letters, digits or underscores")
+	ErrMissingUID      = errors.New("mineru callback requires MINERU_UID")
+	ErrInvalidChecksum = errors.New("invalid mineru callback checksum")
+	ErrUnknownTask     = errors.New("unknown mineru task")
+	ErrInvalidCallback = errors.New("invalid mineru callback content")
+	seedPattern        = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+)
+
+// pendingTask 已提交、等待回调的 MinerU 任务
+type pendingTask struct {
+	client       *Client
+	pdfPath      string
+	modelVersion string
+	extraFormats []string
+	submittedAt  time.Time
+}
+
+// Callbacks MinerU 回调模式：创建任务时带上 callback 和 seed，ProcessPDF 提交后立即返回 *async.Pending，
+// MinerU 解析完成后推送结果，由 Handle 校验签名、下载结果并交给 OnResult 注册的函数。
+type Callbacks struct {
+	URL string // MinerU 推送地址，如 https://yourdomain.com/api/callbacks/mineru
+
+	uid  string
+	seed string
+
+	mu       sync.Mutex
+	pending  map[string]pendingTask // MinerU task_id -> 分片
+	onResult func(async.Result)
+}
+
+// NewCallbacks 创建回调接收器；uid 为 MinerU 个人中心的用户 UID，参与 checksum 计算
+func NewCallbacks(url, uid, seed string) (*Callbacks, error) {
+	if !seedPattern.MatchString(seed) {
+		return nil, ErrInvalidSeed
+	}
+	if strings.TrimSpace(uid) == "" {
+		return nil, ErrMissingUID
+	}
+	return &Callbacks{
+		URL:     url,
+		uid:     strings.TrimSpace(uid),
+		seed:    seed,
+		pending: make(map[string]pendingTask),
+	}, nil
+}
+
+// OnResult 设置结果接收函数，应在提交任何任务之前调用
+func (cb *Callbacks) OnResult(fn func(async.Result)) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.onResult = fn
+}
+
+// Pending 返回等待回调的任务数
+func (cb *Callbacks) Pending() int {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return len(cb.pending)
+}
+
+// Verify 校验 checksum = sha256(uid + seed + content)
+func (cb *Callbacks) Verify(checksum, content string) bool {
+	sum := sha256.Sum256([]byte(cb.uid + cb.seed + content))
+	expected := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(strings.TrimSpace(checksum)))) == 1
+}
+
+// Handle 处理一次 MinerU 推送。content 为任务查询结果的 data 部分（JSON 字符串）。
+// 未知任务返回 ErrUnknownTask，接口以非 200 响应让 MinerU 重推；
+// 结果下载在后台进行，避免 MinerU 推送超时。
+func (cb *Callbacks) Handle(checksum, content string) error {
+	if !cb.Verify(checksum, content) {
+		return ErrInvalidChecksum
+	}
+
+	var item ExtractResult
+	if err := json.Unmarshal([]byte(content), &item); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+	}
+	state := strings.TrimSpace(item.State)
+	if item.TaskID == "" {
+		return fmt.Errorf("%w: missing task_id", ErrInvalidCallback)
+	}
+	if state != "done" && state != "failed" {
+		// 中间状态的推送只确认接收
+		return nil
+	}
+
+	cb.mu.Lock()
+	task, ok := cb.pending[item.TaskID]
+	if ok {
+		delete(cb.pending, item.TaskID)
+	}
+	cb.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTask, item.TaskID)
+	}
+
+	go cb.complete(task, item)
+	return nil
+}
+
+func (cb *Callbacks) register(taskID string, task pendingTask) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	now := time.Now()
+	for id, pending := range cb.pending {
+		if now.Sub(pending.submittedAt) > pendingTTL {
+			delete(cb.pending, id)
+		}
+	}
+	task.submittedAt = now
+	cb.pending[taskID] = task
+}
+
+// forget 删除等待中的任务，之后到达的回调按未知任务处理
+func (cb *Callbacks) forget(taskID string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	delete(cb.pending, taskID)
+}
+
+// complete 下载结果并交给 onResult
+func (cb *Callbacks) complete(task pendingTask, item ExtractResult) {
+	result := async.Result{
+		RemoteID: item.TaskID,
+		Model:    "mineru-" + task.modelVersion,
+	}
+
+	if strings.TrimSpace(item.State) == "failed" {
+		result.Err = fmt.Errorf("task failed: %s", item.ErrMsg)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+		ctx, callReport := report.New(ctx)
+		result.Content, result.Err = task.client.collectResult(ctx, task.pdfPath, item.TaskID, item.FullZipURL, task.extraFormats)
+		recordUsage(ctx, task.pdfPath, task.modelVersion, item.ProgressInfo)
+		result.Usage = callReport.Usage
+		cancel()
+	}
+
+	cb.mu.Lock()
+	onResult := cb.onResult
+	cb.mu.Unlock()
+	if onResult == nil {
+		log.Printf("[mineru] callback result dropped, no receiver task_id=%s", item.TaskID)
+		return
+	}
+	onResult(result)
+}
@@ -0,0 +1,57 @@
+package mineru
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	async "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/async"
+)
+
+func sign(uid, seed, content string) string {
+	sum := sha256.Sum256([]byte(uid + seed + content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCallbacks_Handle(t *testing.T) {
+	cb, err := NewCallbacks("https://example.com"+CallbackPath, "uid-1", "seed_1")
+	if err != nil {
+		t.Fatalf("new callbacks: %v", err)
+	}
+	results := make(chan async.Result, 1)
+	cb.OnResult(func(r async.Result) { results <- r })
+	cb.register("t-1", pendingTask{client: NewClient("", "", ""), pdfPath: "output/x/a.pdf", modelVersion: "vlm"})
+
+	content := `{"task_id":"t-1","state":"failed","err_msg":"bad pdf"}`
+	if err := cb.Handle("deadbeef", content); !errors.Is(err, ErrInvalidChecksum) {
+		t.Fatalf("expected ErrInvalidChecksum, got %v", err)
+	}
+
+	unknown := `{"task_id":"t-2","state":"done"}`
+	if err := cb.Handle(sign("uid-1", "seed_1", unknown), unknown); !errors.Is(err, ErrUnknownTask) {
+		t.Fatalf("expected ErrUnknownTask, got %v", err)
+	}
+
+	if err := cb.Handle(sign("uid-1", "seed_1", content), content); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	select {
+	case r := <-results:
+		if r.RemoteID != "t-1" || r.Err == nil || r.Model != "mineru-vlm" {
+			t.Fatalf("unexpected result: %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("result not delivered")
+	}
+	if cb.Pending() != 0 {
+		t.Fatalf("expected no pending tasks, got %d", cb.Pending())
+	}
+}
+
+func TestNewCallbacks_InvalidSeed(t *testing.T) {
+	if _, err := NewCallbacks("https://example.com", "uid", "bad seed!"); !errors.Is(err, ErrInvalidSeed) {
+		t.Fatalf("expected ErrInvalidSeed, got %v", err)
+	}
+}
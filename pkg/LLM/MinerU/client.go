@@ -11,14 +11,22 @@ import (
 	"strings"
 	"time"
 
+	async "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/async"
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	pdf "github.com/neyuki778/LLM-PDF-OCR/pkg/pdf"
 	"github.com/neyuki778/LLM-PDF-OCR/pkg/result"
 )
 
+// pollInterval 轮询任务和批量结果的间隔
+var pollInterval = 5 * time.Second
+
 type Client struct {
 	BaseURL   string // MinerU API 地址，如 https://mineru.net
 	Token     string // MinerU API Token
-	PublicURL string // 本服务的公开地址，如 https://yourdomain.com
+	PublicURL string // 本服务的公开地址，如 https://yourdomain.com；为空时改用文件直传
 	HTTP      *http.Client
+	Callbacks *Callbacks // 非 nil 时使用回调模式，不再轮询等待
 }
 
 func NewClient(baseURL, token, publicURL string) *Client {
@@ -59,7 +67,7 @@ func (c *Client) CreateTask(ctx context.Context, req CreateTaskRequest) (*Create
 		return nil, err
 	}
 	if out.Code != 0 {
-		return &out, fmt.Errorf("mineru create failed: code=%d msg=%s trace_id=%s", out.Code, out.Msg, out.TraceID)
+		return &out, &APIError{Op: "create", Code: out.Code, Msg: out.Msg, TraceID: out.TraceID}
 	}
 	return &out, nil
 }
@@ -85,13 +93,13 @@ func (c *Client) GetTask(ctx context.Context, taskID string) (*GetTaskResponse,
 	}
 	normalizeGetTaskResponse(&out)
 	if out.Code != 0 {
-		return &out, fmt.Errorf("mineru get failed: code=%d msg=%s trace_id=%s", out.Code, out.Msg, out.TraceID)
+		return &out, &APIError{Op: "get", Code: out.Code, Msg: out.Msg, TraceID: out.TraceID}
 	}
 	return &out, nil
 }
 
 func (c *Client) WaitForCompletion(ctx context.Context, taskID string) (*GetTaskResponse, error) {
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -104,10 +112,7 @@ func (c *Client) WaitForCompletion(ctx context.Context, taskID string) (*GetTask
 				return nil, err
 			}
 
-			fmt.Printf("Progress: %d/%d pages (state: %s)\n",
-				task.Data.ProgressInfo.ExtractedPages,
-				task.Data.ProgressInfo.TotalPages,
-				task.Data.State)
+			reportProgress(ctx, task.Data.State, task.Data.ProgressInfo)
 
 			switch strings.TrimSpace(task.Data.State) {
 			case "done":
@@ -119,9 +124,33 @@ func (c *Client) WaitForCompletion(ctx context.Context, taskID string) (*GetTask
 	}
 }
 
-// ProcessPDF 实现 PDFProcessor 接口
+// ProcessPDF 实现 PDFProcessor 接口，错误按 llmerr 分类返回
 // pdfPath 是本地文件路径，如 uploads/xxx.pdf
-func (c *Client) ProcessPDF(ctx context.Context, pdfPath string) (string, error) {
+func (c *Client) ProcessPDF(ctx context.Context, pdfPath string, opts options.Options) (string, error) {
+	content, err := c.processPDF(ctx, pdfPath, opts)
+	return content, classify(err)
+}
+
+func (c *Client) processPDF(ctx context.Context, pdfPath string, opts options.Options) (string, error) {
+	if len(opts.ResponseSchema) > 0 {
+		return "", ErrSchemaUnsupported
+	}
+
+	modelVersion := opts.MinerU.ModelVersion
+	if modelVersion == "" {
+		modelVersion = "vlm"
+	}
+
+	// 未配置 PUBLIC_URL 时走文件直传，MinerU 不需要访问本服务
+	if strings.TrimSpace(c.PublicURL) == "" {
+		batchID, item, err := c.processByUpload(ctx, pdfPath, modelVersion, opts.MinerU)
+		if err != nil {
+			return "", err
+		}
+		recordUsage(ctx, pdfPath, modelVersion, item.ProgressInfo)
+		return c.collectResult(ctx, pdfPath, batchID, item.FullZipURL, opts.MinerU.ExtraFormats)
+	}
+
 	// 1. 将本地路径转换为公开 URL
 	// pdfPath 格式: output/<task_id>/xxx.pdf -> https://yourdomain.com/output/<task_id>/xxx.pdf
 	cleanPath := filepath.Clean(pdfPath)
@@ -134,24 +163,59 @@ func (c *Client) ProcessPDF(ctx context.Context, pdfPath string) (string, error)
 	pdfURL := publicBase + "/output/" + filepath.ToSlash(relative)
 
 	// 2. 创建 MinerU 任务
-	createResp, err := c.CreateTask(ctx, CreateTaskRequest{
-		URL:          pdfURL,
-		ModelVersion: "vlm",
-	})
+	createReq := CreateTaskRequest{
+		URL:           pdfURL,
+		ModelVersion:  modelVersion,
+		IsOCR:         opts.MinerU.IsOCR,
+		EnableFormula: opts.MinerU.EnableFormula,
+		EnableTable:   opts.MinerU.EnableTable,
+		Language:      opts.MinerU.Language,
+		ExtraFormats:  opts.MinerU.ExtraFormats,
+		PageRanges:    opts.MinerU.PageRanges,
+	}
+	if c.Callbacks != nil {
+		createReq.Callback = c.Callbacks.URL
+		createReq.Seed = c.Callbacks.seed
+	}
+	createResp, err := c.CreateTask(ctx, createReq)
 	if err != nil {
 		return "", fmt.Errorf("failed to create mineru task: %w", err)
 	}
+	taskID := createResp.Data.TaskID
+
+	// 3. 回调模式：登记任务后立即返回，结果由 Callbacks 送达
+	if c.Callbacks != nil {
+		report.SetProgress(ctx, report.Progress{State: "waiting for callback"})
+		c.Callbacks.register(taskID, pendingTask{
+			client:       c,
+			pdfPath:      pdfPath,
+			modelVersion: modelVersion,
+			extraFormats: opts.MinerU.ExtraFormats,
+		})
+		callbacks := c.Callbacks
+		return "", &async.Pending{
+			Provider: "mineru",
+			RemoteID: taskID,
+			Cancel:   func() { callbacks.forget(taskID) },
+		}
+	}
 
 	// 3. 等待任务完成
-	taskResp, err := c.WaitForCompletion(ctx, createResp.Data.TaskID)
+	taskResp, err := c.WaitForCompletion(ctx, taskID)
 	if err != nil {
 		return "", fmt.Errorf("failed to wait for mineru task: %w", err)
 	}
+	recordUsage(ctx, pdfPath, modelVersion, taskResp.Data.ProgressInfo)
+
+	return c.collectResult(ctx, pdfPath, taskID, taskResp.Data.FullZipURL, opts.MinerU.ExtraFormats)
+}
 
+// collectResult 下载结果 ZIP，提取图片、额外导出格式和版面文件到分片所在目录并返回 Markdown
+func (c *Client) collectResult(ctx context.Context, pdfPath, taskID, zipURL string, extraFormats []string) (string, error) {
 	// 4. 下载结果 ZIP
 	tempDir := os.TempDir()
-	zipPath := filepath.Join(tempDir, createResp.Data.TaskID+".zip")
-	if err := result.DownloadZip(ctx, taskResp.Data.FullZipURL, zipPath); err != nil {
+	zipPath := filepath.Join(tempDir, taskID+".zip")
+	if err := result.DownloadZipWithClient(ctx, c.downloadClient(), zipURL, zipPath); err != nil {
 		return "", fmt.Errorf("failed to download result: %w", err)
 	}
 	defer os.Remove(zipPath)
@@ -161,6 +225,14 @@ func (c *Client) ProcessPDF(ctx context.Context, pdfPath string) (string, error)
 	if err := result.ExtractImagesToDir(zipPath, workDir); err != nil {
 		return "", fmt.Errorf("failed to extract images: %w", err)
 	}
+	shardName := strings.TrimSuffix(filepath.Base(pdfPath), filepath.Ext(pdfPath))
+	if err := result.ExtractFormatsToDir(zipPath, workDir, shardName, extraFormats); err != nil {
+		return "", fmt.Errorf("failed to extract extra formats: %w", err)
+	}
+	// 保留版面信息（bbox、块类型），聚合时按原文档页码合并
+	if err := result.ExtractLayoutToDir(zipPath, workDir, shardName); err != nil {
+		return "", fmt.Errorf("failed to extract layout: %w", err)
+	}
 
 	// 6. 提取 Markdown 内容
 	markdown, err := result.ExtractMarkdown(zipPath)
@@ -208,3 +280,32 @@ func applyExtractResult(task *GetTaskResponse, item *ExtractResult) {
 	task.Data.ErrMsg = item.ErrMsg
 	task.Data.ProgressInfo = item.ProgressInfo
 }
+
+// reportProgress 把远端解析进度上报到 ctx 携带的进度回调
+func reportProgress(ctx context.Context, state string, progress ExtractProgress) {
+	report.SetProgress(ctx, report.Progress{
+		Done:  progress.ExtractedPages,
+		Total: progress.TotalPages,
+		Unit:  "pages",
+		State: strings.TrimSpace(state),
+	})
+}
+
+// recordUsage 把本次解析的页数写入 ctx 携带的 report；MinerU 按页计费，任务完成即计入
+func recordUsage(ctx context.Context, pdfPath, modelVersion string, progress ExtractProgress) {
+	r := report.From(ctx)
+	if r == nil {
+		return
+	}
+	r.Model = "mineru-" + modelVersion
+
+	pages := progress.TotalPages
+	if pages <= 0 {
+		count, err := pdf.GetPageCount(pdfPath)
+		if err != nil {
+			return
+		}
+		pages = count
+	}
+	r.Usage.Add(report.Usage{Pages: int64(pages)})
+}
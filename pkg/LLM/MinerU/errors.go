@@ -0,0 +1,87 @@
+package mineru
+
+import (
+	"errors"
+	"fmt"
+
+	async "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/async"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+)
+
+// ErrSchemaUnsupported MinerU 只输出 Markdown，不支持结构化抽取
+var ErrSchemaUnsupported = errors.New("mineru does not support structured extraction")
+
+// APIError MinerU 接口返回 code != 0 时的错误，保留原始 code 供上层分类
+type APIError struct {
+	Op      string // create / get
+	Code    int
+	Msg     string
+	TraceID string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("mineru %s failed: code=%d msg=%s trace_id=%s", e.Op, e.Code, e.Msg, e.TraceID)
+}
+
+// 常见错误码，见 批量操作-api文档.md「常见错误码」
+const (
+	CodeParamError        = -500
+	CodeServiceError      = -10001
+	CodeRequestParamError = -10002
+	CodeFileFormat        = -60002
+	CodeFileRead          = -60003
+	CodeEmptyFile         = -60004
+	CodeFileTooLarge      = -60005
+	CodeTooManyPages      = -60006
+	CodeModelUnavailable  = -60007
+	CodeQueueFull         = -60009
+	CodeNoPermission      = -60013
+	CodeRetryLimit        = -60017
+	CodeDailyTaskLimit    = -60018
+	CodeHTMLQuotaExceeded = -60019
+)
+
+// IsQuota 判断是否为额度/限流类错误
+func (e *APIError) IsQuota() bool {
+	switch e.Code {
+	case CodeQueueFull, CodeDailyTaskLimit, CodeHTMLQuotaExceeded:
+		return true
+	}
+	return false
+}
+
+// IsPermanent 判断是否为重试也不会成功的错误（参数、文件本身、权限问题）
+func (e *APIError) IsPermanent() bool {
+	switch e.Code {
+	case CodeParamError, CodeRequestParamError, CodeFileFormat, CodeFileRead,
+		CodeEmptyFile, CodeFileTooLarge, CodeTooManyPages, CodeNoPermission, CodeRetryLimit:
+		return true
+	}
+	return false
+}
+
+// classify 把错误映射为 llmerr 分类：额度/限流错误码为 rate_limited，参数、文件、权限类错误码为 permanent，
+// 其余接口错误码和网络错误按临时错误处理；async.Pending 原样返回
+func classify(err error) error {
+	if err == nil || llmerr.IsClassified(err) {
+		return err
+	}
+	var pending *async.Pending
+	if errors.As(err, &pending) {
+		return err
+	}
+	if errors.Is(err, ErrSchemaUnsupported) {
+		return llmerr.Permanent(err)
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.IsQuota():
+			return llmerr.RateLimited(err, 0)
+		case apiErr.IsPermanent():
+			return llmerr.Permanent(err)
+		}
+	}
+	return llmerr.Retryable(err)
+}
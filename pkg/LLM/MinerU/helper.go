@@ -3,6 +3,7 @@ package mineru
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 
@@ -27,7 +28,7 @@ func (c *Client) ProcessTask(ctx context.Context, taskID string) (*result.Conten
 	zipPath := filepath.Join(tmpDir, taskID+".zip")
 	defer os.Remove(zipPath) // 清理临时文件
 
-	if err := result.DownloadZip(ctx, task.Data.FullZipURL, zipPath); err != nil {
+	if err := result.DownloadZipWithClient(ctx, c.downloadClient(), task.Data.FullZipURL, zipPath); err != nil {
 		return nil, fmt.Errorf("download zip failed: %w", err)
 	}
 
@@ -51,5 +52,10 @@ func (c *Client) DownloadResult(ctx context.Context, taskID, destPath string) er
 		return fmt.Errorf("task completed but no zip url returned")
 	}
 
-	return result.DownloadZip(ctx, task.Data.FullZipURL, destPath)
+	return result.DownloadZipWithClient(ctx, c.downloadClient(), task.Data.FullZipURL, destPath)
+}
+
+// downloadClient 下载结果 ZIP 用的客户端：沿用 c.HTTP 的 Transport，但不设整体超时，大文件只受 ctx 约束
+func (c *Client) downloadClient() *http.Client {
+	return &http.Client{Transport: c.HTTP.Transport}
 }
@@ -7,8 +7,8 @@ type CreateTaskRequest struct {
 	URL           string   `json:"url"`
 	ModelVersion  string   `json:"model_version,omitempty"`
 	IsOCR         bool     `json:"is_ocr,omitempty"`
-	EnableFormula bool     `json:"enable_formula,omitempty"`
-	EnableTable   bool     `json:"enable_table,omitempty"`
+	EnableFormula *bool    `json:"enable_formula,omitempty"` // nil 表示使用默认值 true
+	EnableTable   *bool    `json:"enable_table,omitempty"`   // nil 表示使用默认值 true
 	Language      string   `json:"language,omitempty"`
 	DataID        string   `json:"data_id,omitempty"`
 	Callback      string   `json:"callback,omitempty"`
@@ -58,3 +58,51 @@ type GetTaskResponse struct {
 		ExtractResult json.RawMessage `json:"extract_result"`
 	} `json:"data"`
 }
+
+// BatchFile 文件批量上传中的单个文件
+type BatchFile struct {
+	Name       string `json:"name"`
+	IsOCR      bool   `json:"is_ocr,omitempty"`
+	DataID     string `json:"data_id,omitempty"`
+	PageRanges string `json:"page_ranges,omitempty"`
+}
+
+// CreateUploadBatchRequest 对应 POST /api/v4/file-urls/batch 的请求体
+type CreateUploadBatchRequest struct {
+	Files         []BatchFile `json:"files"`
+	ModelVersion  string      `json:"model_version,omitempty"`
+	EnableFormula *bool       `json:"enable_formula,omitempty"`
+	EnableTable   *bool       `json:"enable_table,omitempty"`
+	Language      string      `json:"language,omitempty"`
+	Callback      string      `json:"callback,omitempty"`
+	Seed          string      `json:"seed,omitempty"`
+	ExtraFormats  []string    `json:"extra_formats,omitempty"`
+}
+
+// CreateUploadBatchResponse 返回 batch_id 和与 Files 一一对应的上传链接
+type CreateUploadBatchResponse struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	TraceID string `json:"trace_id"`
+	Data    struct {
+		BatchID  string   `json:"batch_id"`
+		FileURLs []string `json:"file_urls"`
+	} `json:"data"`
+}
+
+// BatchExtractResult 批量结果中的单个文件
+type BatchExtractResult struct {
+	FileName string `json:"file_name"`
+	ExtractResult
+}
+
+// GetBatchResponse 对应 GET /api/v4/extract-results/batch/{batch_id}
+type GetBatchResponse struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	TraceID string `json:"trace_id"`
+	Data    struct {
+		BatchID       string               `json:"batch_id"`
+		ExtractResult []BatchExtractResult `json:"extract_result"`
+	} `json:"data"`
+}
@@ -0,0 +1,31 @@
+// Package async 描述 provider 把分片交给远端异步处理的约定：
+// ProcessPDF 提交远端任务后立即返回 *Pending，worker 随即释放；
+// 远端结果到达后由 provider 构造 Result 交给调用方（WorkerPool）完成分片。
+package async
+
+import (
+	"fmt"
+
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// Pending 表示分片已提交到远端、结果稍后送达。以 error 的形式返回，
+// 经回退链、key 池包装后仍可用 errors.As 取出。
+type Pending struct {
+	Provider string // 处理该分片的 provider
+	RemoteID string // 远端任务 ID，用于结果到达时找回分片
+	Cancel   func() // 调用方不再等待结果时调用（如超时），释放 provider 的登记；可为 nil
+}
+
+func (p *Pending) Error() string {
+	return fmt.Sprintf("%s task %s pending", p.Provider, p.RemoteID)
+}
+
+// Result 远端任务的最终结果
+type Result struct {
+	RemoteID string
+	Content  string
+	Model    string
+	Usage    report.Usage
+	Err      error
+}
@@ -0,0 +1,337 @@
+// Package cassette 为 provider 客户端提供录制/回放 HTTP 流量的 http.RoundTripper。
+//
+// 录制模式下真实请求照常发出，请求和响应（去掉鉴权信息）按顺序追加写入 cassette 文件；
+// 回放模式下不访问网络，按 method + URL 从 cassette 中依次取出录制的响应，
+// 用于在本地复现线上 provider 返回的异常结构。
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+// Redacted 替换被清除的鉴权信息
+const Redacted = "REDACTED"
+
+var (
+	ErrInvalidMode   = errors.New("cassette mode must be record or replay")
+	ErrNoInteraction = errors.New("cassette: no recorded interaction")
+)
+
+// 需要清除的请求/响应头和 URL 查询参数（均不区分大小写）
+var (
+	sensitiveHeaders = []string{
+		"Authorization", "Proxy-Authorization", "X-Goog-Api-Key", "Cookie", "Set-Cookie",
+	}
+	sensitiveParams = []string{
+		"key", "token", "access_token", "signature", "ossaccesskeyid",
+		"x-amz-signature", "x-amz-credential", "x-amz-security-token",
+	}
+)
+
+// Interaction 一次请求与对应的响应
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body 文本内容原样保存便于阅读和手工修改，二进制内容（PDF、ZIP）以 base64 保存
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(map[string]string{"text": string(b)})
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Text   string `json:"text"`
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Base64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(raw.Base64)
+		if err != nil {
+			return err
+		}
+		*b = decoded
+		return nil
+	}
+	*b = []byte(raw.Text)
+	return nil
+}
+
+// Cassette 文件内容
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Load 读取 cassette 文件
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// cassetteTail 录制文件的结尾，每条新记录从这里覆盖写入后重新补上，文件在任意时刻都是完整的 JSON
+const cassetteTail = "\n  ]\n}\n"
+
+// create 创建只含空列表的录制文件，返回写入的字节数
+func create(path string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	data := []byte("{\n  \"interactions\": [" + cassetteTail)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// appendInteraction 覆盖文件结尾写入一条记录，返回新的文件大小；first 为 true 时不加逗号
+func appendInteraction(path string, size int64, first bool, interaction Interaction) (int64, error) {
+	data, err := json.MarshalIndent(interaction, "    ", "  ")
+	if err != nil {
+		return size, err
+	}
+	var b bytes.Buffer
+	if !first {
+		b.WriteString(",")
+	}
+	b.WriteString("\n    ")
+	b.Write(data)
+	b.WriteString(cassetteTail)
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return size, err
+	}
+	defer file.Close()
+	offset := size - int64(len(cassetteTail))
+	if _, err := file.WriteAt(b.Bytes(), offset); err != nil {
+		return size, err
+	}
+	return offset + int64(b.Len()), nil
+}
+
+// Transport 录制或回放 HTTP 流量，可被多个客户端共享，并发安全
+type Transport struct {
+	mode string
+	path string
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette *Cassette // 回放模式下加载的记录
+	used     []bool
+	size     int64 // 录制模式下已写入的文件大小
+	recorded int   // 录制模式下已写入的记录数
+}
+
+// New 创建 Transport。record 模式下 next 为空时使用 http.DefaultTransport，已有的 cassette 文件会被覆盖；
+// replay 模式下 cassette 文件必须存在
+func New(mode, path string, next http.RoundTripper) (*Transport, error) {
+	t := &Transport{mode: mode, path: path, next: next}
+	switch mode {
+	case ModeRecord:
+		if t.next == nil {
+			t.next = http.DefaultTransport
+		}
+		size, err := create(path)
+		if err != nil {
+			return nil, fmt.Errorf("create cassette: %w", err)
+		}
+		t.size = size
+	case ModeReplay:
+		c, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		t.cassette = c
+		t.used = make([]bool, len(c.Interactions))
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+	return t, nil
+}
+
+// Mode 返回 record 或 replay
+func (t *Transport) Mode() string {
+	return t.mode
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if t.mode == ModeReplay {
+		return t.replay(req, body)
+	}
+	return t.record(req, body)
+}
+
+func (t *Transport) record(req *http.Request, reqBody []byte) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// 流式响应（如 Gemini SSE）在录制时会被整体读完再交给调用方
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     scrubURL(req.URL),
+			Headers: scrubHeaders(req.Header),
+			Body:    reqBody,
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Headers:    scrubHeaders(resp.Header),
+			Body:       respBody,
+		},
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// 写入失败只影响录制，不影响真实请求
+	size, err := appendInteraction(t.path, t.size, t.recorded == 0, interaction)
+	if err != nil {
+		log.Printf("[cassette] append interaction failed path=%s url=%s err=%v", t.path, interaction.Request.URL, err)
+		return resp, nil
+	}
+	t.size = size
+	t.recorded++
+	return resp, nil
+}
+
+// replay 按录制顺序取第一个未使用的同 method + URL 记录，请求体完全相同的记录优先；
+// 同一 URL 的记录用完后重复返回最后一条（如轮询次数多于录制时）
+func (t *Transport) replay(req *http.Request, reqBody []byte) (*http.Response, error) {
+	target := scrubURL(req.URL)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	match, last := -1, -1
+	for i, interaction := range t.cassette.Interactions {
+		if interaction.Request.Method != req.Method || interaction.Request.URL != target {
+			continue
+		}
+		last = i
+		if t.used[i] {
+			continue
+		}
+		if bytes.Equal(interaction.Request.Body, reqBody) {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		match = last
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w for %s %s", ErrNoInteraction, req.Method, target)
+	}
+	t.used[match] = true
+
+	recorded := t.cassette.Interactions[match].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Headers.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readBody 读出并还原 body，使请求/响应仍可被正常使用
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+func scrubHeaders(headers http.Header) http.Header {
+	scrubbed := headers.Clone()
+	for _, name := range sensitiveHeaders {
+		if scrubbed.Get(name) != "" {
+			scrubbed.Set(name, Redacted)
+		}
+	}
+	return scrubbed
+}
+
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	query := scrubbed.Query()
+	changed := false
+	for name := range query {
+		for _, sensitive := range sensitiveParams {
+			if strings.EqualFold(name, sensitive) {
+				query.Set(name, Redacted)
+				changed = true
+			}
+		}
+	}
+	if changed {
+		scrubbed.RawQuery = query.Encode()
+	}
+	return scrubbed.String()
+}
@@ -0,0 +1,91 @@
+package cassette
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, `{"state":"poll-%d"}`, calls)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "mineru.json")
+	recorder, err := New(ModeRecord, path, nil)
+	if err != nil {
+		t.Fatalf("New record: %v", err)
+	}
+	if empty, err := Load(path); err != nil || len(empty.Interactions) != 0 {
+		t.Fatalf("new cassette should be valid and empty: %v", err)
+	}
+	client := &http.Client{Transport: recorder}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v4/extract/task/1?key=secret-key", nil)
+		req.Header.Set("Authorization", "Bearer secret-token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("record request: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if recorded, err := Load(path); err != nil || len(recorded.Interactions) != 2 {
+		t.Fatalf("expected 2 recorded interactions: %v", err)
+	}
+	if strings.Contains(string(data), "secret-token") || strings.Contains(string(data), "secret-key") {
+		t.Fatalf("cassette leaks credentials: %s", data)
+	}
+
+	player, err := New(ModeReplay, path, nil)
+	if err != nil {
+		t.Fatalf("New replay: %v", err)
+	}
+	client = &http.Client{Transport: player}
+	for _, want := range []string{"poll-1", "poll-2", "poll-2"} {
+		resp, err := client.Get(server.URL + "/api/v4/extract/task/1?key=other-key")
+		if err != nil {
+			t.Fatalf("replay request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("replay body = %s, want %s", body, want)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("replay must not hit the server, calls = %d", calls)
+	}
+
+	if _, err := client.Get(server.URL + "/api/v4/extract/task/2"); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("expected ErrNoInteraction, got %v", err)
+	}
+}
+
+func TestBinaryBodyRoundTrip(t *testing.T) {
+	body := Body{0x50, 0x4b, 0x03, 0x04, 0xff, 0xfe}
+	data, err := body.MarshalJSON()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded Body
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if string(decoded) != string(body) {
+		t.Fatalf("decoded = %v, want %v", decoded, body)
+	}
+}
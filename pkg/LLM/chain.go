@@ -0,0 +1,246 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"time"
+
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	ratelimit "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/ratelimit"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// namedProcessor 回退链中的一个成员
+type namedProcessor struct {
+	name      string
+	processor PDFProcessor
+}
+
+// supports 判断成员能否处理 opts：结构化抽取只有 gemini 支持
+func (m namedProcessor) supports(opts ProcessOptions) bool {
+	return len(opts.ResponseSchema) == 0 || m.name == "gemini"
+}
+
+// ErrNoCapableProvider 回退链中没有能处理本次参数的成员
+var ErrNoCapableProvider = errors.New("no provider in the chain supports the requested options")
+
+// ChainProcessor 按顺序尝试多个 provider：前一个返回配额错误或不可重试错误时切换到下一个，
+// 临时性错误（网络、5xx）直接返回，交给 worker 的重试逻辑处理。
+// ctx 中带有 ratelimit.Gate 时，调用每个成员前等待该成员的限流器；
+// 带有 Guard 时跳过其不放行的成员（如熔断器打开），并把每个成员的调用结果交给 Guard。
+type ChainProcessor struct {
+	members []namedProcessor
+}
+
+func newChainProcessor(configs []Config, pools *KeyPoolLease) (*ChainProcessor, error) {
+	members := make([]namedProcessor, 0, len(configs))
+	for _, cfg := range configs {
+		processor, err := buildProcessor(cfg, pools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s processor: %w", cfg.Provider, err)
+		}
+		members = append(members, namedProcessor{name: cfg.Provider, processor: processor})
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("empty provider chain")
+	}
+	return &ChainProcessor{members: members}, nil
+}
+
+// ProcessPDF 实现 PDFProcessor 接口，成功的 provider 写入 ctx 中的 report.Report
+// opts.Provider 指定首选 provider 时先尝试它，其余成员保持原顺序作为回退；不能处理 opts 的成员直接跳过
+func (c *ChainProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts ProcessOptions) (string, error) {
+	members := make([]namedProcessor, 0, len(c.members))
+	for _, member := range c.ordered(opts.Provider) {
+		if member.supports(opts) {
+			members = append(members, member)
+		}
+	}
+	if len(members) == 0 {
+		return "", llmerr.Permanent(ErrNoCapableProvider)
+	}
+
+	gate := ratelimit.GateFrom(ctx)
+	errs := make([]error, 0, len(members))
+	var skipped []error
+	for i, member := range members {
+		done, wait, ok := guardAllow(ctx, member.name)
+		if !ok {
+			skipped = append(skipped, unavailable(member.name, wait))
+			continue
+		}
+		settle, err := gate.Acquire(ctx, member.name)
+		if err != nil {
+			done(err)
+			return "", err
+		}
+		before := totalTokens(ctx)
+		content, err := member.processor.ProcessPDF(ctx, pdfPath, opts)
+		settle(totalTokens(ctx) - before)
+		done(err)
+		if err == nil {
+			if r := report.From(ctx); r != nil {
+				r.Provider = member.name
+			}
+			return content, nil
+		}
+		err = fmt.Errorf("%s: %w", member.name, err)
+		if ctx.Err() != nil || !shouldFallback(err) {
+			return "", err
+		}
+		errs = append(errs, err)
+		if i+1 < len(members) {
+			// 丢弃上一个 provider 的半成品预览
+			if stream := report.StreamFrom(ctx); stream != nil {
+				stream.Reset()
+			}
+			log.Printf("[llm] provider fallback from=%s to=%s path=%s err=%v", member.name, members[i+1].name, pdfPath, err)
+		}
+	}
+	return "", chainError(errs, skipped)
+}
+
+// GenerateText 实现 TextGenerator 接口：按顺序尝试支持纯文本调用的成员，回退规则与 ProcessPDF 相同
+func (c *ChainProcessor) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	gate := ratelimit.GateFrom(ctx)
+	var errs, skipped []error
+	for _, member := range c.members {
+		generator, ok := member.processor.(TextGenerator)
+		if !ok {
+			continue
+		}
+		done, wait, ok := guardAllow(ctx, member.name)
+		if !ok {
+			skipped = append(skipped, unavailable(member.name, wait))
+			continue
+		}
+		settle, err := gate.Acquire(ctx, member.name)
+		if err != nil {
+			done(err)
+			return "", err
+		}
+		before := totalTokens(ctx)
+		content, err := generator.GenerateText(ctx, instruction, input)
+		settle(totalTokens(ctx) - before)
+		done(err)
+		if err == nil {
+			if r := report.From(ctx); r != nil {
+				r.Provider = member.name
+			}
+			return content, nil
+		}
+		err = fmt.Errorf("%s: %w", member.name, err)
+		if ctx.Err() != nil || !shouldFallback(err) {
+			return "", err
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 && len(skipped) == 0 {
+		return "", llmerr.Permanent(ErrTextUnsupported)
+	}
+	return "", chainError(errs, skipped)
+}
+
+// Providers 返回能处理 opts 的成员的 provider 名字，按 ProcessPDF 尝试的顺序
+func (c *ChainProcessor) Providers(opts ProcessOptions) []string {
+	names := make([]string, 0, len(c.members))
+	for _, member := range c.ordered(opts.Provider) {
+		if member.supports(opts) {
+			names = append(names, member.name)
+		}
+	}
+	return names
+}
+
+// KeyLimiters 合并成员中 key 池的单 key 限流器
+func (c *ChainProcessor) KeyLimiters() map[string]*ratelimit.Limiter {
+	limiters := make(map[string]*ratelimit.Limiter)
+	for _, member := range c.members {
+		if pool, ok := member.processor.(*KeyPoolProcessor); ok {
+			maps.Copy(limiters, pool.KeyLimiters())
+		}
+	}
+	return limiters
+}
+
+// unavailable 被 Guard 跳过的成员按限流处理，等待时间为其最早可能放行的时间
+func unavailable(provider string, wait time.Duration) error {
+	return llmerr.RateLimited(fmt.Errorf("%s: %w", provider, ErrProviderUnavailable), wait)
+}
+
+// chainError 所有成员都失败时的错误：有成员实际调用过时按 mostRetryable 选择，
+// 全部被 Guard 跳过时返回最早可能放行的成员的 ErrProviderUnavailable
+func chainError(errs, skipped []error) error {
+	if len(errs) > 0 {
+		return mostRetryable(errs)
+	}
+	best := skipped[0]
+	for _, err := range skipped[1:] {
+		if llmerr.RetryAfter(err) < llmerr.RetryAfter(best) {
+			best = err
+		}
+	}
+	return best
+}
+
+// totalTokens 返回 ctx 中 report 已累计的 token，用于计算单个成员的实际用量
+func totalTokens(ctx context.Context) int64 {
+	if r := report.From(ctx); r != nil {
+		return r.Usage.TotalTokens
+	}
+	return 0
+}
+
+// retryRank 错误分类按可重试程度排序，越小越值得 worker 重试
+var retryRank = map[llmerr.Class]int{
+	llmerr.ClassRetryable:      0,
+	llmerr.ClassRateLimited:    1,
+	llmerr.ClassContentBlocked: 2,
+	llmerr.ClassPermanent:      3,
+}
+
+// mostRetryable 所有成员都失败时返回最值得重试的错误（同类取先尝试的成员），
+// 避免后面成员的不可重试错误（如不支持的参数）掩盖前面成员的限流
+func mostRetryable(errs []error) error {
+	best := errs[0]
+	for _, err := range errs[1:] {
+		if retryRank[llmerr.ClassOf(err)] < retryRank[llmerr.ClassOf(best)] {
+			best = err
+		}
+	}
+	return best
+}
+
+// ordered 返回把 preferred 提到最前的成员列表，preferred 为空或不在链中时返回原顺序
+func (c *ChainProcessor) ordered(preferred string) []namedProcessor {
+	if preferred == "" || c.members[0].name == preferred {
+		return c.members
+	}
+	ordered := make([]namedProcessor, 0, len(c.members))
+	for _, member := range c.members {
+		if member.name == preferred {
+			ordered = append(ordered, member)
+		}
+	}
+	if len(ordered) == 0 {
+		return c.members
+	}
+	for _, member := range c.members {
+		if member.name != preferred {
+			ordered = append(ordered, member)
+		}
+	}
+	return ordered
+}
+
+// shouldFallback 判断错误是否应切换到下一个 provider：配额/限流或不可重试错误。
+// provider 已把接口错误映射为 llmerr 分类，只有临时错误（含未分类错误）留给 worker 重试
+func shouldFallback(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return llmerr.ClassOf(err) != llmerr.ClassRetryable
+}
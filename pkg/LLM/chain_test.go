@@ -0,0 +1,215 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mineru "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/MinerU"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	ratelimit "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/ratelimit"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	"google.golang.org/genai"
+)
+
+type stubProcessor struct {
+	content string
+	err     error
+	calls   int
+}
+
+func (p *stubProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts ProcessOptions) (string, error) {
+	p.calls++
+	return p.content, p.err
+}
+
+func TestChainProcessor_FallbackOnQuota(t *testing.T) {
+	first := &stubProcessor{err: llmerr.RateLimited(genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"}, 0)}
+	second := &stubProcessor{content: "# ok"}
+	chain := &ChainProcessor{members: []namedProcessor{
+		{name: "gemini", processor: first},
+		{name: "mineru", processor: second},
+	}}
+
+	ctx, callReport := report.New(context.Background())
+	content, err := chain.ProcessPDF(ctx, "output/x/a.pdf", ProcessOptions{})
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if content != "# ok" {
+		t.Fatalf("unexpected content: %q", content)
+	}
+	if callReport.Provider != "mineru" {
+		t.Fatalf("expected provider mineru, got %q", callReport.Provider)
+	}
+}
+
+func TestChainProcessor_GateLimitsFallbackMember(t *testing.T) {
+	first := &stubProcessor{err: llmerr.RateLimited(genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"}, 0)}
+	second := &stubProcessor{content: "# ok"}
+	chain := &ChainProcessor{members: []namedProcessor{
+		{name: "gemini", processor: first},
+		{name: "mineru", processor: second},
+	}}
+	limiters := map[string]*ratelimit.Limiter{
+		"gemini": ratelimit.New(ratelimit.Limits{RPM: 10}),
+		"mineru": ratelimit.New(ratelimit.Limits{RPM: 10}),
+	}
+
+	ctx := ratelimit.WithGate(context.Background(), ratelimit.NewGate(limiters, 0))
+	if _, err := chain.ProcessPDF(ctx, "output/x/a.pdf", ProcessOptions{}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	for name, limiter := range limiters {
+		if limiter.Delay(0) == 0 {
+			t.Fatalf("expected %s limiter to be consumed", name)
+		}
+	}
+}
+
+func TestChainProcessor_TransientErrorDoesNotFallback(t *testing.T) {
+	first := &stubProcessor{err: llmerr.Retryable(genai.APIError{Code: 503, Status: "UNAVAILABLE"})}
+	second := &stubProcessor{content: "# ok"}
+	chain := &ChainProcessor{members: []namedProcessor{
+		{name: "gemini", processor: first},
+		{name: "mineru", processor: second},
+	}}
+
+	_, err := chain.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if second.calls != 0 {
+		t.Fatalf("transient error should not fall back, second called %d times", second.calls)
+	}
+}
+
+func TestChainProcessor_PreferredProviderFirst(t *testing.T) {
+	first := &stubProcessor{content: "# gemini"}
+	second := &stubProcessor{content: "# mineru"}
+	chain := &ChainProcessor{members: []namedProcessor{
+		{name: "gemini", processor: first},
+		{name: "mineru", processor: second},
+	}}
+
+	ctx, callReport := report.New(context.Background())
+	content, err := chain.ProcessPDF(ctx, "output/x/a.pdf", ProcessOptions{Provider: "mineru"})
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if content != "# mineru" || callReport.Provider != "mineru" || first.calls != 0 {
+		t.Fatalf("preferred provider not used first: content=%q provider=%s gemini_calls=%d", content, callReport.Provider, first.calls)
+	}
+
+	if _, err := chain.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{Provider: "unknown"}); err != nil {
+		t.Fatalf("unknown preferred provider should keep configured order: %v", err)
+	}
+	if first.calls != 1 {
+		t.Fatalf("expected gemini to be tried first for unknown preference, calls=%d", first.calls)
+	}
+}
+
+func TestShouldFallback(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"gemini bad request", llmerr.Permanent(genai.APIError{Code: 400}), true},
+		{"gemini server error", llmerr.Retryable(genai.APIError{Code: 500}), false},
+		{"mineru daily limit", llmerr.RateLimited(&mineru.APIError{Code: mineru.CodeDailyTaskLimit}, 0), true},
+		{"mineru service error", llmerr.Retryable(&mineru.APIError{Code: mineru.CodeServiceError}), false},
+		{"no available key", llmerr.RateLimited(ErrNoAvailableKey, time.Second), true},
+		{"context canceled", context.Canceled, false},
+		{"plain error", errors.New("boom"), false},
+	}
+	for _, tc := range cases {
+		if got := shouldFallback(tc.err); got != tc.want {
+			t.Errorf("%s: shouldFallback=%v want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestChainProcessor_SchemaSkipsIncapableMembers(t *testing.T) {
+	gemini := &stubProcessor{err: llmerr.RateLimited(errors.New("quota"), 5*time.Second)}
+	mineru := &stubProcessor{content: "# markdown"}
+	chain := &ChainProcessor{members: []namedProcessor{
+		{name: "gemini", processor: gemini},
+		{name: "mineru", processor: mineru},
+	}}
+
+	_, err := chain.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{ResponseSchema: []byte(`{"type":"object"}`)})
+	if mineru.calls != 0 {
+		t.Fatalf("mineru cannot serve schema tasks, called %d times", mineru.calls)
+	}
+	if llmerr.RetryAfter(err) != 5*time.Second {
+		t.Fatalf("expected gemini rate limit to surface, got %v", err)
+	}
+
+	onlyMinerU := &ChainProcessor{members: []namedProcessor{{name: "mineru", processor: mineru}}}
+	if _, err := onlyMinerU.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{ResponseSchema: []byte(`{"type":"object"}`)}); !errors.Is(err, ErrNoCapableProvider) || !llmerr.Fatal(err) {
+		t.Fatalf("expected permanent ErrNoCapableProvider, got %v", err)
+	}
+}
+
+func TestChainProcessor_ReturnsMostRetryableError(t *testing.T) {
+	first := &stubProcessor{err: llmerr.RateLimited(errors.New("quota"), time.Second)}
+	second := &stubProcessor{err: llmerr.Permanent(errors.New("unsupported"))}
+	chain := &ChainProcessor{members: []namedProcessor{
+		{name: "gemini", processor: first},
+		{name: "mineru", processor: second},
+	}}
+
+	_, err := chain.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{})
+	if second.calls != 1 {
+		t.Fatalf("expected fallback to mineru, calls=%d", second.calls)
+	}
+	if llmerr.ClassOf(err) != llmerr.ClassRateLimited || llmerr.RetryAfter(err) != time.Second {
+		t.Fatalf("expected the rate limit error, got class=%s err=%v", llmerr.ClassOf(err), err)
+	}
+}
+
+// stubGuard 拒绝 closed 中的成员，记录放行成员的调用结果
+type stubGuard struct {
+	closed  map[string]time.Duration
+	results map[string]error
+}
+
+func (g *stubGuard) Allow(provider string) (func(error), time.Duration, bool) {
+	if wait, ok := g.closed[provider]; ok {
+		return nil, wait, false
+	}
+	return func(err error) { g.results[provider] = err }, 0, true
+}
+
+func TestChainProcessor_GuardSkipsUnavailableMember(t *testing.T) {
+	first := &stubProcessor{content: "# gemini"}
+	second := &stubProcessor{content: "# mineru"}
+	chain := &ChainProcessor{members: []namedProcessor{
+		{name: "gemini", processor: first},
+		{name: "mineru", processor: second},
+	}}
+	guard := &stubGuard{closed: map[string]time.Duration{"gemini": time.Minute}, results: make(map[string]error)}
+
+	content, err := chain.ProcessPDF(WithGuard(context.Background(), guard), "output/x/a.pdf", ProcessOptions{})
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if content != "# mineru" || first.calls != 0 {
+		t.Fatalf("expected unavailable gemini to be skipped, content=%q gemini calls=%d", content, first.calls)
+	}
+	if result, ok := guard.results["mineru"]; !ok || result != nil {
+		t.Fatalf("expected mineru success to be reported, got %v", guard.results)
+	}
+
+	// 所有成员都不可用时返回最早可能放行的等待时间
+	guard.closed["mineru"] = time.Second
+	_, err = chain.ProcessPDF(WithGuard(context.Background(), guard), "output/x/a.pdf", ProcessOptions{})
+	if !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("expected ErrProviderUnavailable, got %v", err)
+	}
+	if wait := llmerr.RetryAfter(err); wait != time.Second {
+		t.Fatalf("retry after = %s, want 1s", wait)
+	}
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// choiceNamePattern 模型选项名字，作为表单取值和熔断器名字的一部分
+var choiceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ModelChoice 运营方允许任务选择的 provider + 模型组合，来自 LLM_MODEL_CHOICES。
+// 任务未选择时使用顶层配置（LLM_PROVIDER）。
+type ModelChoice struct {
+	Name        string   `json:"name"`                  // 创建任务时传入的名字，如 "draft"、"contract"
+	Provider    string   `json:"provider"`              // gemini / mineru / mock / replay
+	Model       string   `json:"model,omitempty"`       // 覆盖该 provider 的默认模型，仅 gemini 使用
+	Description string   `json:"description,omitempty"` // 展示给用户的说明
+	Tiers       []string `json:"tiers,omitempty"`       // 允许使用的用户等级（guest / user），为空时不限制
+
+	Config Config `json:"-"` // 该选项的 provider 配置，创建 processor 用
+}
+
+// Allows 判断用户等级是否可以使用该选项
+func (c ModelChoice) Allows(tier string) bool {
+	return len(c.Tiers) == 0 || slices.Contains(c.Tiers, tier)
+}
+
+// Choice 按名字查找模型选项
+func (c Config) Choice(name string) (ModelChoice, bool) {
+	for _, choice := range c.Choices {
+		if choice.Name == name {
+			return choice, true
+		}
+	}
+	return ModelChoice{}, false
+}
+
+// loadModelChoices 读取 LLM_MODEL_CHOICES_PATH 指向的文件或 LLM_MODEL_CHOICES 中的 JSON 数组；
+// 选项的 provider 已在 LLM_PROVIDER 中时沿用该 provider 的配置，否则按该 provider 的环境变量加载
+func loadModelChoices(base Config) ([]ModelChoice, error) {
+	var raw []byte
+	if path := strings.TrimSpace(os.Getenv("LLM_MODEL_CHOICES_PATH")); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read model choices: %w", err)
+		}
+		raw = content
+	} else if inline := strings.TrimSpace(os.Getenv("LLM_MODEL_CHOICES")); inline != "" {
+		raw = []byte(inline)
+	} else {
+		return nil, nil
+	}
+
+	choices, err := ParseModelChoices(raw)
+	if err != nil {
+		return nil, err
+	}
+	for i := range choices {
+		cfg, ok := base.member(choices[i].Provider)
+		if !ok {
+			if cfg, err = loadProviderConfig(choices[i].Provider); err != nil {
+				return nil, fmt.Errorf("model choice %s: %w", choices[i].Name, err)
+			}
+		}
+		if choices[i].Model != "" {
+			cfg.Model = choices[i].Model
+		}
+		choices[i].Config = cfg
+	}
+	return choices, nil
+}
+
+// ParseModelChoices 解析并校验模型选项：[{"name": "draft", "provider": "gemini", "model": "...", "tiers": ["guest", "user"]}]
+func ParseModelChoices(raw []byte) ([]ModelChoice, error) {
+	var choices []ModelChoice
+	if err := json.Unmarshal(raw, &choices); err != nil {
+		return nil, fmt.Errorf("parse model choices: %w", err)
+	}
+	seen := make(map[string]bool, len(choices))
+	for i := range choices {
+		choice := &choices[i]
+		choice.Name = strings.ToLower(strings.TrimSpace(choice.Name))
+		choice.Provider = strings.ToLower(strings.TrimSpace(choice.Provider))
+		choice.Model = strings.TrimSpace(choice.Model)
+		if !choiceNamePattern.MatchString(choice.Name) {
+			return nil, fmt.Errorf("parse model choices: invalid name %q", choice.Name)
+		}
+		if seen[choice.Name] {
+			return nil, fmt.Errorf("parse model choices: duplicate name %s", choice.Name)
+		}
+		seen[choice.Name] = true
+		if choice.Provider == "" {
+			return nil, fmt.Errorf("parse model choices: missing provider for %s", choice.Name)
+		}
+		for j, tier := range choice.Tiers {
+			choice.Tiers[j] = strings.ToLower(strings.TrimSpace(tier))
+		}
+	}
+	return choices, nil
+}
+
+// member 返回配置（含回退链）中指定 provider 的配置
+func (c Config) member(provider string) (Config, bool) {
+	if len(c.Chain) == 0 {
+		return c, c.Provider == provider
+	}
+	for _, member := range c.Chain {
+		if member.Provider == provider {
+			return member, true
+		}
+	}
+	return Config{}, false
+}
@@ -0,0 +1,35 @@
+package llm
+
+import "testing"
+
+func TestLoadModelChoicesReusesProviderConfig(t *testing.T) {
+	t.Setenv("LLM_MODEL_CHOICES", `[
+		{"name": "Draft", "provider": "gemini", "tiers": ["guest", "user"]},
+		{"name": "contract", "provider": "gemini", "model": "gemini-3-pro-preview", "tiers": ["user"]}
+	]`)
+	base := Config{Provider: "gemini", APIKey: "key", Model: "gemini-3-flash-preview"}
+
+	choices, err := loadModelChoices(base)
+	if err != nil {
+		t.Fatalf("loadModelChoices: %v", err)
+	}
+	if len(choices) != 2 || choices[0].Name != "draft" {
+		t.Fatalf("unexpected choices: %+v", choices)
+	}
+	if choices[0].Config.Model != "gemini-3-flash-preview" || choices[1].Config.Model != "gemini-3-pro-preview" {
+		t.Fatalf("models = %q, %q", choices[0].Config.Model, choices[1].Config.Model)
+	}
+	if choices[1].Config.APIKey != "key" {
+		t.Fatalf("choice should reuse the gemini config from LLM_PROVIDER")
+	}
+	if choices[1].Allows("guest") || !choices[1].Allows("user") {
+		t.Fatalf("contract should only be allowed for user tier")
+	}
+}
+
+func TestParseModelChoicesRejectsDuplicates(t *testing.T) {
+	_, err := ParseModelChoices([]byte(`[{"name": "a", "provider": "mock"}, {"name": "A", "provider": "gemini"}]`))
+	if err == nil {
+		t.Fatalf("expected duplicate name error")
+	}
+}
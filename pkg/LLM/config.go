@@ -2,26 +2,242 @@ package llm
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	mineru "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/MinerU"
+	cassette "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/cassette"
+	mock "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/mock"
+	pricing "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/pricing"
+	ratelimit "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/ratelimit"
 )
 
 type Config struct {
-	Provider  string // "gemini" or "mineru"
+	Provider  string // "gemini" / "mineru" / "mock" / "replay"；多 provider 链式回退时为逗号拼接的名字，如 "gemini,mineru"
 	APIKey    string
-	BaseURL   string // Optional, MinerU API 地址
-	Model     string // Optional, 如 "gemini-3-flash-preview"
-	PublicURL string // Optional, 本服务公开地址，将PDF暴露给LLM API提供商 需要
+	BaseURL   string   // Optional, MinerU API 地址
+	Model     string   // Optional, 如 "gemini-3-flash-preview"
+	PublicURL string   // Optional, 本服务公开地址，将PDF暴露给LLM API提供商 需要
+	Chain     []Config // Optional, 按顺序回退的 provider 列表，非空时 Provider 字段仅用于展示
+
+	// Gemini 多 key 轮换（KeyStorePath 非空时启用，APIKey 不再必填）
+	KeyStorePath string        // keystore SQLite 路径
+	KeyStrategy  string        // round_robin / least_used
+	KeyCooldown  time.Duration // 429 后 key 的冷却时间
+
+	// MinerU 回调模式（MINERU_CALLBACK_SEED 非空时启用），结果由 MinerU 推送而非轮询
+	Callbacks *mineru.Callbacks
+
+	// 非 nil 时 provider 客户端经它收发 HTTP 请求，用于录制/回放真实流量（见 cassette 包）
+	Transport http.RoundTripper
+
+	// 限流：RateLimit 为该 provider 所有调用共享，KeyRateLimit 为 Gemini key 池中每个 key 单独的限制
+	RateLimit     ratelimit.Limits
+	KeyRateLimit  ratelimit.Limits
+	TokensPerPage int // 预估 TPM 用的每页 token 数
+
+	// 离线 provider：mock 按页码生成确定性输出，replay 从 fixtures 目录回放
+	Mock        mock.Config
+	FixturesDir string // replay 的 fixtures 目录
+
+	// 任务可选的 provider + 模型组合（LLM_MODEL_CHOICES），仅顶层配置使用
+	Choices []ModelChoice
+
+	// 按模型计费的价目表，仅顶层配置使用
+	Prices pricing.Table
+	// 非空时把真实 provider 的输出录制为 replay fixtures，仅顶层配置使用
+	RecordFixturesDir string
+}
+
+// Providers 返回配置中涉及的所有 provider 名字（按回退顺序）
+func (c Config) Providers() []string {
+	if len(c.Chain) == 0 {
+		return []string{c.Provider}
+	}
+	names := make([]string, 0, len(c.Chain))
+	for _, member := range c.Chain {
+		names = append(names, member.Provider)
+	}
+	return names
+}
+
+// UsesProvider 判断配置（含回退链）中是否包含指定 provider
+func (c Config) UsesProvider(name string) bool {
+	for _, provider := range c.Providers() {
+		if provider == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MinerUCallbacks 返回配置（含回退链）中 mineru 的回调接收器，未启用回调模式时为 nil
+func (c Config) MinerUCallbacks() *mineru.Callbacks {
+	if c.Callbacks != nil {
+		return c.Callbacks
+	}
+	for _, member := range c.Chain {
+		if member.Callbacks != nil {
+			return member.Callbacks
+		}
+	}
+	return nil
+}
+
+// CallbackReceivers 返回顶层配置和模型选项中所有 MinerU 回调接收器（去重），未启用回调模式时为空
+func (c Config) CallbackReceivers() []*mineru.Callbacks {
+	var receivers []*mineru.Callbacks
+	add := func(callbacks *mineru.Callbacks) {
+		if callbacks != nil && !slices.Contains(receivers, callbacks) {
+			receivers = append(receivers, callbacks)
+		}
+	}
+	add(c.MinerUCallbacks())
+	for _, choice := range c.Choices {
+		add(choice.Config.MinerUCallbacks())
+	}
+	return receivers
 }
 
 // LoadConfigFromEnv 从环境变量加载配置
+// LLM_PROVIDER 支持逗号分隔的有序列表（如 "gemini,mineru"），前一个 provider 配额耗尽或返回不可重试错误时回退到下一个。
 func LoadConfigFromEnv() (Config, error) {
-	provider := strings.TrimSpace(os.Getenv("LLM_PROVIDER"))
-	if provider == "" {
-		provider = "gemini" // 默认使用 gemini
+	cfg, err := loadProvidersFromEnv()
+	if err != nil {
+		return Config{}, err
+	}
+	if cfg.Choices, err = loadModelChoices(cfg); err != nil {
+		return Config{}, err
+	}
+	prices, err := pricing.LoadFromEnv()
+	if err != nil {
+		return Config{}, err
 	}
-	provider = strings.ToLower(provider)
+	cfg.Prices = prices
+	if err := applyTokensPerPage(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := applyCassette(&cfg); err != nil {
+		return Config{}, err
+	}
+	cfg.RecordFixturesDir = strings.TrimSpace(os.Getenv("LLM_RECORD_FIXTURES_DIR"))
+	return cfg, nil
+}
 
+// applyTokensPerPage 按 LLM_TOKENS_PER_PAGE 设置所有 provider 预估 token 的每页 token 数
+func applyTokensPerPage(cfg *Config) error {
+	perPage := ratelimit.DefaultTokensPerPage
+	if raw := strings.TrimSpace(os.Getenv("LLM_TOKENS_PER_PAGE")); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			return fmt.Errorf("invalid LLM_TOKENS_PER_PAGE: %s", raw)
+		}
+		perPage = value
+	}
+	cfg.TokensPerPage = perPage
+	for i := range cfg.Chain {
+		cfg.Chain[i].TokensPerPage = perPage
+	}
+	for i := range cfg.Choices {
+		cfg.Choices[i].Config.TokensPerPage = perPage
+	}
+	return nil
+}
+
+// loadRateLimits 读取 {prefix}_RPM / {prefix}_TPM，如 GEMINI_RPM、GEMINI_KEY_TPM
+func loadRateLimits(prefix string) (ratelimit.Limits, error) {
+	var limits ratelimit.Limits
+	for _, item := range []struct {
+		env    string
+		target *int
+	}{
+		{prefix + "_RPM", &limits.RPM},
+		{prefix + "_TPM", &limits.TPM},
+	} {
+		raw := strings.TrimSpace(os.Getenv(item.env))
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return ratelimit.Limits{}, fmt.Errorf("invalid %s: %s", item.env, raw)
+		}
+		*item.target = value
+	}
+	return limits, nil
+}
+
+// applyCassette 按 LLM_HTTP_CASSETTE / LLM_HTTP_CASSETTE_MODE 为所有 provider 设置录制或回放的 Transport
+func applyCassette(cfg *Config) error {
+	path := strings.TrimSpace(os.Getenv("LLM_HTTP_CASSETTE"))
+	if path == "" {
+		return nil
+	}
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_HTTP_CASSETTE_MODE")))
+	if mode == "" {
+		mode = cassette.ModeReplay
+	}
+	transport, err := cassette.New(mode, path, nil)
+	if err != nil {
+		return fmt.Errorf("LLM_HTTP_CASSETTE: %w", err)
+	}
+	cfg.Transport = transport
+	for i := range cfg.Chain {
+		cfg.Chain[i].Transport = transport
+	}
+	for i := range cfg.Choices {
+		cfg.Choices[i].Config.Transport = transport
+	}
+	return nil
+}
+
+func loadProvidersFromEnv() (Config, error) {
+	raw := strings.TrimSpace(os.Getenv("LLM_PROVIDER"))
+	if raw == "" {
+		raw = "gemini" // 默认使用 gemini
+	}
+
+	names := make([]string, 0, 2)
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			return Config{}, fmt.Errorf("duplicate provider in LLM_PROVIDER: %s", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return Config{}, fmt.Errorf("empty LLM_PROVIDER")
+	}
+
+	if len(names) == 1 {
+		return loadProviderConfig(names[0])
+	}
+
+	chain := make([]Config, 0, len(names))
+	for _, name := range names {
+		member, err := loadProviderConfig(name)
+		if err != nil {
+			return Config{}, err
+		}
+		chain = append(chain, member)
+	}
+	return Config{
+		Provider:  strings.Join(names, ","),
+		PublicURL: os.Getenv("PUBLIC_URL"),
+		Chain:     chain,
+	}, nil
+}
+
+func loadProviderConfig(provider string) (Config, error) {
 	cfg := Config{
 		Provider:  provider,
 		PublicURL: os.Getenv("PUBLIC_URL"),
@@ -34,7 +250,22 @@ func LoadConfigFromEnv() (Config, error) {
 		if cfg.Model == "" {
 			cfg.Model = "gemini-3-flash-preview"
 		}
-		if cfg.APIKey == "" {
+		cfg.KeyStorePath = strings.TrimSpace(os.Getenv("GEMINI_KEYSTORE_PATH"))
+		if cfg.KeyStorePath != "" {
+			cfg.KeyStrategy = strings.ToLower(strings.TrimSpace(os.Getenv("GEMINI_KEY_STRATEGY")))
+			switch cfg.KeyStrategy {
+			case "", KeyStrategyRoundRobin, KeyStrategyLeastUsed:
+			default:
+				return Config{}, fmt.Errorf("unknown GEMINI_KEY_STRATEGY: %s", cfg.KeyStrategy)
+			}
+			if raw := strings.TrimSpace(os.Getenv("GEMINI_KEY_COOLDOWN")); raw != "" {
+				cooldown, err := time.ParseDuration(raw)
+				if err != nil {
+					return Config{}, fmt.Errorf("invalid GEMINI_KEY_COOLDOWN: %w", err)
+				}
+				cfg.KeyCooldown = cooldown
+			}
+		} else if cfg.APIKey == "" {
 			return Config{}, fmt.Errorf("missing GEMINI_API_KEY for provider=gemini")
 		}
 	case "mineru":
@@ -43,12 +274,59 @@ func LoadConfigFromEnv() (Config, error) {
 		if cfg.APIKey == "" {
 			return Config{}, fmt.Errorf("missing MINERU_TOKEN for provider=mineru")
 		}
-		if strings.TrimSpace(cfg.PublicURL) == "" {
-			return Config{}, fmt.Errorf("missing PUBLIC_URL for provider=mineru")
+		// PUBLIC_URL 为空时 MinerU 走文件直传；回调模式需要 MinerU 能访问本服务
+		if seed := strings.TrimSpace(os.Getenv("MINERU_CALLBACK_SEED")); seed != "" {
+			if strings.TrimSpace(cfg.PublicURL) == "" {
+				return Config{}, fmt.Errorf("MINERU_CALLBACK_SEED requires PUBLIC_URL")
+			}
+			callbackURL := strings.TrimRight(cfg.PublicURL, "/") + mineru.CallbackPath
+			callbacks, err := mineru.NewCallbacks(callbackURL, os.Getenv("MINERU_UID"), seed)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.Callbacks = callbacks
+		}
+	case "mock":
+		if raw := strings.TrimSpace(os.Getenv("MOCK_LATENCY")); raw != "" {
+			latency, err := time.ParseDuration(raw)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid MOCK_LATENCY: %w", err)
+			}
+			cfg.Mock.Latency = latency
+		}
+		if raw := strings.TrimSpace(os.Getenv("MOCK_FAILURE_RATE")); raw != "" {
+			rate, err := strconv.ParseFloat(raw, 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return Config{}, fmt.Errorf("invalid MOCK_FAILURE_RATE: %s, want 0-1", raw)
+			}
+			cfg.Mock.FailureRate = rate
+		}
+		if raw := strings.TrimSpace(os.Getenv("MOCK_SEED")); raw != "" {
+			seed, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid MOCK_SEED: %w", err)
+			}
+			cfg.Mock.Seed = seed
+		}
+	case "replay":
+		cfg.FixturesDir = strings.TrimSpace(os.Getenv("REPLAY_FIXTURES_DIR"))
+		if cfg.FixturesDir == "" {
+			return Config{}, fmt.Errorf("missing REPLAY_FIXTURES_DIR for provider=replay")
 		}
 	default:
 		return Config{}, fmt.Errorf("unknown LLM_PROVIDER: %s", provider)
 	}
 
+	limits, err := loadRateLimits(strings.ToUpper(provider))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.RateLimit = limits
+	if provider == "gemini" && cfg.KeyStorePath != "" {
+		if cfg.KeyRateLimit, err = loadRateLimits("GEMINI_KEY"); err != nil {
+			return Config{}, err
+		}
+	}
+
 	return cfg, nil
 }
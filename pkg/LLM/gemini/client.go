@@ -3,16 +3,24 @@ package gemini
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	schema "github.com/neyuki778/LLM-PDF-OCR/pkg/schema"
 	"google.golang.org/genai"
 )
 
 const (
 	DefaultModel = "gemini-3-flash-preview"
 	Prompt       = "Extract the PDF content and convert it into a clean Markdown format. Output only the content of the PDF without any additional commentary or preamble. Maintain the original language of the document; do not translate."
+	// ExtractPrompt 结构化抽取模式的默认提示词，字段定义由 response schema 给出
+	ExtractPrompt = "Extract the information defined by the response schema from these PDF pages. Copy values exactly as printed and keep the original language; do not translate. Use null or an empty array for fields that do not appear on these pages. Output only JSON."
 )
 
 // Client 封装 Gemini API 客户端，实现 PDFProcessor 接口
@@ -24,12 +32,23 @@ type Client struct {
 
 // NewClient 创建 Gemini 客户端
 func NewClient(apiKey, model, publicURL string) (*Client, error) {
+	return NewClientWithTransport(apiKey, model, publicURL, nil)
+}
+
+// NewClientWithTransport 创建使用指定 http.RoundTripper 的客户端（如录制/回放流量的 cassette.Transport），
+// transport 为 nil 时与 NewClient 相同
+func NewClientWithTransport(apiKey, model, publicURL string, transport http.RoundTripper) (*Client, error) {
 	if model == "" {
 		model = DefaultModel
 	}
 
 	ctx := context.Background()
-	client, err := genai.NewClient(ctx, nil) // API key 从环境变量 GEMINI_API_KEY 读取
+	// apiKey 为空时 SDK 回退到环境变量 GEMINI_API_KEY
+	clientConfig := &genai.ClientConfig{APIKey: apiKey}
+	if transport != nil {
+		clientConfig.HTTPClient = &http.Client{Transport: transport}
+	}
+	client, err := genai.NewClient(ctx, clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create genai client: %w", err)
 	}
@@ -42,41 +61,101 @@ func NewClient(apiKey, model, publicURL string) (*Client, error) {
 }
 
 // ProcessPDF 实现 PDFProcessor 接口，读取本地 PDF 并调用 Gemini OCR
-func (c *Client) ProcessPDF(ctx context.Context, pdfPath string) (string, error) {
-	var pdfPart []*genai.Part
-	if url, ok := c.buildPublicURL(pdfPath); ok {
-		pdfPart = []*genai.Part{
-			genai.NewPartFromURI(url, "application/pdf"),
-			genai.NewPartFromText(Prompt),
-		}
-	} else {
-		// 回退为本地读取
-		pdfBytes, err := os.ReadFile(pdfPath)
+// opts.Prompt 非空时替换默认提示词
+func (c *Client) ProcessPDF(ctx context.Context, pdfPath string, opts options.Options) (string, error) {
+	prompt := Prompt
+	var (
+		config       *genai.GenerateContentConfig
+		outputSchema *schema.Schema
+	)
+	if len(opts.ResponseSchema) > 0 {
+		parsed, err := schema.Parse(opts.ResponseSchema)
 		if err != nil {
-			return "", fmt.Errorf("failed to read PDF file: %w", err)
+			return "", llmerr.Permanent(err)
 		}
-		pdfPart = []*genai.Part{
-			{
-				InlineData: &genai.Blob{
-					MIMEType: "application/pdf",
-					Data:     pdfBytes,
-				},
-			},
-			genai.NewPartFromText(Prompt),
+		outputSchema = parsed
+		prompt = ExtractPrompt
+		config = &genai.GenerateContentConfig{
+			ResponseMIMEType:   "application/json",
+			ResponseJsonSchema: parsed.Raw(),
 		}
 	}
+	if strings.TrimSpace(opts.Prompt) != "" {
+		prompt = opts.Prompt
+	}
+
+	// 公网 URL / 内联 / Files API 三选一，上传的文件在调用结束后删除
+	part, cleanup, err := c.pdfPart(ctx, pdfPath)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
 
 	contents := []*genai.Content{
-		genai.NewContentFromParts(pdfPart, genai.RoleUser),
+		genai.NewContentFromParts([]*genai.Part{part, genai.NewPartFromText(prompt)}, genai.RoleUser),
 	}
 
-	// 3. 调用 Gemini API
-	result, err := c.client.Models.GenerateContent(ctx, c.model, contents, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
+	// 3. 以流式接口调用 Gemini API，增量文本写入 ctx 中的 Stream（如有）
+	stream := report.StreamFrom(ctx)
+	if stream != nil {
+		stream.Reset()
 	}
 
-	return result.Text(), nil
+	var (
+		text  strings.Builder
+		chars int
+		usage *genai.GenerateContentResponseUsageMetadata
+	)
+	for chunk, err := range c.client.Models.GenerateContentStream(ctx, c.model, contents, config) {
+		if err != nil {
+			c.recordUsage(ctx, usage)
+			return "", classify(fmt.Errorf("failed to generate content: %w", err))
+		}
+		if chunk.UsageMetadata != nil {
+			// 每个分块携带截至当前的累计用量，取最后一个即可
+			usage = chunk.UsageMetadata
+		}
+		if err := checkBlocked(chunk); err != nil {
+			c.recordUsage(ctx, usage)
+			return "", err
+		}
+		piece := chunk.Text()
+		if piece == "" {
+			continue
+		}
+		text.WriteString(piece)
+		if stream != nil {
+			stream.Write(piece)
+		}
+		chars += utf8.RuneCountInString(piece)
+		report.SetProgress(ctx, report.Progress{Done: chars, Unit: "chars", State: "generating"})
+	}
+	c.recordUsage(ctx, usage)
+
+	// 结构化模式下校验输出，不符合 schema 时返回错误交给 worker 重试
+	if outputSchema != nil {
+		if _, err := outputSchema.ValidateJSON([]byte(text.String())); err != nil {
+			return "", fmt.Errorf("gemini output: %w", err)
+		}
+	}
+	return text.String(), nil
+}
+
+// recordUsage 把响应中的 token 用量累加到 ctx 携带的 report 中
+func (c *Client) recordUsage(ctx context.Context, usage *genai.GenerateContentResponseUsageMetadata) {
+	r := report.From(ctx)
+	if r == nil {
+		return
+	}
+	r.Model = c.model
+	if usage == nil {
+		return
+	}
+	r.Usage.Add(report.Usage{
+		PromptTokens:    int64(usage.PromptTokenCount),
+		CandidateTokens: int64(usage.CandidatesTokenCount),
+		TotalTokens:     int64(usage.TotalTokenCount),
+	})
 }
 
 func (c *Client) buildPublicURL(pdfPath string) (string, bool) {
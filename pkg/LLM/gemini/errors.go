@@ -0,0 +1,77 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	"google.golang.org/genai"
+)
+
+// ErrContentBlocked 提示词或输出被 Gemini 安全策略拦截
+var ErrContentBlocked = errors.New("gemini blocked the content")
+
+// ErrKeyRejected API key 无效或无权限（401 / 403），key 池据此禁用该 key
+var ErrKeyRejected = errors.New("gemini api key rejected")
+
+// blockedFinishReasons 因内容策略提前结束生成的 finish reason
+var blockedFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:            true,
+	genai.FinishReasonRecitation:        true,
+	genai.FinishReasonBlocklist:         true,
+	genai.FinishReasonProhibitedContent: true,
+	genai.FinishReasonSPII:              true,
+	genai.FinishReasonImageSafety:       true,
+}
+
+// classify 把 Gemini API 错误映射为 llmerr 分类：429 / RESOURCE_EXHAUSTED 为限流（带 RetryInfo 中的等待时间），
+// 408 和 5xx 为临时错误，其余 4xx 为不可重试错误（401 / 403 额外包装 ErrKeyRejected）
+func classify(err error) error {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests || strings.EqualFold(apiErr.Status, "RESOURCE_EXHAUSTED"):
+		return llmerr.RateLimited(err, retryDelay(apiErr))
+	case apiErr.Code == http.StatusRequestTimeout || apiErr.Code >= 500:
+		return llmerr.Retryable(err)
+	case apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden:
+		return llmerr.Permanent(fmt.Errorf("%w: %w", ErrKeyRejected, err))
+	case apiErr.Code >= 400:
+		return llmerr.Permanent(err)
+	}
+	return err
+}
+
+// retryDelay 读取错误详情中 google.rpc.RetryInfo 的 retryDelay（如 "12s"）
+func retryDelay(apiErr genai.APIError) time.Duration {
+	for _, detail := range apiErr.Details {
+		kind, _ := detail["@type"].(string)
+		if !strings.HasSuffix(kind, "RetryInfo") {
+			continue
+		}
+		raw, _ := detail["retryDelay"].(string)
+		if delay, err := time.ParseDuration(raw); err == nil {
+			return delay
+		}
+	}
+	return 0
+}
+
+// checkBlocked 检查流式响应分块是否表明内容被拦截
+func checkBlocked(chunk *genai.GenerateContentResponse) error {
+	if feedback := chunk.PromptFeedback; feedback != nil && feedback.BlockReason != "" &&
+		feedback.BlockReason != genai.BlockedReasonUnspecified {
+		return llmerr.ContentBlocked(fmt.Errorf("%w: prompt block_reason=%s", ErrContentBlocked, feedback.BlockReason))
+	}
+	for _, candidate := range chunk.Candidates {
+		if candidate != nil && blockedFinishReasons[candidate.FinishReason] {
+			return llmerr.ContentBlocked(fmt.Errorf("%w: finish_reason=%s", ErrContentBlocked, candidate.FinishReason))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	"google.golang.org/genai"
+)
+
+// InlineMaxBytes 内联传输的分片大小上限。
+// Gemini 单次请求体上限约 20MB，base64 编码会膨胀 4/3，超过该值改走 Files API 上传。
+const InlineMaxBytes = 14 << 20
+
+const (
+	filePollInterval  = 2 * time.Second
+	fileReadyTimeout  = 2 * time.Minute
+	fileDeleteTimeout = 10 * time.Second
+)
+
+// ErrFileProcessingFailed Files API 处理上传文件失败
+var ErrFileProcessingFailed = errors.New("gemini file processing failed")
+
+// pdfPart 为分片选择传输方式：PUBLIC_URL 可用时引用公网 URL，
+// 否则小分片内联、大分片经 Files API 上传。返回的 cleanup 删除已上传文件，不会为 nil
+func (c *Client) pdfPart(ctx context.Context, pdfPath string) (*genai.Part, func(), error) {
+	noop := func() {}
+	if url, ok := c.buildPublicURL(pdfPath); ok {
+		return genai.NewPartFromURI(url, "application/pdf"), noop, nil
+	}
+
+	info, err := os.Stat(pdfPath)
+	if err != nil {
+		return nil, noop, llmerr.Permanent(fmt.Errorf("failed to read PDF file: %w", err))
+	}
+	if info.Size() <= InlineMaxBytes {
+		pdfBytes, err := os.ReadFile(pdfPath)
+		if err != nil {
+			return nil, noop, llmerr.Permanent(fmt.Errorf("failed to read PDF file: %w", err))
+		}
+		return &genai.Part{
+			InlineData: &genai.Blob{
+				MIMEType: "application/pdf",
+				Data:     pdfBytes,
+			},
+		}, noop, nil
+	}
+
+	file, err := c.uploadPDF(ctx, pdfPath)
+	if err != nil {
+		return nil, noop, err
+	}
+	cleanup := func() { c.deleteFile(file.Name) }
+	return genai.NewPartFromURI(file.URI, file.MIMEType), cleanup, nil
+}
+
+// uploadPDF 通过 Files API 上传分片并等待文件进入 ACTIVE 状态
+func (c *Client) uploadPDF(ctx context.Context, pdfPath string) (*genai.File, error) {
+	report.SetProgress(ctx, report.Progress{State: "uploading"})
+	file, err := c.client.Files.UploadFromPath(ctx, pdfPath, &genai.UploadFileConfig{
+		MIMEType:    "application/pdf",
+		DisplayName: filepath.Base(pdfPath),
+	})
+	if err != nil {
+		return nil, classify(fmt.Errorf("failed to upload PDF file: %w", err))
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, fileReadyTimeout)
+	defer cancel()
+	ticker := time.NewTicker(filePollInterval)
+	defer ticker.Stop()
+	for {
+		// 只有 ACTIVE 可用于生成；PROCESSING 和未返回状态（UNSPECIFIED / 空）继续轮询
+		switch file.State {
+		case genai.FileStateActive:
+			return file, nil
+		case genai.FileStateFailed:
+			c.deleteFile(file.Name)
+			return nil, llmerr.Permanent(fmt.Errorf("%w: %s", ErrFileProcessingFailed, file.Name))
+		}
+
+		select {
+		case <-waitCtx.Done():
+			c.deleteFile(file.Name)
+			return nil, fmt.Errorf("wait for file %s: %w", file.Name, waitCtx.Err())
+		case <-ticker.C:
+		}
+
+		next, err := c.client.Files.Get(waitCtx, file.Name, nil)
+		if err != nil {
+			c.deleteFile(file.Name)
+			return nil, classify(fmt.Errorf("failed to get file %s: %w", file.Name, err))
+		}
+		file = next
+	}
+}
+
+// deleteFile 删除已上传的文件；调用方的 ctx 可能已取消，使用独立超时
+func (c *Client) deleteFile(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), fileDeleteTimeout)
+	defer cancel()
+	if _, err := c.client.Files.Delete(ctx, name, nil); err != nil {
+		log.Printf("[gemini] delete uploaded file failed name=%s err=%v", name, err)
+	}
+}
@@ -0,0 +1,26 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+// GenerateText 纯文本调用（如翻译），instruction 作为系统指令，input 作为用户内容
+func (c *Client) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(instruction, genai.RoleUser),
+	}
+	contents := []*genai.Content{genai.NewContentFromText(input, genai.RoleUser)}
+
+	resp, err := c.client.Models.GenerateContent(ctx, c.model, contents, config)
+	if err != nil {
+		return "", classify(fmt.Errorf("failed to generate content: %w", err))
+	}
+	c.recordUsage(ctx, resp.UsageMetadata)
+	if err := checkBlocked(resp); err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrProviderUnavailable 回退链成员被 Guard 拒绝（如熔断器打开）
+var ErrProviderUnavailable = errors.New("provider is unavailable")
+
+// Guard 由调用方（worker）放入 ctx 交给回退链，按成员熔断：
+// 链在调用每个成员前询问 Guard，不放行的成员直接跳过，调用结束后把结果交给 done
+type Guard interface {
+	// Allow 不放行时返回最早可能放行的等待时间；放行时 done 必须以本次调用的错误（成功为 nil）调用一次
+	Allow(provider string) (done func(err error), wait time.Duration, ok bool)
+}
+
+type guardKey struct{}
+
+// WithGuard 返回携带 Guard 的 ctx
+func WithGuard(ctx context.Context, guard Guard) context.Context {
+	return context.WithValue(ctx, guardKey{}, guard)
+}
+
+// guardAllow 询问 ctx 中的 Guard，未设置时直接放行
+func guardAllow(ctx context.Context, provider string) (func(error), time.Duration, bool) {
+	guard, _ := ctx.Value(guardKey{}).(Guard)
+	if guard == nil {
+		return func(error) {}, 0, true
+	}
+	return guard.Allow(provider)
+}
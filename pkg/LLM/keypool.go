@@ -0,0 +1,443 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	keystore "github.com/neyuki778/LLM-PDF-OCR/internal/keystore"
+	secret "github.com/neyuki778/LLM-PDF-OCR/internal/secret"
+	gemini "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/gemini"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	ratelimit "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/ratelimit"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	pdf "github.com/neyuki778/LLM-PDF-OCR/pkg/pdf"
+)
+
+const (
+	KeyStrategyRoundRobin = "round_robin"
+	KeyStrategyLeastUsed  = "least_used"
+
+	defaultKeyCooldown     = time.Minute
+	keyPoolRefreshInterval = 30 * time.Second
+	maxConsecutiveFailures = 3 // 连续失败达到该次数的 key 进入冷却
+)
+
+// ErrNoAvailableKey 所有 key 都在冷却中或已被禁用
+var ErrNoAvailableKey = errors.New("no available gemini key")
+
+// ErrKeyPoolClosed key 池已被关闭（热加载后不再使用）
+var ErrKeyPoolClosed = errors.New("gemini key pool is closed")
+
+type pooledKey struct {
+	id            int64
+	masked        string
+	client        PDFProcessor
+	uses          int64
+	failures      int64 // 连续失败次数
+	cooldownUntil time.Time
+	limiter       *ratelimit.Limiter // 单 key 的 RPM / TPM 限制，未配置时为 nil
+}
+
+// KeyPoolProcessor 从 keystore 读取多个 Gemini key，每个 key 一个 client，按策略轮换使用。
+// 限流或连续失败的 key 进入临时冷却，被拒绝（401/403）的 key 会在 keystore 中被禁用。
+// 每次调用后把请求数、token、错误和冷却时间写回 keystore。
+type KeyPoolProcessor struct {
+	store     *keystore.Store
+	model     string
+	publicURL string
+	transport http.RoundTripper
+	keyLimits ratelimit.Limits
+	perPage   int
+	strategy  string
+	cooldown  time.Duration
+	nowFn     func() time.Time
+
+	mu          sync.Mutex
+	keys        []*pooledKey
+	next        int
+	refreshedAt time.Time // 上次尝试刷新 key 列表的时间，失败也记录
+	active      int       // 进行中的调用数
+	closed      bool      // Close 后不再接受调用，最后一个调用结束时关闭 keystore
+}
+
+func newKeyPoolProcessor(cfg Config) (*KeyPoolProcessor, error) {
+	cipher, err := secret.LoadFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load master key: %w", err)
+	}
+	store, err := keystore.NewStore(cfg.KeyStorePath, cipher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open keystore: %w", err)
+	}
+
+	strategy := cfg.KeyStrategy
+	if strategy == "" {
+		strategy = KeyStrategyRoundRobin
+	}
+	cooldown := cfg.KeyCooldown
+	if cooldown <= 0 {
+		cooldown = defaultKeyCooldown
+	}
+
+	p := &KeyPoolProcessor{
+		store:     store,
+		model:     cfg.Model,
+		publicURL: cfg.PublicURL,
+		transport: cfg.Transport,
+		keyLimits: cfg.KeyRateLimit,
+		perPage:   cfg.TokensPerPage,
+		strategy:  strategy,
+		cooldown:  cooldown,
+		nowFn:     time.Now,
+	}
+	if err := p.refresh(context.Background()); err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	if len(p.keys) == 0 {
+		_ = store.Close()
+		return nil, fmt.Errorf("failed to load gemini keys: %w", keystore.ErrNoKeys)
+	}
+	return p, nil
+}
+
+// ProcessPDF 实现 PDFProcessor 接口：选一个可用 key 调用，遇到 key 级别错误时换下一个 key
+func (p *KeyPoolProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts ProcessOptions) (string, error) {
+	return p.call(ctx, p.estimateTokens(pdfPath), func(callCtx context.Context, client PDFProcessor) (string, error) {
+		return client.ProcessPDF(callCtx, pdfPath, opts)
+	})
+}
+
+// GenerateText 实现 TextGenerator 接口，key 的选择、冷却和记录与 ProcessPDF 相同
+func (p *KeyPoolProcessor) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	var estimate int64
+	if p.keyLimits.TPM > 0 {
+		// 按字符数粗略估计，调用结束后按实际用量修正
+		estimate = int64(utf8.RuneCountInString(instruction) + 2*utf8.RuneCountInString(input))
+	}
+	return p.call(ctx, estimate, func(callCtx context.Context, client PDFProcessor) (string, error) {
+		generator, ok := client.(TextGenerator)
+		if !ok {
+			return "", llmerr.Permanent(ErrTextUnsupported)
+		}
+		return generator.GenerateText(callCtx, instruction, input)
+	})
+}
+
+// call 选一个可用 key 执行 fn，遇到 key 级别错误时换下一个 key
+func (p *KeyPoolProcessor) call(ctx context.Context, estimate int64, fn func(context.Context, PDFProcessor) (string, error)) (string, error) {
+	if !p.acquire() {
+		return "", llmerr.Permanent(ErrKeyPoolClosed)
+	}
+	defer p.release()
+	p.maybeRefresh(ctx)
+
+	tried := make(map[int64]bool)
+	var lastErr error
+	for {
+		key, limited := p.pick(tried, estimate)
+		if key == nil && limited != nil {
+			// 未尝试的 key 都只是达到单 key 限流：在最早恢复的 key 的限流器上等待并预占，等待计入其统计
+			report.SetProgress(ctx, report.Progress{State: "rate limited"})
+			if _, err := limited.limiter.Wait(ctx, estimate); err != nil {
+				return "", err
+			}
+			p.mu.Lock()
+			limited.uses++
+			p.mu.Unlock()
+			key = limited
+		}
+		if key == nil {
+			if lastErr != nil {
+				return "", lastErr
+			}
+			// 所有 key 冷却中：按最早结束的冷却时间提示 worker 等待
+			return "", llmerr.RateLimited(ErrNoAvailableKey, p.nextAvailableIn())
+		}
+		tried[key.id] = true
+
+		callCtx, keyReport := report.New(ctx)
+		content, err := fn(callCtx, key.client)
+		if estimate > 0 && keyReport.Usage.TotalTokens > 0 {
+			key.limiter.Adjust(keyReport.Usage.TotalTokens - estimate)
+		}
+		if outer := report.From(ctx); outer != nil {
+			outer.Usage.Add(keyReport.Usage)
+			if keyReport.Model != "" {
+				outer.Model = keyReport.Model
+			}
+		}
+		cooldownUntil := p.settle(key, err)
+		p.recordResult(ctx, key, keystore.CallResult{
+			Tokens:        keyReport.Usage.TotalTokens,
+			Err:           err,
+			CooldownUntil: cooldownUntil,
+		})
+		if err == nil {
+			return content, nil
+		}
+		lastErr = fmt.Errorf("gemini key %s: %w", key.masked, err)
+		if ctx.Err() != nil || !p.handleKeyError(ctx, key, err) {
+			return "", lastErr
+		}
+	}
+}
+
+// Close 停止接受新调用，进行中的调用结束后关闭 keystore；重复调用无副作用
+func (p *KeyPoolProcessor) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	if p.active > 0 {
+		return nil
+	}
+	return p.store.Close()
+}
+
+func (p *KeyPoolProcessor) acquire() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.active++
+	return true
+}
+
+func (p *KeyPoolProcessor) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active--
+	if p.closed && p.active == 0 {
+		if err := p.store.Close(); err != nil {
+			log.Printf("[keypool] close keystore failed err=%v", err)
+		}
+	}
+}
+
+// estimateTokens 未配置单 key TPM 时不读取页数，返回 0
+func (p *KeyPoolProcessor) estimateTokens(pdfPath string) int64 {
+	if p.keyLimits.TPM <= 0 {
+		return 0
+	}
+	pages, err := pdf.GetPageCount(pdfPath)
+	if err != nil {
+		pages = 1
+	}
+	return ratelimit.EstimateTokens(pages, p.perPage)
+}
+
+// pick 按策略选出一个未尝试过、不在冷却中且未达到单 key 限流的 key，并预占其限流额度；
+// 没有可用 key 时返回 nil，以及仅因限流不可用的 key 中最早恢复的一个（没有时为 nil），由调用方在其限流器上等待
+func (p *KeyPoolProcessor) pick(tried map[int64]bool, estimate int64) (chosen, soonest *pooledKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.nowFn()
+	var wait time.Duration
+	limited := func(key *pooledKey) bool {
+		delay := key.limiter.Delay(estimate)
+		if delay > 0 && (soonest == nil || delay < wait) {
+			soonest, wait = key, delay
+		}
+		return delay > 0
+	}
+	switch p.strategy {
+	case KeyStrategyLeastUsed:
+		for _, key := range p.keys {
+			if tried[key.id] || now.Before(key.cooldownUntil) || limited(key) {
+				continue
+			}
+			if chosen == nil || key.uses < chosen.uses {
+				chosen = key
+			}
+		}
+	default:
+		for i := 0; i < len(p.keys); i++ {
+			idx := (p.next + i) % len(p.keys)
+			key := p.keys[idx]
+			if tried[key.id] || now.Before(key.cooldownUntil) || limited(key) {
+				continue
+			}
+			chosen = key
+			p.next = idx + 1
+			break
+		}
+	}
+
+	if chosen == nil {
+		return nil, soonest
+	}
+	chosen.uses++
+	chosen.limiter.Reserve(estimate)
+	return chosen, nil
+}
+
+// KeyLimiters 返回各 key（脱敏）的单 key 限流器，未配置单 key 限流时为空，供状态接口展示
+func (p *KeyPoolProcessor) KeyLimiters() map[string]*ratelimit.Limiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	limiters := make(map[string]*ratelimit.Limiter, len(p.keys))
+	for _, key := range p.keys {
+		if key.limiter != nil {
+			limiters[key.masked] = key.limiter
+		}
+	}
+	return limiters
+}
+
+// nextAvailableIn 返回距最早一个 key 冷却结束的时间，没有冷却中的 key 时为 0
+func (p *KeyPoolProcessor) nextAvailableIn() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.nowFn()
+	var wait time.Duration
+	for _, key := range p.keys {
+		if remaining := key.cooldownUntil.Sub(now); remaining > 0 && (wait == 0 || remaining < wait) {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+// settle 更新 key 的内存健康状态，返回本次新设置的冷却截止时间（未设置时为零值）
+func (p *KeyPoolProcessor) settle(key *pooledKey, err error) time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		key.failures = 0
+		return time.Time{}
+	}
+	key.failures++
+
+	rateLimited := llmerr.ClassOf(err) == llmerr.ClassRateLimited
+	if !rateLimited && key.failures < maxConsecutiveFailures {
+		return time.Time{}
+	}
+	key.cooldownUntil = p.nowFn().Add(p.cooldown)
+	log.Printf("[keypool] key cooling down key_id=%d key=%s failures=%d rate_limited=%t cooldown=%s", key.id, key.masked, key.failures, rateLimited, p.cooldown)
+	return key.cooldownUntil
+}
+
+// handleKeyError 处理 key 级别错误（按 gemini client 的错误分类），返回 true 表示应换下一个 key 重试
+func (p *KeyPoolProcessor) handleKeyError(ctx context.Context, key *pooledKey, err error) bool {
+	switch {
+	case errors.Is(err, gemini.ErrKeyRejected):
+		p.remove(key.id)
+		if disableErr := p.store.SetKeyEnabled(ctx, key.id, false); disableErr != nil {
+			log.Printf("[keypool] disable key failed key_id=%d key=%s err=%v", key.id, key.masked, disableErr)
+		} else {
+			log.Printf("[keypool] key rejected, disabled key_id=%d key=%s err=%v", key.id, key.masked, err)
+		}
+		return true
+	case llmerr.ClassOf(err) == llmerr.ClassRateLimited:
+		// 冷却已在 settle 中设置，直接换下一个 key
+		return true
+	default:
+		return false
+	}
+}
+
+// recordResult 把调用结果写回 keystore，供轮换和管理端使用；写入失败只记日志
+func (p *KeyPoolProcessor) recordResult(ctx context.Context, key *pooledKey, result keystore.CallResult) {
+	if err := p.store.RecordKeyResult(context.WithoutCancel(ctx), key.id, result); err != nil {
+		log.Printf("[keypool] record key result failed key_id=%d key=%s err=%v", key.id, key.masked, err)
+	}
+}
+
+func (p *KeyPoolProcessor) remove(id int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, key := range p.keys {
+		if key.id == id {
+			p.keys = append(p.keys[:i], p.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// maybeRefresh 距上次尝试超过 keyPoolRefreshInterval 时刷新 key 列表；
+// 先记录尝试时间，keystore 出错时同样等下一个间隔再试，并发调用也只有一个去刷新
+func (p *KeyPoolProcessor) maybeRefresh(ctx context.Context) {
+	p.mu.Lock()
+	now := p.nowFn()
+	stale := now.Sub(p.refreshedAt) >= keyPoolRefreshInterval
+	if stale {
+		p.refreshedAt = now
+	}
+	p.mu.Unlock()
+	if !stale {
+		return
+	}
+	if err := p.refresh(ctx); err != nil {
+		log.Printf("[keypool] refresh keys failed, keep current pool err=%v", err)
+	}
+}
+
+// refresh 重新读取 enabled key 列表，已有 key 保留其 client；冷却时间取内存与 keystore 中较晚者，
+// 以便重启后或多实例间也能跳过不健康的 key
+func (p *KeyPoolProcessor) refresh(ctx context.Context) error {
+	keys, err := p.store.ListEnabledKeys(ctx)
+	if errors.Is(err, keystore.ErrNoKeys) {
+		// 所有 key 都已被禁用：换成空池，不再使用内存中残留的 key
+		keys, err = nil, nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load gemini keys: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing := make(map[int64]*pooledKey, len(p.keys))
+	for _, key := range p.keys {
+		existing[key.id] = key
+	}
+
+	pool := make([]*pooledKey, 0, len(keys))
+	for _, key := range keys {
+		if current, ok := existing[key.ID]; ok {
+			if key.CooldownUntil != nil && key.CooldownUntil.After(current.cooldownUntil) {
+				current.cooldownUntil = *key.CooldownUntil
+			}
+			pool = append(pool, current)
+			continue
+		}
+		apiKey, err := p.store.Reveal(key)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt key %d: %w", key.ID, err)
+		}
+		client, err := gemini.NewClientWithTransport(apiKey, p.model, p.publicURL, p.transport)
+		if err != nil {
+			return fmt.Errorf("failed to create client for key %d: %w", key.ID, err)
+		}
+		pooled := &pooledKey{
+			id:       key.ID,
+			masked:   key.Masked(),
+			client:   client,
+			uses:     key.RequestCount,
+			failures: key.ConsecutiveFailures,
+			limiter:  ratelimit.New(p.keyLimits),
+		}
+		if key.CooldownUntil != nil {
+			pooled.cooldownUntil = *key.CooldownUntil
+		}
+		pool = append(pool, pooled)
+	}
+
+	p.keys = pool
+	p.refreshedAt = p.nowFn()
+	return nil
+}
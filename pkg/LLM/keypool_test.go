@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	keystore "github.com/neyuki778/LLM-PDF-OCR/internal/keystore"
+	gemini "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/gemini"
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	"google.golang.org/genai"
+)
+
+func newTestKeyPool(t *testing.T, strategy string, clients ...*stubProcessor) (*KeyPoolProcessor, *keystore.Store) {
+	t.Helper()
+	store, err := keystore.NewStore(filepath.Join(t.TempDir(), "keys.db"), nil)
+	if err != nil {
+		t.Fatalf("new keystore: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+
+	pool := &KeyPoolProcessor{
+		store:    store,
+		strategy: strategy,
+		cooldown: time.Minute,
+		nowFn:    time.Now,
+	}
+	for i, client := range clients {
+		key, err := store.AddKey(context.Background(), "AIza-test-key-"+string(rune('a'+i)), "")
+		if err != nil {
+			t.Fatalf("add key: %v", err)
+		}
+		pool.keys = append(pool.keys, &pooledKey{id: key.ID, masked: key.Masked(), client: client})
+	}
+	pool.refreshedAt = time.Now()
+	return pool, store
+}
+
+func TestKeyPool_RateLimitedKeyCoolsDown(t *testing.T) {
+	limited := &stubProcessor{err: llmerr.RateLimited(genai.APIError{Code: 429}, 0)}
+	healthy := &stubProcessor{content: "# page"}
+	pool, _ := newTestKeyPool(t, KeyStrategyRoundRobin, limited, healthy)
+
+	content, err := pool.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{})
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if content != "# page" {
+		t.Fatalf("unexpected content: %q", content)
+	}
+
+	// 冷却中的 key 不应再被选中
+	if _, err := pool.ProcessPDF(context.Background(), "output/x/b.pdf", ProcessOptions{}); err != nil {
+		t.Fatalf("second process: %v", err)
+	}
+	if limited.calls != 1 {
+		t.Fatalf("rate limited key should be skipped while cooling down, calls=%d", limited.calls)
+	}
+}
+
+func TestKeyPool_RejectedKeyIsDisabled(t *testing.T) {
+	rejected := &stubProcessor{err: llmerr.Permanent(fmt.Errorf("%w: %w", gemini.ErrKeyRejected, genai.APIError{Code: 403}))}
+	healthy := &stubProcessor{content: "# page"}
+	pool, store := newTestKeyPool(t, KeyStrategyRoundRobin, rejected, healthy)
+	rejectedID := pool.keys[0].id
+
+	if _, err := pool.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	key, err := store.GetKey(context.Background(), rejectedID)
+	if err != nil {
+		t.Fatalf("get key: %v", err)
+	}
+	if key.Enabled {
+		t.Fatalf("key rejected with 403 should be disabled")
+	}
+	if len(pool.keys) != 1 {
+		t.Fatalf("rejected key should be removed from pool, got %d keys", len(pool.keys))
+	}
+}
+
+func TestKeyPool_AllKeysCoolingDown(t *testing.T) {
+	pool, _ := newTestKeyPool(t, KeyStrategyLeastUsed, &stubProcessor{err: llmerr.RateLimited(genai.APIError{Code: 429}, 0)})
+
+	if _, err := pool.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{}); err == nil {
+		t.Fatalf("expected rate limit error")
+	}
+	_, err := pool.ProcessPDF(context.Background(), "output/x/b.pdf", ProcessOptions{})
+	if !errors.Is(err, ErrNoAvailableKey) {
+		t.Fatalf("expected ErrNoAvailableKey, got %v", err)
+	}
+	if llmerr.ClassOf(err) != llmerr.ClassRateLimited || llmerr.RetryAfter(err) <= 0 {
+		t.Fatalf("ErrNoAvailableKey should be rate limited until the earliest cooldown ends, got %v", err)
+	}
+	if !shouldFallback(err) {
+		t.Fatalf("ErrNoAvailableKey should trigger provider fallback")
+	}
+}
+
+func TestKeyPool_RefreshDropsKeysWhenAllDisabled(t *testing.T) {
+	client := &stubProcessor{content: "# page"}
+	pool, store := newTestKeyPool(t, KeyStrategyRoundRobin, client)
+	if err := store.SetKeyEnabled(context.Background(), pool.keys[0].id, false); err != nil {
+		t.Fatalf("disable key: %v", err)
+	}
+
+	if err := pool.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, err := pool.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{}); !errors.Is(err, ErrNoAvailableKey) {
+		t.Fatalf("expected ErrNoAvailableKey after all keys were disabled, got %v", err)
+	}
+	if client.calls != 0 {
+		t.Fatalf("disabled key should not be used, calls=%d", client.calls)
+	}
+}
+
+func TestKeyPool_FailedRefreshWaitsForNextInterval(t *testing.T) {
+	pool, store := newTestKeyPool(t, KeyStrategyRoundRobin, &stubProcessor{content: "# page"})
+	now := time.Now()
+	pool.nowFn = func() time.Time { return now }
+	pool.refreshedAt = now.Add(-keyPoolRefreshInterval)
+	// keystore 不可用时刷新失败，保留原有 key
+	if err := store.Close(); err != nil {
+		t.Fatalf("close store: %v", err)
+	}
+
+	pool.maybeRefresh(context.Background())
+	if !pool.refreshedAt.Equal(now) {
+		t.Fatalf("failed refresh should record the attempt, refreshed_at=%s", pool.refreshedAt)
+	}
+	if len(pool.keys) != 1 {
+		t.Fatalf("failed refresh should keep the current keys, got %d", len(pool.keys))
+	}
+}
+
+func TestKeyPools_ReuseUntilLastLeaseReleased(t *testing.T) {
+	pool, store := newTestKeyPool(t, KeyStrategyRoundRobin, &stubProcessor{content: "# page"})
+	cfg := Config{Provider: "gemini", KeyStorePath: "keys.db", Model: "m1"}
+	pools := NewKeyPools()
+	pools.pools[keyPoolSpec(cfg)] = &pooledKeyPool{pool: pool, refs: 1}
+	old := &KeyPoolLease{pools: pools, specs: []string{keyPoolSpec(cfg)}}
+
+	next := pools.Lease()
+	reused, err := next.get(cfg)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if reused != pool {
+		t.Fatalf("expected unchanged key config to reuse the pool")
+	}
+
+	// 新租约仍引用该 key 池时，旧租约释放不关闭
+	old.Release()
+	if _, err := pool.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{}); err != nil {
+		t.Fatalf("process after old lease released: %v", err)
+	}
+
+	next.Release()
+	next.Release()
+	_, err = pool.ProcessPDF(context.Background(), "output/x/a.pdf", ProcessOptions{})
+	if !errors.Is(err, ErrKeyPoolClosed) {
+		t.Fatalf("expected ErrKeyPoolClosed, got %v", err)
+	}
+	if _, err := store.ListEnabledKeys(context.Background()); err == nil {
+		t.Fatalf("expected keystore to be closed")
+	}
+	if len(pools.pools) != 0 {
+		t.Fatalf("released pool still registered")
+	}
+}
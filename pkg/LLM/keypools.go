@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// KeyPools 在配置热加载之间复用 Gemini key 池：key 相关配置未变时沿用同一个 KeyPoolProcessor，
+// keystore 连接、key 冷却和单 key 限流状态不随热加载重置。
+// 每个 key 池按租约计数，最后一个引用它的租约释放后才关闭。并发安全
+type KeyPools struct {
+	mu    sync.Mutex
+	pools map[string]*pooledKeyPool
+}
+
+type pooledKeyPool struct {
+	pool *KeyPoolProcessor
+	refs int
+}
+
+// KeyPoolLease 一组 processor 对 key 池的引用，processor 不再使用后调用 Release
+type KeyPoolLease struct {
+	pools *KeyPools
+
+	mu       sync.Mutex
+	specs    []string
+	released bool
+}
+
+// NewKeyPools 创建空的 key 池注册表
+func NewKeyPools() *KeyPools {
+	return &KeyPools{pools: make(map[string]*pooledKeyPool)}
+}
+
+// Lease 创建新租约，通过它创建的 processor 引用的 key 池在租约释放前不会关闭
+func (k *KeyPools) Lease() *KeyPoolLease {
+	return &KeyPoolLease{pools: k}
+}
+
+// NewProcessor 与 llm.NewProcessor 相同，但 key 池从注册表中复用或创建，并记入租约
+func (l *KeyPoolLease) NewProcessor(cfg Config) (PDFProcessor, error) {
+	return buildProcessor(cfg, l)
+}
+
+// Release 释放租约引用的 key 池，引用归零的 key 池从注册表移除并关闭；重复调用无效
+func (l *KeyPoolLease) Release() {
+	l.mu.Lock()
+	specs := l.specs
+	l.specs = nil
+	released := l.released
+	l.released = true
+	l.mu.Unlock()
+	if released {
+		return
+	}
+	l.pools.release(specs)
+}
+
+// get 返回配置对应的 key 池，不存在时创建；l 为 nil 时每次都新建
+func (l *KeyPoolLease) get(cfg Config) (*KeyPoolProcessor, error) {
+	if l == nil {
+		return newKeyPoolProcessor(cfg)
+	}
+	spec := keyPoolSpec(cfg)
+	pool, err := l.pools.acquire(spec, cfg)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	l.specs = append(l.specs, spec)
+	l.mu.Unlock()
+	return pool, nil
+}
+
+func (k *KeyPools) acquire(spec string, cfg Config) (*KeyPoolProcessor, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if entry, ok := k.pools[spec]; ok {
+		entry.refs++
+		return entry.pool, nil
+	}
+	pool, err := newKeyPoolProcessor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	k.pools[spec] = &pooledKeyPool{pool: pool, refs: 1}
+	return pool, nil
+}
+
+func (k *KeyPools) release(specs []string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, spec := range specs {
+		entry, ok := k.pools[spec]
+		if !ok {
+			continue
+		}
+		entry.refs--
+		if entry.refs > 0 {
+			continue
+		}
+		delete(k.pools, spec)
+		closeKeyPool(entry.pool)
+	}
+}
+
+// Close 关闭所有 key 池，服务退出时调用
+func (k *KeyPools) Close() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for spec, entry := range k.pools {
+		delete(k.pools, spec)
+		closeKeyPool(entry.pool)
+	}
+}
+
+func closeKeyPool(pool *KeyPoolProcessor) {
+	if err := pool.Close(); err != nil {
+		log.Printf("[keypool] close retired pool failed err=%v", err)
+	}
+}
+
+// keyPoolSpec 决定 key 池行为的配置项，全部相同时才复用；Transport 按指针比较
+func keyPoolSpec(cfg Config) string {
+	return fmt.Sprintf(
+		"%s|%s|%s|%s|%s|%d/%d|%d|%p",
+		cfg.KeyStorePath,
+		cfg.Model,
+		cfg.PublicURL,
+		cfg.KeyStrategy,
+		cfg.KeyCooldown,
+		cfg.KeyRateLimit.RPM,
+		cfg.KeyRateLimit.TPM,
+		cfg.TokensPerPage,
+		cfg.Transport,
+	)
+}
@@ -0,0 +1,99 @@
+// Package llmerr provider 错误分类。provider 把接口返回的错误映射为以下类别之一，
+// worker 据此决定重试、等待后重试还是直接判失败：
+//
+//   - retryable：临时性错误（网络、5xx、超时），指数退避后重试
+//   - rate_limited：配额/限流，等待 RetryAfter（未知时按退避）后重试
+//   - permanent：重试也不会成功（参数错误、PDF 损坏、无权限），直接失败
+//   - content_blocked：内容被安全策略拦截，直接失败
+//
+// 未分类的错误按 retryable 处理。
+package llmerr
+
+import (
+	"errors"
+	"time"
+)
+
+type Class string
+
+const (
+	ClassRetryable      Class = "retryable"
+	ClassRateLimited    Class = "rate_limited"
+	ClassPermanent      Class = "permanent"
+	ClassContentBlocked Class = "content_blocked"
+)
+
+// Error 带分类的 provider 错误，Unwrap 保留原始错误（如 genai.APIError、*mineru.APIError）
+type Error struct {
+	Class      Class
+	RetryAfter time.Duration // 仅 rate_limited 使用，0 表示未知
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 标记为临时性错误，err 为 nil 时返回 nil
+func Retryable(err error) error {
+	return wrap(err, ClassRetryable, 0)
+}
+
+// RateLimited 标记为限流错误，retryAfter 为服务端建议的等待时间
+func RateLimited(err error, retryAfter time.Duration) error {
+	return wrap(err, ClassRateLimited, retryAfter)
+}
+
+// Permanent 标记为不可重试错误
+func Permanent(err error) error {
+	return wrap(err, ClassPermanent, 0)
+}
+
+// ContentBlocked 标记为内容被拦截
+func ContentBlocked(err error) error {
+	return wrap(err, ClassContentBlocked, 0)
+}
+
+func wrap(err error, class Class, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: class, RetryAfter: retryAfter, Err: err}
+}
+
+// ClassOf 返回错误链中最外层的分类，未分类时为 retryable
+func ClassOf(err error) Class {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Class
+	}
+	return ClassRetryable
+}
+
+// IsClassified 判断错误是否已被 provider 分类
+func IsClassified(err error) bool {
+	var classified *Error
+	return errors.As(err, &classified)
+}
+
+// RetryAfter 返回限流错误建议的等待时间，非限流错误或未知时为 0
+func RetryAfter(err error) time.Duration {
+	var classified *Error
+	if errors.As(err, &classified) && classified.Class == ClassRateLimited {
+		return classified.RetryAfter
+	}
+	return 0
+}
+
+// Fatal 判断错误是否应直接失败（permanent / content_blocked）
+func Fatal(err error) bool {
+	switch ClassOf(err) {
+	case ClassPermanent, ClassContentBlocked:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+// Package mock 离线开发用的 provider：不访问网络，按页码和 PDF 元信息生成确定性的 markdown，
+// 可配置延迟和失败率，用于本地联调和 CI 端到端测试。
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	pdf "github.com/neyuki778/LLM-PDF-OCR/pkg/pdf"
+	schema "github.com/neyuki778/LLM-PDF-OCR/pkg/schema"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// Model 写入 report 的模型名，价目表中未配置时不计费
+const Model = "mock"
+
+// ErrInjectedFailure 按 FailureRate 注入的失败，worker 视为临时错误重试
+var ErrInjectedFailure = errors.New("mock: injected failure")
+
+type Config struct {
+	Latency     time.Duration // 每次调用的固定延迟，模拟远端处理时间
+	FailureRate float64       // 0-1，调用失败的概率
+	Seed        int64         // 失败注入的随机种子，与分片路径共同决定该分片的失败序列，不受调度顺序影响
+}
+
+type Client struct {
+	cfg Config
+
+	mu   sync.Mutex
+	rngs map[string]*rand.Rand // 分片路径（或文本输入）-> 独立的失败序列
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:  cfg,
+		rngs: make(map[string]*rand.Rand),
+	}
+}
+
+func (c *Client) ProcessPDF(ctx context.Context, pdfPath string, opts options.Options) (string, error) {
+	info, err := readInfo(pdfPath)
+	if err != nil {
+		return "", err
+	}
+	pages := shardPages(pdfPath, info.pageCount)
+
+	report.SetProgress(ctx, report.Progress{Total: len(pages), Unit: "pages", State: "running"})
+	if err := c.wait(ctx); err != nil {
+		return "", err
+	}
+	if c.shouldFail(pdfPath) {
+		return "", llmerr.Retryable(fmt.Errorf("%w: %s", ErrInjectedFailure, filepath.Base(pdfPath)))
+	}
+
+	content, err := c.content(info, pages, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if stream := report.StreamFrom(ctx); stream != nil {
+		stream.Reset()
+		stream.Write(content)
+	}
+	report.SetProgress(ctx, report.Progress{Done: len(pages), Total: len(pages), Unit: "pages", State: "done"})
+	if r := report.From(ctx); r != nil {
+		r.Model = Model
+		r.Usage.Add(report.Usage{Pages: int64(len(pages))})
+	}
+	return content, nil
+}
+
+// GenerateText 原样返回输入，延迟和失败注入与 ProcessPDF 相同
+func (c *Client) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	if err := c.wait(ctx); err != nil {
+		return "", err
+	}
+	if c.shouldFail(instruction + "\x00" + input) {
+		return "", llmerr.Retryable(fmt.Errorf("%w: text generation", ErrInjectedFailure))
+	}
+	if r := report.From(ctx); r != nil {
+		r.Model = Model
+	}
+	return input, nil
+}
+
+// wait 模拟远端处理时间
+func (c *Client) wait(ctx context.Context) error {
+	if c.cfg.Latency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(c.cfg.Latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// content 结构化抽取时返回符合 schema 的最小 JSON 文档，否则渲染 markdown
+func (c *Client) content(info pdfInfo, pages []int, opts options.Options) (string, error) {
+	if len(opts.ResponseSchema) == 0 {
+		return render(info, pages), nil
+	}
+	parsed, err := schema.Parse(opts.ResponseSchema)
+	if err != nil {
+		return "", llmerr.Permanent(err)
+	}
+	doc, err := json.Marshal(parsed.Minimal())
+	if err != nil {
+		return "", llmerr.Permanent(err)
+	}
+	return string(doc), nil
+}
+
+// shouldFail 按 key（分片路径）取独立的随机序列，同一分片的第 n 次调用是否失败只由 Seed 和 key 决定
+func (c *Client) shouldFail(key string) bool {
+	if c.cfg.FailureRate <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rng, ok := c.rngs[key]
+	if !ok {
+		hash := fnv.New64a()
+		hash.Write([]byte(key))
+		rng = rand.New(rand.NewSource(c.cfg.Seed ^ int64(hash.Sum64())))
+		c.rngs[key] = rng
+	}
+	return rng.Float64() < c.cfg.FailureRate
+}
+
+// pdfInfo 参与生成输出的 PDF 元信息
+type pdfInfo struct {
+	pageCount int
+	title     string
+	author    string
+	version   string
+}
+
+func readInfo(pdfPath string) (pdfInfo, error) {
+	file, err := os.Open(pdfPath)
+	if err != nil {
+		return pdfInfo{}, err
+	}
+	defer file.Close()
+
+	info, err := api.PDFInfo(file, filepath.Base(pdfPath), nil, false, nil)
+	if err != nil {
+		return pdfInfo{}, llmerr.Permanent(fmt.Errorf("mock: read pdf info: %w", err))
+	}
+	return pdfInfo{
+		pageCount: info.PageCount,
+		title:     strings.TrimSpace(info.Title),
+		author:    strings.TrimSpace(info.Author),
+		version:   info.Version,
+	}, nil
+}
+
+// shardPages 返回分片对应的原文档页码；文件名不含页码范围时按 1..pageCount 计
+func shardPages(pdfPath string, pageCount int) []int {
+	first, last, ok := pdf.ShardPages(pdfPath)
+	if !ok || last-first+1 != pageCount {
+		first, last = 1, pageCount
+	}
+	pages := make([]int, 0, last-first+1)
+	for page := first; page <= last; page++ {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// render 只依赖页码和元信息（不含上传后随机生成的文件名），同一份文档多次处理输出一致
+func render(info pdfInfo, pages []int) string {
+	var b strings.Builder
+	title := info.title
+	if title == "" {
+		title = "Untitled"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	if info.author != "" {
+		fmt.Fprintf(&b, "> Author: %s\n\n", info.author)
+	}
+	fmt.Fprintf(&b, "> Mock output · PDF %s · %d page(s)\n\n", info.version, len(pages))
+
+	for _, page := range pages {
+		fmt.Fprintf(&b, "## Page %d\n\n", page)
+		fmt.Fprintf(&b, "Mock OCR text for page %d.\n\n", page)
+		fmt.Fprintf(&b, "| Field | Value |\n| --- | --- |\n| page | %d |\n| title | %s |\n\n", page, title)
+	}
+	return b.String()
+}
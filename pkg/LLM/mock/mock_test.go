@@ -0,0 +1,49 @@
+package mock
+
+import (
+	"encoding/json"
+	"testing"
+
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	schema "github.com/neyuki778/LLM-PDF-OCR/pkg/schema"
+)
+
+func TestContent_SchemaConforming(t *testing.T) {
+	raw := json.RawMessage(`{"type":"object","properties":{"title":{"type":"string"},"kind":{"enum":["a","b"]}},"required":["title","kind"]}`)
+	c := NewClient(Config{})
+	content, err := c.content(pdfInfo{pageCount: 1}, []int{1}, options.Options{ResponseSchema: raw})
+	if err != nil {
+		t.Fatalf("content: %v", err)
+	}
+	s, err := schema.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if _, err := s.ValidateJSON([]byte(content)); err != nil {
+		t.Fatalf("mock output %s does not match schema: %v", content, err)
+	}
+}
+
+func TestShouldFail_PerPathSequence(t *testing.T) {
+	cfg := Config{FailureRate: 0.5, Seed: 7}
+	sequence := func(c *Client, key string) []bool {
+		out := make([]bool, 16)
+		for i := range out {
+			out[i] = c.shouldFail(key)
+		}
+		return out
+	}
+
+	a := NewClient(cfg)
+	want := sequence(a, "output/t/doc_1-2.pdf")
+
+	// 其他分片先被调用，不影响该分片的失败序列
+	b := NewClient(cfg)
+	sequence(b, "output/t/doc_3-4.pdf")
+	got := sequence(b, "output/t/doc_1-2.pdf")
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("call %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package options
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MinerU 参数白名单，见 MinerU/api文档.md
+var (
+	MinerUModelVersions = []string{"pipeline", "vlm"}
+	MinerULanguages     = []string{
+		"ch", "ch_server", "ch_lite", "chinese_cht", "en", "japan", "korean",
+		"latin", "arabic", "cyrillic", "east_slavic", "devanagari", "el", "th", "ta", "te", "ka",
+	}
+	MinerUExtraFormats = []string{"docx", "html", "latex"}
+)
+
+var (
+	ErrInvalidMinerUOption = errors.New("invalid mineru option")
+	ErrInvalidPageRanges   = errors.New("invalid page_ranges")
+)
+
+// pageRangesPattern 逗号分隔的 N、N-M 或 N--K（第 N 页到倒数第 K 页）
+var pageRangesPattern = regexp.MustCompile(`^\d+(--?\d+)?(,\d+(--?\d+)?)*$`)
+
+// Validate 按白名单校验取值，零值视为使用默认值
+func (o MinerUOptions) Validate() error {
+	if o.ModelVersion != "" && !slices.Contains(MinerUModelVersions, o.ModelVersion) {
+		return fmt.Errorf("%w: model_version must be one of %s", ErrInvalidMinerUOption, strings.Join(MinerUModelVersions, ", "))
+	}
+	if o.Language != "" && !slices.Contains(MinerULanguages, o.Language) {
+		return fmt.Errorf("%w: unsupported language %q", ErrInvalidMinerUOption, o.Language)
+	}
+	for _, format := range o.ExtraFormats {
+		if !slices.Contains(MinerUExtraFormats, format) {
+			return fmt.Errorf("%w: extra_formats must be among %s", ErrInvalidMinerUOption, strings.Join(MinerUExtraFormats, ", "))
+		}
+	}
+	if o.PageRanges != "" && !pageRangesPattern.MatchString(o.PageRanges) {
+		return fmt.Errorf("%w: %q", ErrInvalidPageRanges, o.PageRanges)
+	}
+	return nil
+}
+
+// ParsePageRanges 把 page_ranges 解析为升序去重的页码（从 1 开始），超出 totalPages 的部分截断
+func ParsePageRanges(spec string, totalPages int) ([]int, error) {
+	if !pageRangesPattern.MatchString(spec) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPageRanges, spec)
+	}
+
+	selected := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		start, end := part, part
+		fromEnd := false
+		if before, after, ok := strings.Cut(part, "--"); ok {
+			start, end, fromEnd = before, after, true
+		} else if before, after, ok := strings.Cut(part, "-"); ok {
+			start, end = before, after
+		}
+
+		first, _ := strconv.Atoi(start)
+		last, _ := strconv.Atoi(end)
+		if fromEnd {
+			last = totalPages - last + 1
+		}
+		if first < 1 || first > last {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPageRanges, part)
+		}
+		for page := first; page <= min(last, totalPages); page++ {
+			selected[page] = true
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w: %q selects no pages", ErrInvalidPageRanges, spec)
+	}
+
+	pages := make([]int, 0, len(selected))
+	for page := range selected {
+		pages = append(pages, page)
+	}
+	slices.Sort(pages)
+	return pages, nil
+}
+
+// FormatPageRanges 把升序页码压缩为 page_ranges 格式，如 [1 2 3 5] -> "1-3,5"
+func FormatPageRanges(pages []int) string {
+	parts := make([]string, 0, len(pages))
+	for i := 0; i < len(pages); {
+		j := i
+		for j+1 < len(pages) && pages[j+1] == pages[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(pages[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", pages[i], pages[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
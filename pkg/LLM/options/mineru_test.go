@@ -0,0 +1,55 @@
+package options
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParsePageRanges(t *testing.T) {
+	cases := []struct {
+		spec  string
+		total int
+		want  []int
+	}{
+		{"3", 10, []int{3}},
+		{"2,4-6", 10, []int{2, 4, 5, 6}},
+		{"2--2", 5, []int{2, 3, 4}},
+		{"8-20", 10, []int{8, 9, 10}},
+		{"1-2,2-3", 10, []int{1, 2, 3}},
+	}
+	for _, tc := range cases {
+		got, err := ParsePageRanges(tc.spec, tc.total)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.spec, err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("parse %q: got %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+
+	for _, spec := range []string{"", "0", "5-3", "a-b", "11", "1,,2"} {
+		if _, err := ParsePageRanges(spec, 10); !errors.Is(err, ErrInvalidPageRanges) {
+			t.Fatalf("parse %q: expected ErrInvalidPageRanges, got %v", spec, err)
+		}
+	}
+}
+
+func TestFormatPageRanges(t *testing.T) {
+	if got := FormatPageRanges([]int{1, 2, 3, 5, 7, 8}); got != "1-3,5,7-8" {
+		t.Fatalf("unexpected format: %q", got)
+	}
+}
+
+func TestMinerUOptions_Validate(t *testing.T) {
+	valid := MinerUOptions{ModelVersion: "pipeline", Language: "en", ExtraFormats: []string{"docx", "latex"}, PageRanges: "1-3"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if err := (MinerUOptions{ModelVersion: "gpt"}).Validate(); !errors.Is(err, ErrInvalidMinerUOption) {
+		t.Fatalf("expected ErrInvalidMinerUOption, got %v", err)
+	}
+	if err := (MinerUOptions{ExtraFormats: []string{"pdf"}}).Validate(); !errors.Is(err, ErrInvalidMinerUOption) {
+		t.Fatalf("expected ErrInvalidMinerUOption, got %v", err)
+	}
+}
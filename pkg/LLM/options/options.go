@@ -0,0 +1,31 @@
+// Package options 定义单次 ProcessPDF 调用的参数。
+// 独立成包是为了让 llm 与各 provider 子包都能引用而不产生循环依赖。
+package options
+
+import "encoding/json"
+
+// Options 单次调用参数，零值表示使用 provider 默认行为
+type Options struct {
+	// Prompt 覆盖默认提示词，仅对支持提示词的 provider（gemini）生效
+	Prompt string
+	// Provider 首选 provider，回退链中优先尝试；为空或未配置时按配置顺序
+	Provider string
+	// MinerU 仅对 mineru 生效的解析参数
+	MinerU MinerUOptions
+	// ResponseSchema 非空时进入结构化抽取模式：输出符合该 JSON Schema 的 JSON（仅 gemini 支持）
+	ResponseSchema json.RawMessage
+	// TranslateTo 非空时 OCR 成功后再把分片结果翻译为该语言（如 en、ja），原文结果不变；仅 gemini / mock 支持
+	TranslateTo string
+}
+
+// MinerUOptions 对应 MinerU 创建任务时的可选参数，零值沿用 MinerU 默认值
+type MinerUOptions struct {
+	ModelVersion  string   `json:"model_version,omitempty"` // pipeline / vlm，为空时使用 vlm；下面几个开关仅 pipeline 生效
+	IsOCR         bool     `json:"is_ocr,omitempty"`
+	EnableFormula *bool    `json:"enable_formula,omitempty"` // nil 表示使用 MinerU 默认（开启）
+	EnableTable   *bool    `json:"enable_table,omitempty"`   // nil 表示使用 MinerU 默认（开启）
+	Language      string   `json:"language,omitempty"`
+	ExtraFormats  []string `json:"extra_formats,omitempty"` // 额外导出 docx / html / latex，保存在分片旁
+	// PageRanges 任务级为原文档页码，TaskManager 按分片换算为分片内页码后传给 MinerU
+	PageRanges string `json:"page_ranges,omitempty"`
+}
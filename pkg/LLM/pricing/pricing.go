@@ -0,0 +1,67 @@
+// Package pricing 按模型价目表把用量换算为费用（美元）。
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// Price 单个模型的价格，token 按每百万计，页数按每页计
+type Price struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+	PerPage          float64 `json:"per_page"`
+}
+
+// Table 模型名 -> 价格，模型名与 report.Report.Model 一致（如 gemini-3-flash-preview、mineru-vlm）
+type Table map[string]Price
+
+// Cost 计算用量对应的费用；模型不在价目表中时返回 0, false
+func (t Table) Cost(model string, usage report.Usage) (float64, bool) {
+	price, ok := t[model]
+	if !ok {
+		return 0, false
+	}
+	// 思考 token 按输出计费，TotalTokens 已包含它们
+	output := usage.CandidateTokens
+	if usage.TotalTokens > usage.PromptTokens+usage.CandidateTokens {
+		output = usage.TotalTokens - usage.PromptTokens
+	}
+	cost := float64(usage.PromptTokens)/1e6*price.InputPerMillion +
+		float64(output)/1e6*price.OutputPerMillion +
+		float64(usage.Pages)*price.PerPage
+	return cost, true
+}
+
+// LoadFromEnv 读取 LLM_PRICE_TABLE_PATH（JSON 文件）或 LLM_PRICE_TABLE（内联 JSON），都未设置时返回空表
+func LoadFromEnv() (Table, error) {
+	if path := strings.TrimSpace(os.Getenv("LLM_PRICE_TABLE_PATH")); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read price table: %w", err)
+		}
+		return Parse(raw)
+	}
+	if inline := strings.TrimSpace(os.Getenv("LLM_PRICE_TABLE")); inline != "" {
+		return Parse([]byte(inline))
+	}
+	return Table{}, nil
+}
+
+// Parse 解析 JSON 价目表：{"<model>": {"input_per_million": 0.5, "output_per_million": 3, "per_page": 0}}
+func Parse(raw []byte) (Table, error) {
+	var table Table
+	if err := json.Unmarshal(raw, &table); err != nil {
+		return nil, fmt.Errorf("parse price table: %w", err)
+	}
+	for model, price := range table {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 || price.PerPage < 0 {
+			return nil, fmt.Errorf("parse price table: negative price for %s", model)
+		}
+	}
+	return table, nil
+}
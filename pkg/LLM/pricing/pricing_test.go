@@ -0,0 +1,37 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+func TestTable_Cost(t *testing.T) {
+	table, err := Parse([]byte(`{
+		"gemini-3-flash-preview": {"input_per_million": 0.5, "output_per_million": 3},
+		"mineru-vlm": {"per_page": 0.01}
+	}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	// 1000 个思考 token 计入输出
+	cost, ok := table.Cost("gemini-3-flash-preview", report.Usage{PromptTokens: 2000, CandidateTokens: 1000, TotalTokens: 4000})
+	if !ok || math.Abs(cost-(0.001+0.006)) > 1e-12 {
+		t.Fatalf("unexpected gemini cost: %v ok=%t", cost, ok)
+	}
+
+	cost, ok = table.Cost("mineru-vlm", report.Usage{Pages: 3})
+	if !ok || math.Abs(cost-0.03) > 1e-12 {
+		t.Fatalf("unexpected mineru cost: %v ok=%t", cost, ok)
+	}
+
+	if _, ok := table.Cost("unknown", report.Usage{TotalTokens: 1}); ok {
+		t.Fatalf("unknown model should not be priced")
+	}
+
+	if _, err := Parse([]byte(`{"x": {"per_page": -1}}`)); err == nil {
+		t.Fatalf("expected error for negative price")
+	}
+}
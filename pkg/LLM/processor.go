@@ -2,23 +2,89 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	secret "github.com/neyuki778/LLM-PDF-OCR/internal/secret"
 	mineru "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/MinerU"
 	gemini "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/gemini"
+	mock "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/mock"
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	replay "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/replay"
 )
 
+// ProcessOptions 单次调用参数（提示词等），由任务创建时确定并随分片传入
+type ProcessOptions = options.Options
+
+// MinerUOptions MinerU 解析参数，随 ProcessOptions 传入
+type MinerUOptions = options.MinerUOptions
+
 type PDFProcessor interface {
-	ProcessPDF(ctx context.Context, pdfPath string) (string, error)
+	ProcessPDF(ctx context.Context, pdfPath string, opts ProcessOptions) (string, error)
 }
 
+// TextGenerator 纯文本调用（翻译等），gemini、mock 以及包含它们的回退链实现该接口
+type TextGenerator interface {
+	GenerateText(ctx context.Context, instruction, input string) (string, error)
+}
+
+// ErrTextUnsupported provider 不支持纯文本调用（如 MinerU、replay）
+var ErrTextUnsupported = errors.New("provider does not support text generation")
+
 func NewProcessor(cfg Config) (PDFProcessor, error) {
+	return buildProcessor(cfg, nil)
+}
+
+// buildProcessor 创建 processor，pools 非 nil 时 key 池从注册表复用并记入该租约
+func buildProcessor(cfg Config, pools *KeyPoolLease) (PDFProcessor, error) {
+	processor, err := newProcessor(cfg, pools)
+	if err != nil || cfg.RecordFixturesDir == "" {
+		return processor, err
+	}
+	return replay.NewRecorder(processor, cfg.RecordFixturesDir)
+}
+
+func newProcessor(cfg Config, pools *KeyPoolLease) (PDFProcessor, error) {
+	if len(cfg.Chain) > 0 {
+		return newChainProcessor(cfg.Chain, pools)
+	}
+
+	if cfg.Provider == "gemini" && cfg.KeyStorePath != "" {
+		return pools.get(cfg)
+	}
+
+	// 环境变量中的 key 可以是 keyctl encrypt 生成的密文
+	apiKey, err := revealAPIKey(cfg.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s api key: %w", cfg.Provider, err)
+	}
+
 	switch cfg.Provider {
+	case "mock":
+		return mock.NewClient(cfg.Mock), nil
+	case "replay":
+		return replay.NewClient(cfg.FixturesDir)
 	case "gemini":
-		return gemini.NewClient(cfg.APIKey, cfg.Model, cfg.PublicURL)
+		return gemini.NewClientWithTransport(apiKey, cfg.Model, cfg.PublicURL, cfg.Transport)
 	case "mineru":
-		return mineru.NewClient(cfg.BaseURL, cfg.APIKey, cfg.PublicURL), nil
+		client := mineru.NewClient(cfg.BaseURL, apiKey, cfg.PublicURL)
+		client.Callbacks = cfg.Callbacks
+		if cfg.Transport != nil {
+			client.HTTP.Transport = cfg.Transport
+		}
+		return client, nil
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", cfg.Provider)
 	}
 }
+
+func revealAPIKey(value string) (string, error) {
+	if !secret.IsEncrypted(value) {
+		return value, nil
+	}
+	cipher, err := secret.LoadFromEnv()
+	if err != nil {
+		return "", err
+	}
+	return secret.Reveal(cipher, value)
+}
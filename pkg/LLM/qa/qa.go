@@ -0,0 +1,271 @@
+// Package qa 基于任务结果回答问题：把按页码范围划分的 Markdown 切成小块，
+// 按与问题的词项重合度（BM25）挑出相关片段，连同问题交给 LLM，回答中以 [p. N] 标注引用的页码。
+package qa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultChunkChars = 1200 // 每个片段的最大字符数（按 rune 计）
+	DefaultTopK       = 6    // 发送给模型的片段数
+
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+var ErrEmptyQuestion = errors.New("question is required")
+
+// Generator 纯文本调用，与 llm.TextGenerator 一致
+type Generator interface {
+	GenerateText(ctx context.Context, instruction, input string) (string, error)
+}
+
+// Section 结果中对应一段原文档页码的 Markdown，页码从 1 开始
+type Section struct {
+	PageStart int    `json:"page_start"`
+	PageEnd   int    `json:"page_end"`
+	Text      string `json:"text"`
+}
+
+// Citation 回答中引用的页码范围
+type Citation struct {
+	PageStart int `json:"page_start"`
+	PageEnd   int `json:"page_end"`
+}
+
+// Answer 模型的回答、其中引用的页码以及发送给模型的片段
+type Answer struct {
+	Text      string     `json:"answer"`
+	Citations []Citation `json:"citations"`
+	Sources   []Section  `json:"sources"`
+}
+
+// Split 按空行把各段切成不超过 maxChars 的片段，片段保留所属段的页码范围；单个段落超长时按字符硬切
+func Split(sections []Section, maxChars int) []Section {
+	if maxChars <= 0 {
+		maxChars = DefaultChunkChars
+	}
+	var chunks []Section
+	for _, section := range sections {
+		var current strings.Builder
+		flush := func() {
+			if text := strings.TrimSpace(current.String()); text != "" {
+				chunks = append(chunks, Section{PageStart: section.PageStart, PageEnd: section.PageEnd, Text: text})
+			}
+			current.Reset()
+		}
+		for _, paragraph := range strings.Split(section.Text, "\n\n") {
+			for _, piece := range splitRunes(strings.TrimSpace(paragraph), maxChars) {
+				if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(piece) > maxChars {
+					flush()
+				}
+				if current.Len() > 0 {
+					current.WriteString("\n\n")
+				}
+				current.WriteString(piece)
+			}
+		}
+		flush()
+	}
+	return chunks
+}
+
+func splitRunes(text string, maxChars int) []string {
+	if text == "" {
+		return nil
+	}
+	runes := []rune(text)
+	var pieces []string
+	for len(runes) > maxChars {
+		pieces = append(pieces, string(runes[:maxChars]))
+		runes = runes[maxChars:]
+	}
+	return append(pieces, string(runes))
+}
+
+// Retrieve 返回与问题最相关的 k 个片段，按页码顺序排列；没有任何词项重合时返回文档开头的 k 个片段
+func Retrieve(chunks []Section, question string, k int) []Section {
+	if k <= 0 {
+		k = DefaultTopK
+	}
+	if len(chunks) <= k {
+		return chunks
+	}
+
+	terms := tokenize(question)
+	docs := make([]map[string]int, len(chunks))
+	df := make(map[string]int)
+	totalLen := 0
+	for i, chunk := range chunks {
+		docs[i] = make(map[string]int)
+		tokens := tokenize(chunk.Text)
+		totalLen += len(tokens)
+		for _, token := range tokens {
+			docs[i][token]++
+		}
+		for token := range docs[i] {
+			df[token]++
+		}
+	}
+	avgLen := float64(totalLen) / float64(len(chunks))
+
+	type scored struct {
+		index int
+		score float64
+	}
+	scores := make([]scored, len(chunks))
+	seen := make(map[string]bool)
+	for i, doc := range docs {
+		scores[i].index = i
+		docLen := 0
+		for _, count := range doc {
+			docLen += count
+		}
+		clear(seen)
+		for _, term := range terms {
+			if seen[term] || doc[term] == 0 {
+				continue
+			}
+			seen[term] = true
+			idf := math.Log(1 + (float64(len(chunks))-float64(df[term])+0.5)/(float64(df[term])+0.5))
+			tf := float64(doc[term])
+			scores[i].score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(docLen)/math.Max(avgLen, 1)))
+		}
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].score > scores[j].score })
+
+	indexes := make([]int, 0, k)
+	for _, s := range scores[:k] {
+		if s.score > 0 {
+			indexes = append(indexes, s.index)
+		}
+	}
+	if len(indexes) == 0 {
+		return chunks[:k]
+	}
+	sort.Ints(indexes)
+	selected := make([]Section, 0, len(indexes))
+	for _, i := range indexes {
+		selected = append(selected, chunks[i])
+	}
+	return selected
+}
+
+// tokenize 小写的字母数字词；中日韩文字没有空格分词，按相邻两字切分
+func tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		if len(word) > 1 || (len(word) == 1 && unicode.IsDigit(word[0])) {
+			tokens = append(tokens, string(word))
+		}
+		word = word[:0]
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			tokens = append(tokens, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// Instruction 问答的系统提示词
+const Instruction = "You answer questions about a document using only the excerpts provided by the user. " +
+	"Each excerpt starts with the pages it comes from. " +
+	"Cite the excerpt that supports each statement by copying its pages attribute exactly: [p. N] for a single page, [pp. N-M] for a range. " +
+	"If the excerpts do not contain the answer, say so instead of guessing. " +
+	"Answer in the language of the question and keep the answer concise."
+
+// Prompt 拼接片段和问题
+func Prompt(question string, chunks []Section) string {
+	var b strings.Builder
+	for _, chunk := range chunks {
+		fmt.Fprintf(&b, "<excerpt pages=\"%s\">\n%s\n</excerpt>\n\n", pageLabel(chunk.PageStart, chunk.PageEnd), chunk.Text)
+	}
+	fmt.Fprintf(&b, "Question: %s", question)
+	return b.String()
+}
+
+func pageLabel(start, end int) string {
+	if start == end {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d-%d", start, end)
+}
+
+// Ask 检索相关片段并调用模型回答
+func Ask(ctx context.Context, gen Generator, sections []Section, question string) (Answer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return Answer{}, ErrEmptyQuestion
+	}
+	sources := Retrieve(Split(sections, DefaultChunkChars), question, DefaultTopK)
+	text, err := gen.GenerateText(ctx, Instruction, Prompt(question, sources))
+	if err != nil {
+		return Answer{}, err
+	}
+	text = strings.TrimSpace(text)
+	return Answer{Text: text, Citations: Citations(text, sources), Sources: sources}, nil
+}
+
+var citationPattern = regexp.MustCompile(`\[pp?\.\s*(\d+)(?:\s*[-–]\s*(\d+))?\]`)
+
+// Citations 提取回答中的页码引用，只保留与某个片段页码范围完全一致的引用，按出现顺序去重；
+// 片段按分片划分，范围内的单页无法确认出处，不作为引用返回
+func Citations(answer string, sources []Section) []Citation {
+	citations := make([]Citation, 0)
+	seen := make(map[Citation]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		start, _ := strconv.Atoi(match[1])
+		end := start
+		if match[2] != "" {
+			end, _ = strconv.Atoi(match[2])
+		}
+		citation := Citation{PageStart: start, PageEnd: end}
+		if end < start || seen[citation] || !matchesSource(citation, sources) {
+			continue
+		}
+		seen[citation] = true
+		citations = append(citations, citation)
+	}
+	return citations
+}
+
+func matchesSource(citation Citation, sources []Section) bool {
+	for _, source := range sources {
+		if citation.PageStart == source.PageStart && citation.PageEnd == source.PageEnd {
+			return true
+		}
+	}
+	return false
+}
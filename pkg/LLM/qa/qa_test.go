@@ -0,0 +1,54 @@
+package qa
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fixedGenerator 记录发送的内容并返回固定回答
+type fixedGenerator struct {
+	answer string
+	input  string
+}
+
+func (g *fixedGenerator) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	g.input = input
+	return g.answer, nil
+}
+
+func TestRetrievePicksRelevantPages(t *testing.T) {
+	var chunks []Section
+	for page := 1; page <= 10; page++ {
+		chunks = append(chunks, Section{PageStart: page, PageEnd: page, Text: "General terms and conditions apply."})
+	}
+	chunks[6].Text = "Section 3. The submission deadline is 30 June 2026."
+	chunks[8].Text = "第三节 截止日期为六月三十日。"
+
+	got := Retrieve(chunks, "What is the deadline in section 3?", 1)
+	if len(got) != 1 || got[0].PageStart != 7 {
+		t.Fatalf("Retrieve = %+v, want page 7", got)
+	}
+	got = Retrieve(chunks, "截止日期是什么时候？", 1)
+	if len(got) != 1 || got[0].PageStart != 9 {
+		t.Fatalf("Retrieve CJK = %+v, want page 9", got)
+	}
+}
+
+func TestAskReturnsCitationsWithinSources(t *testing.T) {
+	gen := &fixedGenerator{answer: "The deadline is 30 June 2026 [pp. 5-6]. See also [p. 5] and [p. 40]."}
+	sections := []Section{
+		{PageStart: 1, PageEnd: 2, Text: "Introduction."},
+		{PageStart: 5, PageEnd: 6, Text: "The deadline is 30 June 2026."},
+	}
+	answer, err := Ask(context.Background(), gen, sections, "When is the deadline?")
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if len(answer.Citations) != 1 || answer.Citations[0] != (Citation{PageStart: 5, PageEnd: 6}) {
+		t.Fatalf("Citations = %+v, want only the exact excerpt range 5-6", answer.Citations)
+	}
+	if !strings.Contains(gen.input, `<excerpt pages="5-6">`) {
+		t.Fatalf("prompt missing page label:\n%s", gen.input)
+	}
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+)
+
+// Gate 按 provider 选择限流器，由调用方（worker）放入 ctx 交给回退链：
+// 链在调用每个成员前等待该成员自己的限流器，回退到的 provider 同样受限
+type Gate struct {
+	limiters map[string]*Limiter
+	estimate int64 // 本次调用的预估 token
+}
+
+// NewGate 创建 Gate；limiters 中没有的 provider 不限流
+func NewGate(limiters map[string]*Limiter, estimate int64) *Gate {
+	return &Gate{limiters: limiters, estimate: estimate}
+}
+
+type gateKey struct{}
+
+// WithGate 返回携带 Gate 的 ctx
+func WithGate(ctx context.Context, gate *Gate) context.Context {
+	return context.WithValue(ctx, gateKey{}, gate)
+}
+
+// GateFrom 取出 ctx 中的 Gate，未设置时返回 nil；nil Gate 的方法直接放行
+func GateFrom(ctx context.Context) *Gate {
+	gate, _ := ctx.Value(gateKey{}).(*Gate)
+	return gate
+}
+
+// Acquire 等待 provider 的限流器并预占本次调用的额度，等待期间上报 "rate limited" 进度；
+// 返回的 settle 在调用结束后按实际 token 修正预占（actual 为 0 时不修正）
+func (g *Gate) Acquire(ctx context.Context, provider string) (settle func(actual int64), err error) {
+	noop := func(int64) {}
+	if g == nil {
+		return noop, nil
+	}
+	limiter := g.limiters[provider]
+	if limiter == nil {
+		return noop, nil
+	}
+	if limiter.Reserve(g.estimate) > 0 {
+		report.SetProgress(ctx, report.Progress{State: "rate limited"})
+		if _, err := limiter.Wait(ctx, g.estimate); err != nil {
+			return noop, err
+		}
+	}
+	return func(actual int64) {
+		if actual > 0 {
+			limiter.Adjust(actual - g.estimate)
+		}
+	}, nil
+}
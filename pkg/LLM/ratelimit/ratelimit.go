@@ -0,0 +1,219 @@
+// Package ratelimit provider 调用的令牌桶限流：每分钟请求数（RPM）和每分钟预估 token 数（TPM）各一个桶，
+// 按配额匀速补充，桶容量只有配额的 burstFraction，任意一分钟内放行的量不超过配额的 1+burstFraction 倍。
+// 调用前按预估 token 预占，调用结束后按实际用量修正（多退少补，可以欠账）。
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTokensPerPage 每页 PDF 的预估 token（输入约 258 token/页，加上输出的 Markdown）
+const DefaultTokensPerPage = 1000
+
+// Limits 限流配置，0 表示不限制
+type Limits struct {
+	RPM int // 每分钟请求数
+	TPM int // 每分钟 token 数（预估）
+}
+
+// Enabled 判断是否配置了任一限制
+func (l Limits) Enabled() bool {
+	return l.RPM > 0 || l.TPM > 0
+}
+
+// EstimateTokens 按页数预估单次调用的 token 数
+func EstimateTokens(pages, tokensPerPage int) int64 {
+	if pages <= 0 {
+		pages = 1
+	}
+	if tokensPerPage <= 0 {
+		tokensPerPage = DefaultTokensPerPage
+	}
+	return int64(pages) * int64(tokensPerPage)
+}
+
+// burstFraction 桶容量占每分钟配额的比例；容量等于整分钟配额时，一分钟窗口内最多会放行两倍配额
+const burstFraction = 0.1
+
+// bucket 按 perMinute 的速率匀速补充，最多存 capacity 个令牌
+type bucket struct {
+	rate     float64 // 每分钟补充的令牌数
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	capacity := max(1, float64(perMinute)*burstFraction)
+	return &bucket{rate: float64(perMinute), capacity: capacity, tokens: capacity, last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens = min(b.capacity, b.tokens+elapsed.Minutes()*b.rate)
+	b.last = now
+}
+
+// wait 返回攒够 n 个令牌还需的时间；n 超过容量时按容量计（之后欠账），避免单次大请求永远等不到
+func (b *bucket) wait(n float64) time.Duration {
+	n = min(n, b.capacity)
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Minute))
+}
+
+// Stats 限流器的累计统计
+type Stats struct {
+	Waits     int64         // 发生等待的调用次数
+	WaitTotal time.Duration // 累计等待时间
+	Waiting   int           // 正在等待的调用数
+}
+
+// Limiter 一组 RPM / TPM 令牌桶，可被多个 worker 共享，并发安全
+type Limiter struct {
+	limits Limits
+	nowFn  func() time.Time
+
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+	stats    Stats
+}
+
+// New 创建限流器；limits 未配置任何限制时返回 nil，nil 限流器的方法均直接放行
+func New(limits Limits) *Limiter {
+	if !limits.Enabled() {
+		return nil
+	}
+	now := time.Now()
+	return &Limiter{
+		limits:   limits,
+		nowFn:    time.Now,
+		requests: newBucket(limits.RPM, now),
+		tokens:   newBucket(limits.TPM, now),
+	}
+}
+
+// Limits 返回限流配置
+func (l *Limiter) Limits() Limits {
+	if l == nil {
+		return Limits{}
+	}
+	return l.limits
+}
+
+// Delay 返回现在发起一次 tokens 的调用还需等待的时间，不扣除
+func (l *Limiter) Delay(tokens int64) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.delayLocked(tokens)
+}
+
+func (l *Limiter) delayLocked(tokens int64) time.Duration {
+	now := l.nowFn()
+	var wait time.Duration
+	if l.requests != nil {
+		l.requests.refill(now)
+		wait = max(wait, l.requests.wait(1))
+	}
+	if l.tokens != nil {
+		l.tokens.refill(now)
+		wait = max(wait, l.tokens.wait(float64(tokens)))
+	}
+	return wait
+}
+
+// Reserve 不等待：有余量时扣除并返回 0，否则返回还需等待的时间
+func (l *Limiter) Reserve(tokens int64) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.reserveLocked(tokens)
+}
+
+func (l *Limiter) reserveLocked(tokens int64) time.Duration {
+	if wait := l.delayLocked(tokens); wait > 0 {
+		return wait
+	}
+	if l.requests != nil {
+		l.requests.tokens--
+	}
+	if l.tokens != nil {
+		l.tokens.tokens -= float64(tokens)
+	}
+	return 0
+}
+
+// Wait 阻塞直到一次请求和 tokens 个预估 token 都有余量并扣除，返回等待的时间；ctx 结束时返回 ctx.Err()
+func (l *Limiter) Wait(ctx context.Context, tokens int64) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	start := l.nowFn()
+	waiting := false
+	defer func() {
+		if waiting {
+			l.mu.Lock()
+			l.stats.Waiting--
+			l.stats.Waits++
+			l.stats.WaitTotal += l.nowFn().Sub(start)
+			l.mu.Unlock()
+		}
+	}()
+
+	for {
+		l.mu.Lock()
+		wait := l.reserveLocked(tokens)
+		if wait > 0 && !waiting {
+			waiting = true
+			l.stats.Waiting++
+		}
+		l.mu.Unlock()
+		if wait == 0 {
+			return l.nowFn().Sub(start), nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return l.nowFn().Sub(start), ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Adjust 按实际用量修正预占的 token：delta 为实际减预估，正数补扣（可欠账），负数退还
+func (l *Limiter) Adjust(delta int64) {
+	if l == nil || l.tokens == nil || delta == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.refill(l.nowFn())
+	l.tokens.tokens = min(l.tokens.capacity, l.tokens.tokens-float64(delta))
+}
+
+// Stats 返回累计统计
+func (l *Limiter) Stats() Stats {
+	if l == nil {
+		return Stats{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
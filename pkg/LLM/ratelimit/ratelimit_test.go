@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(limits Limits, now *time.Time) *Limiter {
+	l := New(limits)
+	l.nowFn = func() time.Time { return *now }
+	l.requests = newBucket(limits.RPM, *now)
+	l.tokens = newBucket(limits.TPM, *now)
+	return l
+}
+
+func TestLimiterRequestsPerMinute(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newTestLimiter(Limits{RPM: 20}, &now)
+
+	// 桶容量为配额的 10%：前两个请求立即通过，之后按 3s 一个补充
+	for i := 0; i < 2; i++ {
+		if wait := l.Reserve(0); wait != 0 {
+			t.Fatalf("request %d should pass, wait=%s", i, wait)
+		}
+	}
+	if wait := l.Reserve(0); wait != 3*time.Second {
+		t.Fatalf("third request wait = %s, want 3s", wait)
+	}
+	now = now.Add(3 * time.Second)
+	if wait := l.Reserve(0); wait != 0 {
+		t.Fatalf("request after refill should pass, wait=%s", wait)
+	}
+}
+
+func TestLimiterTokensAdjust(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newTestLimiter(Limits{TPM: 10000}, &now)
+
+	if wait := l.Reserve(600); wait != 0 {
+		t.Fatalf("first call should pass, wait=%s", wait)
+	}
+	if wait := l.Delay(600); wait == 0 {
+		t.Fatalf("second call should exceed the token budget")
+	}
+	// 实际只用了 200 token，退还 400 后第二次调用可以通过
+	l.Adjust(200 - 600)
+	if wait := l.Reserve(600); wait != 0 {
+		t.Fatalf("call after refund should pass, wait=%s", wait)
+	}
+}
+
+func TestLimiterBurstIsCapped(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newTestLimiter(Limits{RPM: 60}, &now)
+
+	passed := 0
+	for step := 0; step < 600; step++ {
+		for l.Reserve(0) == 0 {
+			passed++
+		}
+		now = now.Add(100 * time.Millisecond)
+	}
+	// 一分钟窗口内最多放行配额加上桶容量
+	if passed > 66 {
+		t.Fatalf("passed %d requests in one minute, want at most 66", passed)
+	}
+}
+
+func TestNilLimiterNeverWaits(t *testing.T) {
+	l := New(Limits{})
+	if l != nil {
+		t.Fatalf("limiter without limits should be nil")
+	}
+	if waited, err := l.Wait(context.Background(), 1<<20); waited != 0 || err != nil {
+		t.Fatalf("nil limiter Wait = %s, %v", waited, err)
+	}
+}
@@ -0,0 +1,148 @@
+// Package replay 从 fixtures 目录回放录制好的 provider 输出，用于离线开发和 CI。
+//
+// 上传文件名是随机 ID，且拆分产物每次写入的时间戳不同，分片无法按文件名或内容哈希复现，
+// 因此 fixture 按分片在原文档中的页码范围命名：
+//
+//	pages_3-4.md    第 3-4 页的分片
+//	pages_5.md      只有第 5 页的分片
+//	default.md      没有匹配的页码 fixture 时使用
+//
+// 结构化抽取（ResponseSchema 非空）时扩展名为 .json。
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+	report "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/report"
+	pdf "github.com/neyuki778/LLM-PDF-OCR/pkg/pdf"
+)
+
+// Model 写入 report 的模型名
+const Model = "replay"
+
+var (
+	ErrFixtureNotFound = errors.New("replay: fixture not found")
+	ErrTextUnsupported = errors.New("replay: provider does not support text generation")
+)
+
+// Processor 被录制的 provider，与 llm.PDFProcessor 一致
+type Processor interface {
+	ProcessPDF(ctx context.Context, pdfPath string, opts options.Options) (string, error)
+}
+
+type Client struct {
+	dir string
+}
+
+// NewClient 创建回放 provider，dir 必须是已存在的目录
+func NewClient(dir string) (*Client, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("replay fixtures dir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("replay fixtures dir: %s is not a directory", dir)
+	}
+	return &Client{dir: dir}, nil
+}
+
+func (c *Client) ProcessPDF(ctx context.Context, pdfPath string, opts options.Options) (string, error) {
+	for _, name := range FixtureNames(pdfPath, opts) {
+		content, err := os.ReadFile(filepath.Join(c.dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if stream := report.StreamFrom(ctx); stream != nil {
+			stream.Reset()
+			stream.Write(string(content))
+		}
+		if r := report.From(ctx); r != nil {
+			r.Model = Model
+		}
+		return string(content), nil
+	}
+	return "", llmerr.Permanent(fmt.Errorf("%w: %s in %s", ErrFixtureNotFound, filepath.Base(pdfPath), c.dir))
+}
+
+// FixtureNames 按查找顺序返回分片对应的 fixture 文件名
+func FixtureNames(pdfPath string, opts options.Options) []string {
+	ext := fixtureExt(opts)
+	names := make([]string, 0, 2)
+	if name, ok := pageFixtureName(pdfPath, ext); ok {
+		names = append(names, name)
+	}
+	return append(names, "default"+ext)
+}
+
+func fixtureExt(opts options.Options) string {
+	if len(opts.ResponseSchema) > 0 {
+		return ".json"
+	}
+	return ".md"
+}
+
+func pageFixtureName(pdfPath, ext string) (string, bool) {
+	first, last, ok := pdf.ShardPages(pdfPath)
+	if !ok {
+		return "", false
+	}
+	if first == last {
+		return fmt.Sprintf("pages_%d%s", first, ext), true
+	}
+	return fmt.Sprintf("pages_%d-%d%s", first, last, ext), true
+}
+
+// textGenerator 纯文本调用，与 llm.TextGenerator 一致
+type textGenerator interface {
+	GenerateText(ctx context.Context, instruction, input string) (string, error)
+}
+
+// Recorder 包装真实 provider，把成功的输出按页码范围写入 fixtures 目录，供 replay 回放
+type Recorder struct {
+	next Processor
+	dir  string
+}
+
+func NewRecorder(next Processor, dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("record fixtures dir: %w", err)
+	}
+	return &Recorder{next: next, dir: dir}, nil
+}
+
+func (r *Recorder) ProcessPDF(ctx context.Context, pdfPath string, opts options.Options) (string, error) {
+	content, err := r.next.ProcessPDF(ctx, pdfPath, opts)
+	if err != nil {
+		return content, err
+	}
+
+	// 录制失败不影响本次结果
+	name, ok := pageFixtureName(pdfPath, fixtureExt(opts))
+	if !ok {
+		return content, nil
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, name), []byte(content), 0644); err != nil {
+		log.Printf("[replay] record fixture failed name=%s err=%v", name, err)
+	}
+	return content, nil
+}
+
+// GenerateText 纯文本调用直接转给被录制的 provider，不写入 fixture
+func (r *Recorder) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	generator, ok := r.next.(textGenerator)
+	if !ok {
+		return "", llmerr.Permanent(ErrTextUnsupported)
+	}
+	return generator.GenerateText(ctx, instruction, input)
+}
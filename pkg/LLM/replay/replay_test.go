@@ -0,0 +1,78 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	options "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/options"
+)
+
+type fakeProcessor struct {
+	content string
+}
+
+func (f fakeProcessor) ProcessPDF(ctx context.Context, pdfPath string, opts options.Options) (string, error) {
+	return f.content, nil
+}
+
+func TestReplayLookupOrder(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pages_3-4.md"), []byte("pages 3-4"), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "default.md"), []byte("default"), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	client, err := NewClient(dir)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	cases := map[string]string{
+		"output/abc/abc_3-4.pdf": "pages 3-4",
+		"output/abc/abc_5.pdf":   "default",
+		"upload.pdf":             "default",
+	}
+	for path, want := range cases {
+		got, err := client.ProcessPDF(context.Background(), path, options.Options{})
+		if err != nil {
+			t.Fatalf("ProcessPDF(%s): %v", path, err)
+		}
+		if got != want {
+			t.Fatalf("ProcessPDF(%s) = %q, want %q", path, got, want)
+		}
+	}
+
+	schemaOpts := options.Options{ResponseSchema: json.RawMessage(`{"type":"object"}`)}
+	if _, err := client.ProcessPDF(context.Background(), "abc_3-4.pdf", schemaOpts); !errors.Is(err, ErrFixtureNotFound) {
+		t.Fatalf("expected ErrFixtureNotFound for json fixture, got %v", err)
+	}
+}
+
+func TestRecorderWritesPageFixture(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	recorder, err := NewRecorder(fakeProcessor{content: "# recorded"}, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	if _, err := recorder.ProcessPDF(context.Background(), "output/abc/abc_7.pdf", options.Options{}); err != nil {
+		t.Fatalf("ProcessPDF: %v", err)
+	}
+
+	client, err := NewClient(dir)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	got, err := client.ProcessPDF(context.Background(), "output/xyz/xyz_7.pdf", options.Options{})
+	if err != nil {
+		t.Fatalf("replay recorded fixture: %v", err)
+	}
+	if got != "# recorded" {
+		t.Fatalf("replay = %q, want recorded content", got)
+	}
+}
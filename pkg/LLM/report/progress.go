@@ -0,0 +1,43 @@
+package report
+
+import (
+	"context"
+	"fmt"
+)
+
+// Progress provider 上报的处理进度，如 MinerU 的已解析页数、Gemini 已生成的字符数
+type Progress struct {
+	Done  int    `json:"done"`
+	Total int    `json:"total,omitempty"` // 未知时为 0
+	Unit  string `json:"unit,omitempty"`  // pages / chars
+	State string `json:"state,omitempty"` // provider 侧状态，如 running / pending / generating
+}
+
+// String 返回便于展示的进度，如 "12/40 pages"、"1830 chars"，没有计数时返回状态
+func (p Progress) String() string {
+	switch {
+	case p.Total > 0:
+		return fmt.Sprintf("%d/%d %s", p.Done, p.Total, p.Unit)
+	case p.Done > 0:
+		return fmt.Sprintf("%d %s", p.Done, p.Unit)
+	default:
+		return p.State
+	}
+}
+
+// ProgressFunc 接收进度更新，由调用方（worker）提供，可能被频繁调用，需自行保证并发安全
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+// WithProgress 返回携带进度回调的 ctx
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// SetProgress 上报进度，调用方未设置回调时忽略
+func SetProgress(ctx context.Context, p Progress) {
+	if fn, _ := ctx.Value(progressKey{}).(ProgressFunc); fn != nil {
+		fn(p)
+	}
+}
@@ -0,0 +1,42 @@
+// Package report 在 PDFProcessor 调用的 ctx 中携带本次调用的元信息，
+// 调用方（worker）创建，provider 在处理过程中填写，调用结束后由调用方读取。
+package report
+
+import "context"
+
+// Report 记录单次 ProcessPDF 调用的元信息
+type Report struct {
+	Provider string // 实际产出结果的 provider
+	Model    string // 实际使用的模型，用于按价目表计费
+	Usage    Usage  // 本次调用消耗的 token / 页数
+}
+
+// Usage 用量：gemini 按 token 计，mineru 按页计
+type Usage struct {
+	PromptTokens    int64 `json:"prompt_tokens"`
+	CandidateTokens int64 `json:"candidate_tokens"`
+	TotalTokens     int64 `json:"total_tokens"`
+	Pages           int64 `json:"pages,omitempty"`
+}
+
+// Add 累加另一份用量
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CandidateTokens += other.CandidateTokens
+	u.TotalTokens += other.TotalTokens
+	u.Pages += other.Pages
+}
+
+type reportKey struct{}
+
+// New 返回携带空 Report 的 ctx，调用结束后可从返回的指针读取结果
+func New(ctx context.Context) (context.Context, *Report) {
+	r := &Report{}
+	return context.WithValue(ctx, reportKey{}, r), r
+}
+
+// From 取出 ctx 中的 Report，调用方未设置时返回 nil
+func From(ctx context.Context) *Report {
+	r, _ := ctx.Value(reportKey{}).(*Report)
+	return r
+}
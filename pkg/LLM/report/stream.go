@@ -0,0 +1,23 @@
+package report
+
+import "context"
+
+// Stream 接收 provider 的增量输出，由调用方（worker）提供，用于实时预览。
+// provider 每次开始新一轮生成（换 key、回退到下一个 provider）时先调用 Reset。
+type Stream interface {
+	Reset()
+	Write(chunk string)
+}
+
+type streamKey struct{}
+
+// WithStream 返回携带 Stream 的 ctx；与 Report 分开存放，嵌套 New 时仍可取到
+func WithStream(ctx context.Context, s Stream) context.Context {
+	return context.WithValue(ctx, streamKey{}, s)
+}
+
+// StreamFrom 取出 ctx 中的 Stream，调用方未设置时返回 nil
+func StreamFrom(ctx context.Context) Stream {
+	s, _ := ctx.Value(streamKey{}).(Stream)
+	return s
+}
@@ -0,0 +1,189 @@
+// Package translate 把分片的 Markdown 结果翻译为目标语言。
+// 公式、代码块、图片链接和 HTML 标签在发送前替换为占位符，翻译后原样还原；
+// 表格行数、标题数与原文不一致时视为结构被破坏，返回可重试错误。
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	llmerr "github.com/neyuki778/LLM-PDF-OCR/pkg/LLM/llmerr"
+)
+
+var (
+	ErrInvalidLanguage  = errors.New("translate_to must be a language code such as en, ja or zh-TW")
+	ErrStructureChanged = errors.New("translation changed markdown structure")
+)
+
+// languagePattern BCP 47 风格的语言代码，同时用作结果文件名的一部分
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// languageNames 常用语言代码的英文名，写入提示词；未列出的代码直接使用代码
+var languageNames = map[string]string{
+	"en":    "English",
+	"zh":    "Simplified Chinese",
+	"zh-cn": "Simplified Chinese",
+	"zh-tw": "Traditional Chinese",
+	"ja":    "Japanese",
+	"ko":    "Korean",
+	"fr":    "French",
+	"de":    "German",
+	"es":    "Spanish",
+	"ru":    "Russian",
+	"pt":    "Portuguese",
+	"it":    "Italian",
+	"ar":    "Arabic",
+	"vi":    "Vietnamese",
+	"th":    "Thai",
+}
+
+// Generator 纯文本调用，由 gemini、mock 及包含它们的回退链实现
+type Generator interface {
+	GenerateText(ctx context.Context, instruction, input string) (string, error)
+}
+
+// NormalizeLanguage 校验并规范化语言代码（小写），如 "zh-TW" -> "zh-tw"
+func NormalizeLanguage(lang string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(lang))
+	if !languagePattern.MatchString(normalized) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLanguage, lang)
+	}
+	return normalized, nil
+}
+
+// LanguageName 返回语言代码对应的名字
+func LanguageName(lang string) string {
+	if name, ok := languageNames[lang]; ok {
+		return name
+	}
+	return lang
+}
+
+// Instruction 翻译的系统提示词
+func Instruction(lang string) string {
+	return "Translate the Markdown document provided by the user into " + LanguageName(lang) + ". " +
+		"Keep the Markdown structure exactly: the same headings, lists, table rows and columns, and line breaks. " +
+		"Translate the text inside table cells but keep the pipes and alignment rows. " +
+		"Tokens such as ⟦0⟧ are placeholders for formulas, code and images: copy every placeholder unchanged and do not add new ones. " +
+		"Output only the translated Markdown without any commentary or preamble."
+}
+
+// Markdown 翻译一个分片的 Markdown；空内容直接返回
+func Markdown(ctx context.Context, gen Generator, markdown, lang string) (string, error) {
+	if strings.TrimSpace(markdown) == "" {
+		return markdown, nil
+	}
+	masked, placeholders := Protect(markdown)
+	translated, err := gen.GenerateText(ctx, Instruction(lang), masked)
+	if err != nil {
+		return "", err
+	}
+	restored, err := Restore(translated, placeholders)
+	if err != nil {
+		return "", llmerr.Retryable(err)
+	}
+	if err := CheckStructure(markdown, restored); err != nil {
+		return "", llmerr.Retryable(err)
+	}
+	return restored, nil
+}
+
+// protectedPattern 不参与翻译的片段：代码块、行间/行内公式、LaTeX 定界符、图片和 HTML 标签
+var protectedPattern = regexp.MustCompile("(?s)```.*?```" +
+	`|\$\$.+?\$\$` +
+	`|\\\[.+?\\\]` +
+	`|\\\(.+?\\\)` +
+	`|\$[^$\n]+?\$` +
+	"|`[^`\n]+`" +
+	`|!\[[^\]]*\]\([^)]*\)` +
+	`|</?[A-Za-z][^>\n]*>`)
+
+// Protect 把不参与翻译的片段替换为 ⟦n⟧ 占位符，返回替换后的文本和按序号排列的原文片段
+func Protect(markdown string) (string, []string) {
+	var placeholders []string
+	masked := protectedPattern.ReplaceAllStringFunc(markdown, func(match string) string {
+		placeholders = append(placeholders, match)
+		return placeholder(len(placeholders) - 1)
+	})
+	return masked, placeholders
+}
+
+// placeholderPattern 译文中的占位符 ⟦n⟧
+var placeholderPattern = regexp.MustCompile(`⟦(\d+)⟧`)
+
+// Restore 一次扫描还原占位符，还原出的原文不会再被当作占位符处理；
+// 每个占位符必须恰好出现一次，丢失、重复或出现未知序号时返回 ErrStructureChanged
+func Restore(translated string, placeholders []string) (string, error) {
+	seen := make([]int, len(placeholders))
+	var unknown string
+	restored := placeholderPattern.ReplaceAllStringFunc(translated, func(token string) string {
+		i, err := strconv.Atoi(placeholderPattern.FindStringSubmatch(token)[1])
+		if err != nil || i >= len(placeholders) {
+			if unknown == "" {
+				unknown = token
+			}
+			return token
+		}
+		seen[i]++
+		return placeholders[i]
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("%w: unknown placeholder %s", ErrStructureChanged, unknown)
+	}
+	for i, count := range seen {
+		if count == 0 {
+			return "", fmt.Errorf("%w: placeholder %s missing", ErrStructureChanged, placeholder(i))
+		}
+		if count > 1 {
+			return "", fmt.Errorf("%w: placeholder %s repeated %d times", ErrStructureChanged, placeholder(i), count)
+		}
+	}
+	return restored, nil
+}
+
+func placeholder(i int) string {
+	return fmt.Sprintf("⟦%d⟧", i)
+}
+
+// CheckStructure 比较译文与原文的表格行数和标题数
+func CheckStructure(original, translated string) error {
+	before, after := countStructure(original), countStructure(translated)
+	if before.tableRows != after.tableRows {
+		return fmt.Errorf("%w: table rows %d -> %d", ErrStructureChanged, before.tableRows, after.tableRows)
+	}
+	if before.headings != after.headings {
+		return fmt.Errorf("%w: headings %d -> %d", ErrStructureChanged, before.headings, after.headings)
+	}
+	return nil
+}
+
+type structure struct {
+	tableRows int
+	headings  int
+}
+
+func countStructure(markdown string) structure {
+	var s structure
+	inFence := false
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "|"):
+			s.tableRows++
+		case strings.HasPrefix(trimmed, "#"):
+			s.headings++
+		}
+	}
+	return s
+}
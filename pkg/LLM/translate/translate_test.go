@@ -0,0 +1,65 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// upperGenerator 把译文模拟为大写，占位符不受影响
+type upperGenerator struct{ dropPlaceholders bool }
+
+func (g upperGenerator) GenerateText(ctx context.Context, instruction, input string) (string, error) {
+	if g.dropPlaceholders {
+		input = strings.ReplaceAll(input, "⟦0⟧", "")
+	}
+	return strings.ToUpper(input), nil
+}
+
+func TestMarkdownKeepsFormulasAndTables(t *testing.T) {
+	source := "# 标题 x\n\n质能方程 $E=mc^2$ 见下表：\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n$$\\int_0^1 x\\,dx$$\n\n![fig](images/a.png)\n"
+	got, err := Markdown(context.Background(), upperGenerator{}, source, "en")
+	if err != nil {
+		t.Fatalf("Markdown: %v", err)
+	}
+	for _, kept := range []string{"$E=mc^2$", "$$\\int_0^1 x\\,dx$$", "![fig](images/a.png)", "# 标题 X"} {
+		if !strings.Contains(got, kept) {
+			t.Fatalf("translation lost %q:\n%s", kept, got)
+		}
+	}
+}
+
+func TestMarkdownRejectsMissingPlaceholder(t *testing.T) {
+	_, err := Markdown(context.Background(), upperGenerator{dropPlaceholders: true}, "see $x$ here", "en")
+	if !errors.Is(err, ErrStructureChanged) {
+		t.Fatalf("expected ErrStructureChanged, got %v", err)
+	}
+}
+
+func TestRestoreRequiresEachPlaceholderOnce(t *testing.T) {
+	placeholders := []string{"$a$", "$b$"}
+	got, err := Restore("⟦1⟧ and ⟦0⟧", placeholders)
+	if err != nil || got != "$b$ and $a$" {
+		t.Fatalf("Restore = %q, %v", got, err)
+	}
+	for _, translated := range []string{"⟦0⟧ ⟦0⟧ ⟦1⟧", "⟦0⟧ ⟦1⟧ ⟦2⟧", "⟦1⟧"} {
+		if _, err := Restore(translated, placeholders); !errors.Is(err, ErrStructureChanged) {
+			t.Fatalf("Restore(%q) expected ErrStructureChanged, got %v", translated, err)
+		}
+	}
+	// 还原出的原文中的 ⟦n⟧ 不再被替换
+	got, err = Restore("x ⟦0⟧", []string{"`⟦0⟧`"})
+	if err != nil || got != "x `⟦0⟧`" {
+		t.Fatalf("Restore = %q, %v", got, err)
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	if lang, err := NormalizeLanguage(" zh-TW "); err != nil || lang != "zh-tw" {
+		t.Fatalf("NormalizeLanguage = %q, %v", lang, err)
+	}
+	if _, err := NormalizeLanguage("../en"); !errors.Is(err, ErrInvalidLanguage) {
+		t.Fatalf("expected ErrInvalidLanguage, got %v", err)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
 )
@@ -29,3 +32,20 @@ func SplitPDF(ctx context.Context, inputPath, outputDir string, span int) error
 func GetPageCount(pdfPath string) (int, error) {
 	return api.PageCountFile(pdfPath)
 }
+
+// shardNamePattern pdfcpu 拆分产物的文件名：{原名}_{页}.pdf 或 {原名}_{首页}-{末页}.pdf
+var shardNamePattern = regexp.MustCompile(`_(\d+)(?:-(\d+))?\.pdf$`)
+
+// ShardPages 从拆分产物的文件名解析页码范围（从 1 开始），不符合命名规则时 ok 为 false
+func ShardPages(path string) (first, last int, ok bool) {
+	match := shardNamePattern.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return 0, 0, false
+	}
+	first, _ = strconv.Atoi(match[1])
+	last = first
+	if match[2] != "" {
+		last, _ = strconv.Atoi(match[2])
+	}
+	return first, last, true
+}
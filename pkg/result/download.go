@@ -10,12 +10,17 @@ import (
 
 // DownloadZip 从指定 URL 下载 ZIP 文件到本地路径
 func DownloadZip(ctx context.Context, url, destPath string) error {
+	return DownloadZipWithClient(ctx, http.DefaultClient, url, destPath)
+}
+
+// DownloadZipWithClient 与 DownloadZip 相同，使用指定的 http.Client 下载
+func DownloadZipWithClient(ctx context.Context, client *http.Client, url, destPath string) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return fmt.Errorf("create request failed: %w", err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
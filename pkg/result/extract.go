@@ -183,3 +183,65 @@ func ExtractImagesToDir(zipPath, destDir string) error {
 
 	return nil
 }
+
+// formatExtensions MinerU extra_formats 对应的文件后缀
+var formatExtensions = map[string]string{
+	"docx":  ".docx",
+	"html":  ".html",
+	"latex": ".tex",
+}
+
+// FormatExtension 返回 extra_formats 格式对应的文件后缀，未知格式时 ok 为 false
+func FormatExtension(format string) (ext string, ok bool) {
+	ext, ok = formatExtensions[format]
+	return ext, ok
+}
+
+// ExtractFormatsToDir 提取 ZIP 中 extra_formats 导出的文件，保存为 destDir/{baseName}{ext}
+func ExtractFormatsToDir(zipPath, destDir, baseName string, formats []string) error {
+	if len(formats) == 0 {
+		return nil
+	}
+	wanted := make(map[string]bool, len(formats))
+	for _, format := range formats {
+		if ext, ok := formatExtensions[format]; ok {
+			wanted[ext] = true
+		}
+	}
+
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("open zip failed: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		ext := strings.ToLower(filepath.Ext(f.Name))
+		if f.FileInfo().IsDir() || !wanted[ext] {
+			continue
+		}
+		// 每种格式只取第一个文件
+		delete(wanted, ext)
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("open %s failed: %w", f.Name, err)
+		}
+		destPath := filepath.Join(destDir, baseName+ext)
+		outFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			rc.Close()
+			return fmt.Errorf("create %s failed: %w", destPath, err)
+		}
+
+		_, err = io.Copy(outFile, rc)
+		outFile.Close()
+		rc.Close()
+
+		if err != nil {
+			return fmt.Errorf("extract %s failed: %w", destPath, err)
+		}
+	}
+
+	return nil
+}
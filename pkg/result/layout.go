@@ -0,0 +1,112 @@
+package result
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 分片版面文件的后缀，保存为 {分片名}{suffix}，聚合时合并为任务级 layout.json / content_list.json
+const (
+	LayoutSuffix      = ".layout.json"
+	ContentListSuffix = ".content_list.json"
+)
+
+// ExtractLayoutToDir 提取 ZIP 中的 layout.json 和 *content_list.json，
+// 保存为 destDir/{baseName}.layout.json 和 destDir/{baseName}.content_list.json
+func ExtractLayoutToDir(zipPath, destDir, baseName string) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("open zip failed: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		basename := filepath.Base(f.Name)
+		var suffix string
+		switch {
+		case basename == "layout.json":
+			suffix = LayoutSuffix
+		case strings.HasSuffix(basename, "content_list.json"):
+			suffix = ContentListSuffix
+		default:
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("open %s failed: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("read %s failed: %w", f.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(destDir, baseName+suffix), content, 0644); err != nil {
+			return fmt.Errorf("write %s failed: %w", basename, err)
+		}
+	}
+
+	return nil
+}
+
+// LayoutPart 一个分片的版面文件，PageOffset 为分片首页在原文档中的 0 起始页码
+type LayoutPart struct {
+	PageOffset int
+	Data       []byte
+}
+
+// MergeLayouts 合并各分片的 layout.json：pdf_info 按顺序拼接并把 page_idx 换算为原文档页码，
+// 其余顶层字段（_backend、_version_name 等）取第一个分片的值
+func MergeLayouts(parts []LayoutPart) ([]byte, error) {
+	merged := map[string]any{}
+	pages := make([]any, 0)
+	for _, part := range parts {
+		var layout map[string]any
+		if err := json.Unmarshal(part.Data, &layout); err != nil {
+			return nil, fmt.Errorf("parse layout: %w", err)
+		}
+		for key, value := range layout {
+			if _, exists := merged[key]; !exists && key != "pdf_info" {
+				merged[key] = value
+			}
+		}
+		info, _ := layout["pdf_info"].([]any)
+		for _, page := range info {
+			shiftPageIndex(page, part.PageOffset)
+			pages = append(pages, page)
+		}
+	}
+	merged["pdf_info"] = pages
+	return json.Marshal(merged)
+}
+
+// MergeContentLists 合并各分片的 content_list.json（块数组），page_idx 换算为原文档页码
+func MergeContentLists(parts []LayoutPart) ([]byte, error) {
+	blocks := make([]any, 0)
+	for _, part := range parts {
+		var list []any
+		if err := json.Unmarshal(part.Data, &list); err != nil {
+			return nil, fmt.Errorf("parse content list: %w", err)
+		}
+		for _, block := range list {
+			shiftPageIndex(block, part.PageOffset)
+			blocks = append(blocks, block)
+		}
+	}
+	return json.Marshal(blocks)
+}
+
+func shiftPageIndex(value any, offset int) {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+	if index, ok := object["page_idx"].(float64); ok {
+		object["page_idx"] = int(index) + offset
+	}
+}
@@ -0,0 +1,55 @@
+package result
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMergeLayouts(t *testing.T) {
+	parts := []LayoutPart{
+		{PageOffset: 0, Data: []byte(`{"_backend":"vlm","pdf_info":[{"page_idx":0},{"page_idx":1}]}`)},
+		{PageOffset: 4, Data: []byte(`{"_backend":"pipeline","pdf_info":[{"page_idx":0,"para_blocks":[]}]}`)},
+	}
+	data, err := MergeLayouts(parts)
+	if err != nil {
+		t.Fatalf("merge layouts: %v", err)
+	}
+
+	var merged struct {
+		Backend string `json:"_backend"`
+		PDFInfo []struct {
+			PageIdx int `json:"page_idx"`
+		} `json:"pdf_info"`
+	}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if merged.Backend != "vlm" {
+		t.Fatalf("expected first shard's backend, got %q", merged.Backend)
+	}
+	got := make([]int, 0, len(merged.PDFInfo))
+	for _, page := range merged.PDFInfo {
+		got = append(got, page.PageIdx)
+	}
+	if len(got) != 3 || got[0] != 0 || got[1] != 1 || got[2] != 4 {
+		t.Fatalf("unexpected page_idx: %v", got)
+	}
+}
+
+func TestMergeContentLists(t *testing.T) {
+	parts := []LayoutPart{
+		{PageOffset: 0, Data: []byte(`[{"type":"text","text":"a","page_idx":0}]`)},
+		{PageOffset: 2, Data: []byte(`[{"type":"table","page_idx":1,"bbox":[1,2,3,4]}]`)},
+	}
+	data, err := MergeContentLists(parts)
+	if err != nil {
+		t.Fatalf("merge content lists: %v", err)
+	}
+	var blocks []map[string]any
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(blocks) != 2 || blocks[1]["page_idx"].(float64) != 3 || blocks[1]["type"] != "table" {
+		t.Fatalf("unexpected blocks: %v", blocks)
+	}
+}
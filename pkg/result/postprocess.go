@@ -0,0 +1,159 @@
+package result
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// 后处理步骤名，按 profile 中声明的顺序依次执行
+const (
+	StepStripCodeFences     = "strip_code_fences"     // 去掉 LLM 包在输出外层的 ```markdown 围栏
+	StepDehyphenate         = "dehyphenate"           // 合并行尾连字符断开的单词
+	StepStripPageNumbers    = "strip_page_numbers"    // 删除页首/页尾只有页码的行
+	StepNormalizeBlankLines = "normalize_blank_lines" // 去掉行尾空白，连续空行压缩为一行，文件以单个换行结尾
+)
+
+var ErrUnknownPostProcessStep = errors.New("unknown post-process step")
+
+var postProcessSteps = map[string]func(string) string{
+	StepStripCodeFences:     stripCodeFences,
+	StepDehyphenate:         dehyphenate,
+	StepStripPageNumbers:    stripPageNumbers,
+	StepNormalizeBlankLines: normalizeBlankLines,
+}
+
+var (
+	wrapperFencePattern = regexp.MustCompile("^```\\s*(markdown|md)\\s*$")
+	hyphenBreakPattern  = regexp.MustCompile(`(\p{Ll})-\n(\p{Ll})`)
+	pageNumberPattern   = regexp.MustCompile(`(?i)^\s*(-\s*\d+\s*-|page\s+\d+(\s+of\s+\d+)?|第\s*\d+\s*页|\d{1,4})\s*$`)
+	blankLinesPattern   = regexp.MustCompile(`\n{3,}`)
+	pageBreakPattern    = regexp.MustCompile(`^\s*(\f|-{3,}|\*{3,}|_{3,}|<!--.*-->)\s*$`)
+)
+
+// ValidatePostProcess 检查步骤名是否都已注册
+func ValidatePostProcess(steps []string) error {
+	for _, step := range steps {
+		if _, ok := postProcessSteps[step]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownPostProcessStep, step)
+		}
+	}
+	return nil
+}
+
+// PostProcess 按顺序对 Markdown 内容执行后处理步骤
+func PostProcess(content string, steps []string) (string, error) {
+	if err := ValidatePostProcess(steps); err != nil {
+		return "", err
+	}
+	for _, step := range steps {
+		content = postProcessSteps[step](content)
+	}
+	return content, nil
+}
+
+// PostProcessFile 原地改写 Markdown 文件，steps 为空时不做任何事
+func PostProcessFile(path string, steps []string) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	updated, err := PostProcess(string(content), steps)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(updated), 0644)
+}
+
+// stripCodeFences 只去掉外层 ```markdown 围栏，内部的代码块保持不变
+func stripCodeFences(content string) string {
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines))
+	inWrapper, inCode := false, false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "```") {
+			out = append(out, line)
+			continue
+		}
+		if inCode {
+			if trimmed == "```" {
+				inCode = false
+			}
+			out = append(out, line)
+			continue
+		}
+		if !inWrapper && wrapperFencePattern.MatchString(trimmed) {
+			inWrapper = true
+			continue
+		}
+		if inWrapper && trimmed == "```" {
+			inWrapper = false
+			continue
+		}
+		inCode = true
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+func dehyphenate(content string) string {
+	return hyphenBreakPattern.ReplaceAllString(content, "$1$2")
+}
+
+// stripPageNumbers 只删除位于页首或页尾的页码行：聚合结果不保留页边界，
+// 以文档首尾、分隔线、换页符和单独一行的 HTML 注释划分页面；代码块内的内容不处理
+func stripPageNumbers(content string) string {
+	lines := strings.Split(content, "\n")
+	drop := make([]bool, len(lines))
+	first, last := -1, -1
+	flush := func() {
+		for _, i := range []int{first, last} {
+			if i >= 0 && pageNumberPattern.MatchString(lines[i]) {
+				drop[i] = true
+			}
+		}
+		first, last = -1, -1
+	}
+	inCode := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !inCode && pageBreakPattern.MatchString(line) {
+			flush()
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+		}
+		if trimmed == "" {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	flush()
+
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if !drop[i] {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func normalizeBlankLines(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	normalized := blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimRight(normalized, "\n") + "\n"
+}
@@ -0,0 +1,32 @@
+package result
+
+import "testing"
+
+func TestPostProcess(t *testing.T) {
+	input := "```markdown\n# Title\n\nA hyphen-\nated word  \n\n\n\n```go\nfmt.Println(1)\n```\n\n12\n```\n"
+	got, err := PostProcess(input, []string{
+		StepStripCodeFences,
+		StepDehyphenate,
+		StepStripPageNumbers,
+		StepNormalizeBlankLines,
+	})
+	if err != nil {
+		t.Fatalf("post process: %v", err)
+	}
+	want := "# Title\n\nA hyphenated word\n\n```go\nfmt.Println(1)\n```\n"
+	if got != want {
+		t.Fatalf("unexpected output:\n%q\nwant:\n%q", got, want)
+	}
+
+	if _, err := PostProcess(input, []string{"nope"}); err == nil {
+		t.Fatalf("expected error for unknown step")
+	}
+}
+
+func TestStripPageNumbers(t *testing.T) {
+	input := "3\n\nTotal\n\n2024\n\nend of page\n\n4\n\n---\n\n5\nnext page\n\n```\n42\n```\n"
+	want := "\nTotal\n\n2024\n\nend of page\n\n\n---\n\nnext page\n\n```\n42\n```\n"
+	if got := stripPageNumbers(input); got != want {
+		t.Fatalf("unexpected output:\n%q\nwant:\n%q", got, want)
+	}
+}
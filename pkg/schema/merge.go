@@ -0,0 +1,54 @@
+package schema
+
+// Merge 按页码顺序合并各分片的抽取结果：
+// 对象逐字段合并，数组依次拼接，标量以第一个非空值为准（后面的页只补齐缺失字段）。
+func Merge(values []any) any {
+	var merged any
+	for _, value := range values {
+		merged = mergeValue(merged, value)
+	}
+	return merged
+}
+
+func mergeValue(base, next any) any {
+	if isEmpty(base) {
+		return next
+	}
+	if isEmpty(next) {
+		return base
+	}
+
+	switch b := base.(type) {
+	case map[string]any:
+		n, ok := next.(map[string]any)
+		if !ok {
+			return base
+		}
+		for key, value := range n {
+			b[key] = mergeValue(b[key], value)
+		}
+		return b
+	case []any:
+		n, ok := next.([]any)
+		if !ok {
+			return base
+		}
+		return append(b, n...)
+	default:
+		return base
+	}
+}
+
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
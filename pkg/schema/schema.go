@@ -0,0 +1,264 @@
+// Package schema 实现结构化抽取用到的 JSON Schema 子集：
+// type、properties、required、items、enum、additionalProperties，以及把多页结果合并为一个对象。
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MaxSchemaSize 用户提交的 schema 大小上限（字节）
+const MaxSchemaSize = 32 * 1024
+
+var (
+	ErrInvalidSchema = errors.New("invalid JSON schema")
+	ErrMismatch      = errors.New("value does not match schema")
+)
+
+// Schema 解析后的 JSON Schema
+type Schema struct {
+	root map[string]any
+}
+
+// Parse 解析并检查 schema，顶层必须是 type 为 object 的 schema
+func Parse(raw []byte) (*Schema, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidSchema)
+	}
+	if len(raw) > MaxSchemaSize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidSchema, MaxSchemaSize)
+	}
+
+	var root map[string]any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if types := typeList(root); len(types) != 1 || types[0] != "object" {
+		return nil, fmt.Errorf("%w: top-level type must be object", ErrInvalidSchema)
+	}
+	if err := check(root, "$"); err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// Raw 返回 schema 的 map 形式，可直接作为模型的 response schema
+func (s *Schema) Raw() map[string]any {
+	return s.root
+}
+
+// ValidateJSON 解析 data 并按 schema 校验，返回解析后的值
+func (s *Schema) ValidateJSON(data []byte) (any, error) {
+	var value any
+	if err := json.Unmarshal(bytes.TrimSpace(data), &value); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON: %v", ErrMismatch, err)
+	}
+	if err := validate(s.root, value, "$"); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Minimal 生成符合 schema 的最小值：对象只填 required 属性，数组为空，
+// 有 enum 时取第一个值，其余类型取零值；供离线 provider 生成占位结果
+func (s *Schema) Minimal() any {
+	return minimal(s.root)
+}
+
+func minimal(node map[string]any) any {
+	if enum, ok := node["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	types := typeList(node)
+	if len(types) == 0 {
+		return nil
+	}
+	switch types[0] {
+	case "object":
+		value := map[string]any{}
+		props, _ := node["properties"].(map[string]any)
+		required, _ := node["required"].([]any)
+		for _, name := range required {
+			key, _ := name.(string)
+			child, _ := props[key].(map[string]any)
+			value[key] = minimal(child)
+		}
+		return value
+	case "array":
+		return []any{}
+	case "string":
+		return ""
+	case "number", "integer":
+		return float64(0)
+	case "boolean":
+		return false
+	}
+	return nil
+}
+
+// check 递归检查 schema 本身是否只使用了支持的类型
+func check(node map[string]any, path string) error {
+	for _, t := range typeList(node) {
+		switch t {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return fmt.Errorf("%w: %s: unsupported type %q", ErrInvalidSchema, path, t)
+		}
+	}
+	if props, ok := node["properties"].(map[string]any); ok {
+		for name, child := range props {
+			childNode, ok := child.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%w: %s.%s: property schema must be an object", ErrInvalidSchema, path, name)
+			}
+			if err := check(childNode, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if items, ok := node["items"].(map[string]any); ok {
+		if err := check(items, path+"[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validate(node map[string]any, value any, path string) error {
+	if types := typeList(node); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%w: %s: expected %s, got %s", ErrMismatch, path, strings.Join(types, " or "), typeName(value))
+		}
+	}
+
+	if enum, ok := node["enum"].([]any); ok {
+		found := false
+		for _, candidate := range enum {
+			if reflect.DeepEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: %s: value not in enum", ErrMismatch, path)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := node["properties"].(map[string]any)
+		if required, ok := node["required"].([]any); ok {
+			for _, name := range required {
+				key, _ := name.(string)
+				if _, exists := v[key]; !exists {
+					return fmt.Errorf("%w: %s: missing required property %q", ErrMismatch, path, key)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if child, ok := props[key].(map[string]any); ok {
+				if err := validate(child, v[key], path+"."+key); err != nil {
+					return err
+				}
+				continue
+			}
+			switch extra := node["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					return fmt.Errorf("%w: %s: unexpected property %q", ErrMismatch, path, key)
+				}
+			case map[string]any:
+				if err := validate(extra, v[key], path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if items, ok := node["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func typeList(node map[string]any) []string {
+	switch t := node["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(t string, value any) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const invoiceSchema = `{
+	"type": "object",
+	"properties": {
+		"invoice_number": {"type": "string"},
+		"total": {"type": ["number", "null"]},
+		"currency": {"type": "string", "enum": ["USD", "EUR", "CNY"]},
+		"items": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {"description": {"type": "string"}, "quantity": {"type": "integer"}},
+				"required": ["description"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["invoice_number", "items"]
+}`
+
+func TestSchema_ValidateJSON(t *testing.T) {
+	s, err := Parse([]byte(invoiceSchema))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if _, err := s.ValidateJSON([]byte(`{"invoice_number": "A-1", "total": null, "currency": "USD", "items": [{"description": "pen", "quantity": 2}]}`)); err != nil {
+		t.Fatalf("valid document rejected: %v", err)
+	}
+
+	cases := map[string]string{
+		"missing required": `{"items": []}`,
+		"wrong type":       `{"invoice_number": 1, "items": []}`,
+		"not in enum":      `{"invoice_number": "A-1", "currency": "GBP", "items": []}`,
+		"non integer":      `{"invoice_number": "A-1", "items": [{"description": "pen", "quantity": 1.5}]}`,
+		"extra property":   `{"invoice_number": "A-1", "items": [{"description": "pen", "color": "red"}]}`,
+		"invalid json":     `{"invoice_number": `,
+	}
+	for name, doc := range cases {
+		if _, err := s.ValidateJSON([]byte(doc)); !errors.Is(err, ErrMismatch) {
+			t.Fatalf("%s: expected ErrMismatch, got %v", name, err)
+		}
+	}
+
+	if _, err := Parse([]byte(`{"type": "array"}`)); !errors.Is(err, ErrInvalidSchema) {
+		t.Fatalf("expected ErrInvalidSchema for non-object root, got %v", err)
+	}
+	if _, err := Parse([]byte(`{"type": "object", "properties": {"a": {"type": "date"}}}`)); !errors.Is(err, ErrInvalidSchema) {
+		t.Fatalf("expected ErrInvalidSchema for unsupported type, got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var pages []any
+	for _, doc := range []string{
+		`{"invoice_number": "A-1", "total": null, "items": [{"description": "pen"}]}`,
+		`{"invoice_number": "", "total": 12.5, "items": [{"description": "ink"}]}`,
+	} {
+		var value any
+		if err := json.Unmarshal([]byte(doc), &value); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		pages = append(pages, value)
+	}
+
+	got := Merge(pages)
+	want := map[string]any{
+		"invoice_number": "A-1",
+		"total":          12.5,
+		"items":          []any{map[string]any{"description": "pen"}, map[string]any{"description": "ink"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected merge result: %#v", got)
+	}
+}
+
+func TestSchema_Minimal(t *testing.T) {
+	s, err := Parse([]byte(invoiceSchema))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	raw, err := json.Marshal(s.Minimal())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(raw) != `{"invoice_number":"","items":[]}` {
+		t.Fatalf("minimal = %s", raw)
+	}
+	if _, err := s.ValidateJSON(raw); err != nil {
+		t.Fatalf("minimal document rejected: %v", err)
+	}
+}